@@ -28,18 +28,42 @@ const (
 	bufferStateReadOnly bufferState = "readonly"
 )
 
-type buffer struct {
-	state                        bufferState
-	lines                        []string
-	filename                     string
+// view is the cursor and the scroll position in a buffer, together with the
+// size of the area the buffer is shown in.
+type view struct {
 	cursorX, cursorY             int
 	cursorXOffset, cursorYOffset int
 	viewport                     tea.WindowSizeMsg
+}
+
+type buffer struct {
+	state                        bufferState
+	lines                        rope
+	filename                     string
+	// writes counts how many times the buffer was written to its file
+	writes int
+	// view is where the buffer is looked at from. Windows showing the
+	// buffer keep their own views and swap them in when they get focus.
+	view
 
 	style editorStyle
 
 	parser   *tree_sitter.Parser
 	language *tree_sitter.Language
+
+	history undoHistory
+
+	marks marks
+	// globalLines are the lines :g has still to run on
+	globalLines trackedLines
+
+	// diagnostics are the problems reported by the language server, sorted
+	// by their start
+	diagnostics []diagnostic
+
+	// highlights are drawn on top of the syntax highlighting. They are set
+	// on a copy of the buffer right before it's rendered.
+	highlights []highlight
 }
 
 type newBufferOps func(b *buffer)
@@ -50,7 +74,7 @@ func bufferStateSavedOpt(b *buffer) {
 
 func bufferWithContent(f, c string) func(b *buffer) {
 	return func(b *buffer) {
-		b.lines = strings.Split(c, "\n")
+		b.lines = newRope(strings.Split(c, "\n"))
 		b.filename = f
 	}
 }
@@ -58,8 +82,7 @@ func bufferWithContent(f, c string) func(b *buffer) {
 func newBuffer(style editorStyle, ops ...newBufferOps) buffer {
 	b := buffer{
 		state:    bufferStateUnnamed,
-		lines:    []string{""},
-		viewport: tea.WindowSizeMsg{},
+		lines:    newRope([]string{""}),
 		style:    style,
 	}
 
@@ -83,16 +106,47 @@ func newBuffer(style editorStyle, ops ...newBufferOps) buffer {
 	return b
 }
 
-func detectLanguage(filename string) *tree_sitter.Language {
+// languageName returns the name of the language the file is written in, or
+// an empty string if it isn't supported.
+func languageName(filename string) string {
 	parts := strings.Split(filename, ".")
 	ext := parts[len(parts)-1]
 
 	switch ext {
 	case "go":
-		return tree_sitter.NewLanguage(golang.Language())
+		return "go"
 	case "c", "h":
-		return tree_sitter.NewLanguage(c.Language())
+		return "c"
 	case "cpp", "cxx", "hpp":
+		return "cpp"
+	case "html":
+		return "html"
+	case "java":
+		return "java"
+	case "json":
+		return "json"
+	case "py":
+		return "python"
+	case "rb":
+		return "ruby"
+	case "rs":
+		return "rust"
+	}
+
+	return ""
+}
+
+func detectLanguage(filename string) *tree_sitter.Language {
+	return languageByName(languageName(filename))
+}
+
+func languageByName(name string) *tree_sitter.Language {
+	switch name {
+	case "go":
+		return tree_sitter.NewLanguage(golang.Language())
+	case "c":
+		return tree_sitter.NewLanguage(c.Language())
+	case "cpp":
 		return tree_sitter.NewLanguage(cpp.Language())
 	case "html":
 		return tree_sitter.NewLanguage(html.Language())
@@ -100,11 +154,11 @@ func detectLanguage(filename string) *tree_sitter.Language {
 		return tree_sitter.NewLanguage(java.Language())
 	case "json":
 		return tree_sitter.NewLanguage(json.Language())
-	case "py":
+	case "python":
 		return tree_sitter.NewLanguage(python.Language())
-	case "rb":
+	case "ruby":
 		return tree_sitter.NewLanguage(ruby.Language())
-	case "rs":
+	case "rust":
 		return tree_sitter.NewLanguage(rust.Language())
 	}
 
@@ -118,6 +172,7 @@ func (b buffer) SetStateModified() buffer {
 
 func (b buffer) SetStateSaved() buffer {
 	b.state = bufferStateSaved
+	b.history = b.history.markSaved(b.lines)
 	return b
 }
 
@@ -126,16 +181,17 @@ func (m buffer) View() string {
 
 	startY := m.cursorYOffset
 	endY := startY + m.viewport.Height - 2
-	endY = min(endY, len(m.lines))
+	endY = min(endY, m.NoOfLines())
+	// The other highlights are drawn over the diagnostics
+	m.highlights = append(m.diagnosticHighlights(), m.highlights...)
 
 	for y := startY; y < endY; y++ {
-		line := m.lines[y]
+		line := m.Line(y)
 		visual := expandTabs(line)
-		b.WriteString(lineNumber(y+1, len(m.lines)))
+		b.WriteString(m.gutter(y))
 
 		// Apply horizontal scrolling
-		lineNumberWidth := len(fmt.Sprintf("%d", len(m.lines))) + 1
-		availableWidth := m.viewport.Width - lineNumberWidth
+		availableWidth := m.viewport.Width - m.gutterWidth()
 
 		// Trim the line based on horizontal offset
 		startX := m.cursorXOffset
@@ -151,9 +207,10 @@ func (m buffer) View() string {
 		}
 
 		styledChunks := m.HighlightString(visual)
+		styledChunks = applySpans(styledChunks, m.lineSpans(y, line))
 
 		if y == m.cursorY {
-			visX := visualCursorX(m.lines[y], m.cursorX) - m.cursorXOffset
+			visX := visualCursorX(line, m.cursorX) - m.cursorXOffset
 			renderedCursor := false
 			currentCol := 0
 
@@ -232,8 +289,8 @@ func (b buffer) IncreaseCursorY(n int) buffer {
 	if b.cursorY < 0 {
 		b.cursorY = 0
 	}
-	if b.cursorY > len(b.lines) {
-		b.cursorY = len(b.lines)
+	if b.cursorY > b.NoOfLines() {
+		b.cursorY = b.NoOfLines()
 	}
 	return b.adjustViewportForCursor()
 }
@@ -287,8 +344,7 @@ func (b buffer) adjustViewportForCursor() buffer {
 	visualX := visualCursorX(line, b.cursorX)
 
 	// Account for line numbers and padding
-	lineNumberWidth := len(fmt.Sprintf("%d", len(b.lines))) + 1
-	availableWidth := b.viewport.Width - lineNumberWidth
+	availableWidth := b.viewport.Width - b.gutterWidth()
 
 	// If cursor is to the left of the viewport, scroll left
 	if visualX < b.cursorXOffset {
@@ -309,18 +365,30 @@ func (b buffer) adjustViewportForCursor() buffer {
 }
 
 func (b buffer) Line(n int) string {
-	if n >= 0 && n < len(b.lines) {
-		return b.lines[n]
+	if n >= 0 && n < b.lines.Len() {
+		return b.lines.Line(n)
 	}
 	return ""
 }
 
 func (b buffer) Lines() []string {
-	return b.lines
+	return b.lines.Lines()
+}
+
+// LineSlice returns the lines from index from up to to, clamped to the
+// buffer.
+func (b buffer) LineSlice(from, to int) []string {
+	return b.lines.Slice(max(0, from), min(to, b.lines.Len()))
+}
+
+// Content returns the lines joined with newlines, as they are written to
+// the file.
+func (b buffer) Content() string {
+	return b.lines.String()
 }
 
 func (b buffer) NoOfLines() int {
-	return len(b.lines)
+	return b.lines.Len()
 }
 
 func (b buffer) CursorXOffset() int {
@@ -336,7 +404,8 @@ func (b buffer) CursorYOffset() int {
 }
 
 func (b buffer) AppendLine(s string) buffer {
-	b.lines = append(b.lines, s)
+	b.globalLines = b.globalLines.inserted(b.lines.Len())
+	b.lines = b.lines.Insert(b.lines.Len(), s)
 	return b
 }
 
@@ -344,26 +413,28 @@ func (b buffer) InsertLine(n int, s string) buffer {
 	if n < 0 {
 		n = 0
 	}
-	if n > len(b.lines) {
-		n = len(b.lines)
+	if n > b.lines.Len() {
+		n = b.lines.Len()
 	}
-	b.lines = append(b.lines[:n], append([]string{s}, b.lines[n:]...)...)
+	b.globalLines = b.globalLines.inserted(n)
+	b.lines = b.lines.Insert(n, s)
 	return b
 }
 
 func (b buffer) DeleteLine(n int) buffer {
-	if n >= 0 && n < len(b.lines) {
-		b.lines = append(b.lines[:n], b.lines[n+1:]...)
+	if n >= 0 && n < b.lines.Len() {
+		b.globalLines = b.globalLines.deleted(n)
+		b.lines = b.lines.Delete(n)
 	}
-	if len(b.lines) == 0 {
-		b.lines = []string{""}
+	if b.lines.Len() == 0 {
+		b.lines = newRope([]string{""})
 	}
 	return b
 }
 
 func (b buffer) ReplaceLine(n int, s string) buffer {
-	if n >= 0 && n < len(b.lines) {
-		b.lines[n] = s
+	if n >= 0 && n < b.lines.Len() {
+		b.lines = b.lines.Set(n, s)
 	}
 	return b
 }
@@ -407,24 +478,49 @@ func expandTabs(s string) string {
 			col += spaces
 		} else {
 			b.WriteRune(r)
-			col++
+			col += runewidth.RuneWidth(r)
 		}
 	}
 	return b.String()
 }
 
 func visualCursorX(s string, logicalX int) int {
+	return displayColumn(s, logicalX)
+}
+
+// runeColumns is the number of screen columns the rune takes at the column.
+func runeColumns(r rune, col int) int {
+	if r == '\t' {
+		return tabSize - (col % tabSize)
+	}
+	return runewidth.RuneWidth(r)
+}
+
+// displayColumn returns the screen column of the byte offset in the line.
+func displayColumn(line string, offset int) int {
 	col := 0
-	for i := 0; i < logicalX && i < len(s); i++ {
-		if s[i] == '\t' {
-			col += tabSize - (col % tabSize)
-		} else {
-			col++
+	for i, r := range line {
+		if i >= offset {
+			break
 		}
+		col += runeColumns(r, col)
 	}
 	return col
 }
 
+// columnOffset returns the byte offset of the character covering the
+// screen column, or the length of the line when it's shorter.
+func columnOffset(line string, col int) int {
+	c := 0
+	for i, r := range line {
+		c += runeColumns(r, c)
+		if c > col {
+			return i
+		}
+	}
+	return len(line)
+}
+
 func loadFile(filename string, style editorStyle) (buffer, error) {
 	content, err := os.ReadFile(filename)
 	if err != nil {
@@ -445,6 +541,7 @@ func loadFile(filename string, style editorStyle) (buffer, error) {
 	}
 
 	b := newBuffer(style, bufferWithContent(filename, string(content)))
+	b = b.loadUndoFile(string(content))
 
 	return b, nil
 }
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/aymanbagabas/go-osc52/v2"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// clipboardSequenceTime is how long the OSC 52 sequence is sent with the
+// frames.
+const clipboardSequenceTime = 100 * time.Millisecond
+
+// clipboard gives access to the system clipboard used by the "+ and "*
+// registers. primary selects the X11 primary selection ("*). The calls may
+// block, they're made by commands running in the background.
+type clipboard interface {
+	Copy(text string, primary bool) error
+	Paste(primary bool) (string, error)
+}
+
+// clipboardTool is a command line tool that can access the clipboard.
+type clipboardTool struct {
+	name                string
+	copyArgs, pasteArgs []string
+	copyPrimaryArgs     []string
+	pastePrimaryArgs    []string
+}
+
+var clipboardTools = []clipboardTool{
+	{
+		name:             "xclip",
+		copyArgs:         []string{"-selection", "clipboard", "-in"},
+		pasteArgs:        []string{"-selection", "clipboard", "-out"},
+		copyPrimaryArgs:  []string{"-selection", "primary", "-in"},
+		pastePrimaryArgs: []string{"-selection", "primary", "-out"},
+	},
+	{
+		name:             "xsel",
+		copyArgs:         []string{"--clipboard", "--input"},
+		pasteArgs:        []string{"--clipboard", "--output"},
+		copyPrimaryArgs:  []string{"--primary", "--input"},
+		pastePrimaryArgs: []string{"--primary", "--output"},
+	},
+	{
+		name:             "wl-copy",
+		copyArgs:         []string{},
+		pasteArgs:        []string{"--no-newline"},
+		copyPrimaryArgs:  []string{"--primary"},
+		pastePrimaryArgs: []string{"--primary", "--no-newline"},
+	},
+}
+
+// pasteCommand returns the tool used to read the clipboard. wl-copy comes
+// with a separate wl-paste binary.
+func (t clipboardTool) pasteCommand() string {
+	if t.name == "wl-copy" {
+		return "wl-paste"
+	}
+	return t.name
+}
+
+// systemClipboard uses xclip, xsel or wl-copy, when one is installed. The
+// terminal's clipboard is set with OSC 52 escape sequences by the editor,
+// which also works over SSH, but the tools are the only way to read the
+// clipboard back.
+type systemClipboard struct {
+}
+
+func newSystemClipboard() systemClipboard {
+	return systemClipboard{}
+}
+
+func (c systemClipboard) tool() (clipboardTool, bool) {
+	for _, t := range clipboardTools {
+		if isToolInstalled(t.name) {
+			return t, true
+		}
+	}
+	return clipboardTool{}, false
+}
+
+func (c systemClipboard) Copy(text string, primary bool) error {
+	t, ok := c.tool()
+	if !ok {
+		return nil
+	}
+
+	args := t.copyArgs
+	if primary {
+		args = t.copyPrimaryArgs
+	}
+	cmd := exec.Command(t.name, args...)
+	cmd.Stdin = strings.NewReader(text)
+	return cmd.Run()
+}
+
+func (c systemClipboard) Paste(primary bool) (string, error) {
+	t, ok := c.tool()
+	if !ok {
+		return "", errors.New("no clipboard tool found, install xclip, xsel or wl-copy")
+	}
+
+	args := t.pasteArgs
+	if primary {
+		args = t.pastePrimaryArgs
+	}
+	out, err := exec.Command(t.pasteCommand(), args...).Output()
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func isClipboardRegister(name rune) bool {
+	return name == '+' || name == '*'
+}
+
+// osc52Sequence is the escape sequence setting the terminal's clipboard to
+// the text.
+func osc52Sequence(text string, primary bool) string {
+	seq := osc52.New(text)
+	if primary {
+		seq = seq.Primary()
+	}
+	if os.Getenv("TMUX") != "" {
+		seq = seq.Tmux()
+	} else if strings.HasPrefix(os.Getenv("TERM"), "screen") {
+		seq = seq.Screen()
+	}
+	return seq.String()
+}
+
+// clipboardCopy is a text waiting to be copied to the clipboard.
+type clipboardCopy struct {
+	text    string
+	primary bool
+}
+
+// clipboardErrorMsg reports a copy to the clipboard which failed.
+type clipboardErrorMsg struct {
+	err error
+}
+
+// clipboardSequenceMsg stops sending the OSC 52 sequence with the frames.
+type clipboardSequenceMsg struct {
+	seq string
+}
+
+// clipboardPasteMsg carries the clipboard read to put it after (or before)
+// the cursor.
+type clipboardPasteMsg struct {
+	name  rune
+	after bool
+	text  string
+	err   error
+}
+
+// copyToClipboard copies the texts stored in the clipboard registers by the
+// last update. The terminal gets the OSC 52 sequence with the next frames,
+// the tools run in the background.
+func (m model) copyToClipboard() (model, tea.Cmd) {
+	c := m.Clipboard()
+	var seq strings.Builder
+	var cmds []tea.Cmd
+	for _, cp := range m.clipboardCopies {
+		seq.WriteString(osc52Sequence(cp.text, cp.primary))
+		cmds = append(cmds, func() tea.Msg {
+			if err := c.Copy(cp.text, cp.primary); err != nil {
+				return clipboardErrorMsg{err: err}
+			}
+			return nil
+		})
+	}
+	m.clipboardCopies = nil
+
+	sent := seq.String()
+	m.clipboardSequence = sent
+	cmds = append(cmds, tea.Tick(clipboardSequenceTime, func(time.Time) tea.Msg {
+		return clipboardSequenceMsg{seq: sent}
+	}))
+	return m, tea.Batch(cmds...)
+}
+
+// pasteFromClipboard reads the clipboard in the background to put it.
+func (m model) pasteFromClipboard(name rune, after bool) tea.Cmd {
+	c := m.Clipboard()
+	return func() tea.Msg {
+		text, err := c.Paste(name == '*')
+		return clipboardPasteMsg{name: name, after: after, text: text, err: err}
+	}
+}
+
+func (m model) updateClipboard(msg tea.Msg) (model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case clipboardErrorMsg:
+		return m.SetErrorMessage("Failed to copy to clipboard: " + msg.err.Error()), nil
+	case clipboardSequenceMsg:
+		if m.clipboardSequence == msg.seq {
+			m.clipboardSequence = ""
+		}
+	case clipboardPasteMsg:
+		if m.mode != ModeNormal {
+			return m, nil
+		}
+		// Fall back to the last copied text when the clipboard can't be read
+		r, ok := m.Register(msg.name)
+		if msg.err == nil && msg.text != "" {
+			r, ok = registerFromText(msg.text), true
+		}
+		if !ok {
+			if msg.err != nil {
+				return m.SetErrorMessage("Failed to paste from clipboard: " + msg.err.Error()), nil
+			}
+			return m.SetErrorMessage("Nothing in register " + string(msg.name)), nil
+		}
+		return m.putRegister(r, msg.after), nil
+	}
+	return m, nil
+}
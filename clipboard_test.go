@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type fakeClipboard struct {
+	content map[bool]string
+}
+
+func (c *fakeClipboard) Copy(text string, primary bool) error {
+	c.content[primary] = text
+	return nil
+}
+
+func (c *fakeClipboard) Paste(primary bool) (string, error) {
+	return c.content[primary], nil
+}
+
+// pressKeysAndRun presses the keys and runs the commands they return, like
+// the program does.
+func pressKeysAndRun(m model, keys ...tea.KeyMsg) model {
+	for _, k := range keys {
+		res, cmd := m.Update(k)
+		m = runBatch(res.(model), cmd)
+	}
+	return m
+}
+
+func runBatch(m model, cmd tea.Cmd) model {
+	if cmd == nil {
+		return m
+	}
+	switch msg := cmd().(type) {
+	case nil:
+	case tea.BatchMsg:
+		for _, cmd := range msg {
+			m = runBatch(m, cmd)
+		}
+	default:
+		res, cmd := m.Update(msg)
+		m = runBatch(res.(model), cmd)
+	}
+	return m
+}
+
+func TestClipboardRegisters(t *testing.T) {
+	cb := &fakeClipboard{content: map[bool]string{}}
+	m := initialModel()
+	m.clipboard = cb
+	m.buffers[0] = newBuffer(m.style, bufferWithContent("", "first\nsecond"))
+
+	// The copy runs in the background, the terminal gets OSC 52 with the
+	// frames until then
+	keys := runeKeys(`"+yy`)
+	m = pressKeys(m, keys[:len(keys)-1]...)
+	res, cmd := m.Update(keys[len(keys)-1])
+	m = res.(model)
+	if cb.content[false] != "" || !strings.HasPrefix(m.View(), osc52Sequence("first\n", false)) {
+		t.Errorf("Expected the OSC 52 sequence in the view before copying")
+	}
+	m = runBatch(m, cmd)
+	if cb.content[false] != "first\n" {
+		t.Errorf("Expected clipboard to hold the yanked line, got %q", cb.content[false])
+	}
+	if m.clipboardSequence != "" {
+		t.Errorf("Expected the OSC 52 sequence to be sent only for a moment")
+	}
+
+	m = pressKeysAndRun(m, runeKeys(`j"*dd`)...)
+	if cb.content[true] != "second\n" {
+		t.Errorf("Expected primary selection to hold the deleted line, got %q", cb.content[true])
+	}
+
+	// Text copied outside of the editor is pasted charwise
+	cb.content[false] = "outside"
+	m = pressKeys(m, runeKeys(`"+P`)...)
+	if got := m.CurrentBuffer().Lines(); !slices.Equal(got, []string{"first"}) {
+		t.Errorf("Expected the clipboard to be put once it's read, got %v", got)
+	}
+	m = pressKeysAndRun(m, runeKeys(`"+P`)...)
+	if got := m.CurrentBuffer().Lines(); !slices.Equal(got, []string{"outsidefirst"}) {
+		t.Errorf("Expected [outsidefirst], got %v", got)
+	}
+}
+
+type failingClipboard struct{}
+
+func (failingClipboard) Copy(text string, primary bool) error {
+	return errors.New("no display")
+}
+
+func (failingClipboard) Paste(primary bool) (string, error) {
+	return "", errors.New("no display")
+}
+
+func TestClipboardFailures(t *testing.T) {
+	m := initialModel()
+	m.clipboard = failingClipboard{}
+	m.buffers[0] = newBuffer(m.style, bufferWithContent("", "first"))
+
+	m = pressKeysAndRun(m, runeKeys(`"+yy`)...)
+	if m.currentMessage == nil || m.currentMessage.text != "Failed to copy to clipboard: no display" {
+		t.Errorf("Expected the copy error, got %+v", m.currentMessage)
+	}
+
+	// The last copied text is put when the clipboard can't be read
+	m = pressKeysAndRun(m, runeKeys(`"+p`)...)
+	if got := m.CurrentBuffer().Lines(); !slices.Equal(got, []string{"first", "first"}) {
+		t.Errorf("Expected the register to be put, got %v", got)
+	}
+	m = pressKeysAndRun(m, runeKeys(`"*p`)...)
+	if m.currentMessage == nil || m.currentMessage.text != "Failed to paste from clipboard: no display" {
+		t.Errorf("Expected the paste error, got %+v", m.currentMessage)
+	}
+}
+
+func TestSystemClipboardWithoutTools(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	t.Setenv("TMUX", "")
+	t.Setenv("TERM", "xterm")
+
+	c := newSystemClipboard()
+	if err := c.Copy("hello", false); err != nil {
+		t.Fatalf("Expected copy without a tool to be left to OSC 52, got %v", err)
+	}
+	encoded := base64.StdEncoding.EncodeToString([]byte("hello"))
+	if seq := osc52Sequence("hello", false); !strings.Contains(seq, "\x1b]52;c;"+encoded) {
+		t.Errorf("Expected OSC 52 sequence, got %q", seq)
+	}
+
+	if _, err := c.Paste(false); err == nil {
+		t.Error("Expected paste to fail without a clipboard tool")
+	}
+}
+
+func TestClipboardFromNormalCommand(t *testing.T) {
+	cb := &fakeClipboard{content: map[bool]string{}}
+	m := initialModel()
+	m.clipboard = cb
+	m.buffers[0] = newBuffer(m.style, bufferWithContent("", "first\nsecond"))
+
+	for _, command := range []string{`2normal "+yy`, `g/sec/normal "+yy`} {
+		cb.content[false] = ""
+		m.mode = ModeCommand
+		m.commandBuffer = command
+		res, cmd := m.updateCommand(tea.KeyMsg{Type: tea.KeyEnter})
+		runBatch(res.(model), cmd)
+		if cb.content[false] != "second\n" {
+			t.Errorf("Expected :%s to copy to the clipboard, got %q", command, cb.content[false])
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func bufferListModel(names ...string) model {
+	m := initialModel()
+	m.buffers = nil
+	for _, name := range names {
+		m.buffers = append(m.buffers, newBuffer(m.style, bufferStateSavedOpt, bufferWithContent(name, name)))
+	}
+	return m
+}
+
+func bufferNames(m model) []string {
+	var names []string
+	for _, b := range m.buffers {
+		names = append(names, b.filename)
+	}
+	return names
+}
+
+func TestSwitchBuffer(t *testing.T) {
+	tests := []struct {
+		arg     string
+		want    int
+		message string
+	}{
+		{"2", 1, ""},
+		{"4", 0, "Buffer 4 does not exist"},
+		{"model", 1, ""},
+		{"mode", 2, ""},
+		{"go", 0, "More than one match for go"},
+		{"xyz", 0, "No matching buffer for xyz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			m := bufferListModel("main.go", "model.go", "mode")
+			m = runCommand(m, "b "+tt.arg)
+			if m.currBuffer != tt.want {
+				t.Errorf("Expected buffer %d, got %d", tt.want, m.currBuffer)
+			}
+			if tt.message != "" && (m.currentMessage == nil || m.currentMessage.text != tt.message) {
+				t.Errorf("Expected message %q, got %v", tt.message, m.currentMessage)
+			}
+		})
+	}
+}
+
+func TestCompleteBufferNames(t *testing.T) {
+	m := bufferListModel("main.go", "model.go", "mode.go")
+	m.mode = ModeCommand
+	m = pressKeys(m, runeKeys("b mod")...)
+
+	var got []string
+	if c := m.newCompletion(); c != nil {
+		got = c.candidates
+	}
+	if want := []string{"model.go", "mode.go"}; !slices.Equal(got, want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestListBuffers(t *testing.T) {
+	m := bufferListModel("main.go", "model.go")
+	m.buffers[1] = m.buffers[1].SetStateModified()
+
+	m = runCommand(m, "ls")
+	if m.mode != ModeList || len(m.list.items) != 2 {
+		t.Fatalf("Expected a list of the buffers, got %s", m.mode)
+	}
+	if got := m.list.items[0].label; !strings.Contains(got, "1 %") || !strings.Contains(got, `"main.go"`) || !strings.Contains(got, "line 1") {
+		t.Errorf("Expected the current buffer to be marked, got %q", got)
+	}
+	if got := m.list.items[1].label; !strings.Contains(got, "2  +") {
+		t.Errorf("Expected the modified buffer to be marked, got %q", got)
+	}
+	if got := m.list.items[0].preview; !slices.Equal(got, []string{"main.go"}) {
+		t.Errorf("Expected the lines from the cursor in the preview, got %q", got)
+	}
+
+	m = pressKeys(m, runeKeys("j")...)
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.currBuffer != 1 {
+		t.Errorf("Expected enter to switch to the selected buffer, got %d", m.currBuffer)
+	}
+}
+
+func TestDeleteBuffer(t *testing.T) {
+	m := bufferListModel("a", "b", "c")
+	m.currBuffer = 1
+	m.buffers[1] = m.buffers[1].SetStateModified()
+
+	m = runCommand(m, "bd")
+	if len(m.buffers) != 3 || m.currentMessage == nil || m.currentMessage.msgType != MessageError {
+		t.Fatalf("Expected a modified buffer not to be deleted")
+	}
+
+	m = runCommand(m, "bd!")
+	if got := bufferNames(m); !slices.Equal(got, []string{"a", "c"}) || m.currBuffer != 1 {
+		t.Errorf("Expected b to be deleted and c to be current, got %q and %d", got, m.currBuffer)
+	}
+
+	m = runCommand(m, "bd 1")
+	if got := bufferNames(m); !slices.Equal(got, []string{"c"}) || m.currBuffer != 0 {
+		t.Errorf("Expected a to be deleted, got %q and %d", got, m.currBuffer)
+	}
+
+	m = runCommand(m, "bd")
+	if len(m.buffers) != 1 || m.buffers[0].filename != "" {
+		t.Errorf("Expected an empty buffer after deleting the last one, got %q", bufferNames(m))
+	}
+}
+
+func TestOpenReusesBuffer(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file.txt")
+	if err := os.WriteFile(file, []byte("one"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := initialModel()
+	m = runCommand(m, "e "+file)
+	m = runCommand(m, "bf")
+	m = runCommand(m, "e "+file)
+	if len(m.buffers) != 2 || m.currBuffer != 1 {
+		t.Errorf("Expected the open buffer to be reused, got %d buffers", len(m.buffers))
+	}
+
+	m = runCommand(m, "e "+filepath.Join(dir, "new.txt"))
+	if len(m.buffers) != 3 || m.CurrentBuffer().filename != filepath.Join(dir, "new.txt") {
+		t.Errorf("Expected a new buffer for a missing file")
+	}
+}
+
+func TestReloadBuffer(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file.txt")
+	if err := os.WriteFile(file, []byte("one\ntwo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := initialModel()
+	m = runCommand(m, "e "+file)
+	m = pressKeys(m, runeKeys("dd")...)
+	m = runCommand(m, "e!")
+	if got := m.CurrentBuffer().Lines(); !slices.Equal(got, []string{"one", "two"}) {
+		t.Errorf("Expected the file to be reloaded, got %q", got)
+	}
+	if m.CurrentBuffer().state != bufferStateSaved {
+		t.Errorf("Expected the buffer not to be modified, got %s", m.CurrentBuffer().state)
+	}
+
+	m = pressKeys(m, runeKeys("u")...)
+	if got := m.CurrentBuffer().Lines(); !slices.Equal(got, []string{"two"}) {
+		t.Errorf("Expected the reload to be undoable, got %q", got)
+	}
+}
@@ -1,6 +1,12 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
 	tea "github.com/charmbracelet/bubbletea"
 )
 
@@ -17,7 +23,7 @@ func (c commandBufferNext) Update(m model, msg tea.Msg, args []string) (model, t
 	m.currBuffer = (m.currBuffer + 1) % len(m.buffers)
 	
 	// Update viewport for the new buffer
-	m.buffers[m.currBuffer].viewport = m.viewport
+	m.buffers[m.currBuffer].viewport = m.windowSize()
 	
 	// Clear command buffer and switch to normal mode
 	m.commandBuffer = ""
@@ -43,7 +49,7 @@ func (c commandBufferPrev) Update(m model, msg tea.Msg, args []string) (model, t
 	m.currBuffer = (m.currBuffer - 1 + len(m.buffers)) % len(m.buffers)
 	
 	// Update viewport for the new buffer
-	m.buffers[m.currBuffer].viewport = m.viewport
+	m.buffers[m.currBuffer].viewport = m.windowSize()
 	
 	// Clear command buffer and switch to normal mode
 	m.commandBuffer = ""
@@ -69,7 +75,7 @@ func (c commandBufferLast) Update(m model, msg tea.Msg, args []string) (model, t
 	m.currBuffer = len(m.buffers) - 1
 	
 	// Update viewport for the new buffer
-	m.buffers[m.currBuffer].viewport = m.viewport
+	m.buffers[m.currBuffer].viewport = m.windowSize()
 	
 	// Clear command buffer and switch to normal mode
 	m.commandBuffer = ""
@@ -95,7 +101,7 @@ func (c commandBufferFirst) Update(m model, msg tea.Msg, args []string) (model,
 	m.currBuffer = 0
 	
 	// Update viewport for the new buffer
-	m.buffers[m.currBuffer].viewport = m.viewport
+	m.buffers[m.currBuffer].viewport = m.windowSize()
 	
 	// Clear command buffer and switch to normal mode
 	m.commandBuffer = ""
@@ -106,4 +112,170 @@ func (c commandBufferFirst) Update(m model, msg tea.Msg, args []string) (model,
 
 func (c commandBufferFirst) Aliases() []string {
 	return []string{"bfirst", "bf"}
-} 
\ No newline at end of file
+}
+
+// commandBufferSwitch is :b {N} or :b {name}, switching to the buffer with
+// the number or the only one whose name contains the text.
+type commandBufferSwitch struct {
+}
+
+func (c commandBufferSwitch) Update(m model, msg tea.Msg, args []string) (model, tea.Cmd) {
+	m.commandBuffer = ""
+	m.mode = ModeNormal
+
+	name := strings.Join(args, " ")
+	if name == "" {
+		return m, nil
+	}
+
+	i, err := m.findBufferArg(name)
+	if err != nil {
+		return m.SetErrorMessage(err.Error()), nil
+	}
+	return m.selectBuffer(i), nil
+}
+
+func (c commandBufferSwitch) Aliases() []string {
+	return []string{"buffer", "b"}
+}
+
+// Complete returns the names of the buffers containing the argument.
+func (c commandBufferSwitch) Complete(m model, arg string) []string {
+	var names []string
+	for _, b := range m.buffers {
+		if b.filename != "" && strings.Contains(b.filename, arg) {
+			names = append(names, b.filename)
+		}
+	}
+	return names
+}
+
+// commandBufferList is :ls, listing the buffers with their number, flags,
+// name and cursor line. % marks the current buffer and + a modified one.
+// Selecting a buffer in the list switches to it.
+type commandBufferList struct {
+}
+
+func (c commandBufferList) Update(m model, msg tea.Msg, args []string) (model, tea.Cmd) {
+	m.commandBuffer = ""
+	m.mode = ModeNormal
+
+	var items []listItem
+	for i, b := range m.buffers {
+		current, modified := " ", " "
+		if i == m.currBuffer {
+			current = "%"
+		}
+		if b.state == bufferStateModified {
+			modified = "+"
+		}
+		name := b.filename
+		if name == "" {
+			name = "[No Name]"
+		}
+
+		items = append(items, listItem{
+			label:   fmt.Sprintf("%3d %s%s %-30q line %d", i+1, current, modified, name, b.cursorY+1),
+			preview: b.LineSlice(b.cursorY, b.cursorY+previewLines),
+		})
+	}
+
+	return m.OpenList("Buffers", items, m.currBuffer, func(m model, i int) (model, tea.Cmd) {
+		return m.selectBuffer(i), nil
+	}), nil
+}
+
+func (c commandBufferList) Aliases() []string {
+	return []string{"ls", "buffers", "files"}
+}
+
+// commandBufferDelete is :bd [N|name], closing the buffer. A modified buffer
+// is only closed with :bd!.
+type commandBufferDelete struct {
+	force bool
+}
+
+func (c commandBufferDelete) Update(m model, msg tea.Msg, args []string) (model, tea.Cmd) {
+	m.commandBuffer = ""
+	m.mode = ModeNormal
+
+	i := m.currBuffer
+	if name := strings.Join(args, " "); name != "" {
+		var err error
+		if i, err = m.findBufferArg(name); err != nil {
+			return m.SetErrorMessage(err.Error()), nil
+		}
+	}
+
+	if !c.force && m.buffers[i].state == bufferStateModified {
+		return m.SetErrorMessage(fmt.Sprintf("No write since last change for buffer %d (add ! to override)", i+1)), nil
+	}
+
+	return m.deleteBuffer(i), nil
+}
+
+func (c commandBufferDelete) Aliases() []string {
+	if c.force {
+		return []string{"bdelete!", "bd!"}
+	}
+	return []string{"bdelete", "bd"}
+}
+
+func (c commandBufferDelete) Complete(m model, arg string) []string {
+	return commandBufferSwitch{}.Complete(m, arg)
+}
+
+// previewLines is the number of lines shown in the preview of :ls.
+const previewLines = 20
+
+// selectBuffer makes the buffer with the index the current one.
+func (m model) selectBuffer(i int) model {
+	m.currBuffer = i
+	m.buffers[m.currBuffer].viewport = m.windowSize()
+	return m
+}
+
+// deleteBuffer removes the buffer. The buffer after it becomes the current
+// one when it was current. Deleting the last buffer leaves an empty one.
+func (m model) deleteBuffer(i int) model {
+	// The slice is shared with other copies of the model
+	m.buffers = slices.Delete(slices.Clone(m.buffers), i, i+1)
+	if len(m.buffers) == 0 {
+		m.buffers = []buffer{newBuffer(m.style)}
+	}
+
+	if i < m.currBuffer {
+		m.currBuffer--
+	}
+	m = m.selectBuffer(min(m.currBuffer, len(m.buffers)-1))
+	return m.windowsBufferDeleted(i)
+}
+
+// findBufferArg returns the buffer with the number, or the only buffer whose
+// name contains the text. An exact name wins over partial matches.
+func (m model) findBufferArg(arg string) (int, error) {
+	if n, err := strconv.Atoi(arg); err == nil {
+		if n < 1 || n > len(m.buffers) {
+			return 0, fmt.Errorf("Buffer %d does not exist", n)
+		}
+		return n - 1, nil
+	}
+
+	var matches []int
+	for i, b := range m.buffers {
+		if b.filename == arg {
+			return i, nil
+		}
+		if b.filename != "" && strings.Contains(b.filename, arg) {
+			matches = append(matches, i)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return 0, errors.New("No matching buffer for " + arg)
+	case 1:
+		return matches[0], nil
+	}
+	return 0, errors.New("More than one match for " + arg)
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// completer is a command that can complete its argument in the command
+// line.
+type completer interface {
+	Complete(m model, arg string) []string
+}
+
+// completionState is the tab completion in progress. Tab cycles through
+// the candidates and then back to the original text.
+type completionState struct {
+	candidates []string
+	// index is the candidate in the command line, len(candidates) stands
+	// for the original text
+	index int
+	// start is where the completed word starts in the command line
+	start    int
+	original string
+	// line is the command line with the current candidate. Once it's
+	// edited a new completion starts.
+	line string
+}
+
+// completeCommandLine completes the word before the cursor, or cycles to
+// the next (or previous with back) candidate.
+func (m model) completeCommandLine(back bool) model {
+	c := m.completion
+	if c == nil || c.line != m.commandBuffer {
+		c = m.newCompletion()
+		if c == nil {
+			return m
+		}
+		c.index = len(c.candidates)
+	}
+
+	step := 1
+	if back {
+		step = -1
+	}
+	c.index = (c.index + step + len(c.candidates) + 1) % (len(c.candidates) + 1)
+
+	word := c.original
+	if c.index < len(c.candidates) {
+		word = c.candidates[c.index]
+	}
+
+	// The word being replaced always ends at the cursor
+	cur := min(m.commandCursor, len(m.commandBuffer))
+	m.commandBuffer = m.commandBuffer[:c.start] + word + m.commandBuffer[cur:]
+	m.commandCursor = c.start + len(word)
+	c.line = m.commandBuffer
+
+	m.completion = c
+	if len(c.candidates) == 1 {
+		// Tab continues from the completed word, like in a directory
+		m.completion = nil
+	}
+	return m
+}
+
+// newCompletion finds the candidates for the word before the cursor. The
+// first word is completed to a command name, the arguments are completed by
+// the command: file paths and buffer names. The editor has no options, so
+// there are no option names to complete.
+func (m model) newCompletion() *completionState {
+	text := m.commandBuffer[:min(m.commandCursor, len(m.commandBuffer))]
+	_, rest, _, err := m.parseRange(text)
+	if err != nil {
+		return nil
+	}
+	rest = strings.TrimLeft(rest, " ")
+	offset := len(text) - len(rest)
+
+	var word string
+	var candidates []string
+	if name, args, found := strings.Cut(rest, " "); found {
+		i := strings.LastIndex(args, " ")
+		word = args[i+1:]
+		offset += len(name) + 1 + i + 1
+		if c, ok := m.findCommand(name).(completer); ok {
+			candidates = c.Complete(m, word)
+		}
+	} else {
+		if strings.ContainsFunc(rest, func(r rune) bool { return !unicode.IsLetter(r) && r != '!' }) {
+			return nil
+		}
+		word = rest
+		candidates = m.commandNames(word)
+	}
+
+	if len(candidates) == 0 {
+		return nil
+	}
+	return &completionState{candidates: candidates, start: offset, original: word}
+}
+
+// findCommand returns the command with the alias, or nil.
+func (m model) findCommand(name string) command {
+	for _, c := range m.commands {
+		if slices.Contains(c.Aliases(), name) {
+			return c
+		}
+	}
+	return nil
+}
+
+// commandNames returns the aliases of all commands starting with the
+// prefix.
+func (m model) commandNames(prefix string) []string {
+	var names []string
+	for _, c := range m.commands {
+		for _, alias := range c.Aliases() {
+			if strings.HasPrefix(alias, prefix) {
+				names = append(names, alias)
+			}
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// completeFiles returns the files and directories starting with the path.
+// Directories end with a slash, hidden files are only shown when the name
+// starts with a dot.
+func completeFiles(path string) []string {
+	dir, prefix := filepath.Split(path)
+	readDir := dir
+	if readDir == "" {
+		readDir = "."
+	}
+
+	entries, err := os.ReadDir(readDir)
+	if err != nil {
+		return nil
+	}
+
+	var files []string
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasPrefix(name, prefix) || (strings.HasPrefix(name, ".") && !strings.HasPrefix(prefix, ".")) {
+			continue
+		}
+		if e.IsDir() {
+			name += string(filepath.Separator)
+		}
+		files = append(files, dir+name)
+	}
+	return files
+}
+
+// View renders the candidates in a single line with the current one
+// highlighted.
+func (c *completionState) View(style editorStyle, width int) string {
+	var line strings.Builder
+	used := 0
+	for i, candidate := range c.candidates {
+		if used+len(candidate)+2 > width && width > 0 {
+			line.WriteString(style.statusBar.Render(">"))
+			break
+		}
+
+		s := style.statusBar
+		if i == c.index {
+			s = style.selection
+		}
+		line.WriteString(s.Render(candidate) + style.statusBar.Render("  "))
+		used += len(candidate) + 2
+	}
+	if used < width {
+		line.WriteString(style.statusBar.Render(strings.Repeat(" ", width-used)))
+	}
+	return line.String()
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// commandDiagnostics is :diagnostics, listing the diagnostics of all the
+// buffers.
+type commandDiagnostics struct {
+}
+
+func (c commandDiagnostics) Update(m model, msg tea.Msg, args []string) (model, tea.Cmd) {
+	m.commandBuffer = ""
+	m.mode = ModeNormal
+
+	type location struct {
+		buffer int
+		pos    position
+	}
+	var items []listItem
+	var locations []location
+	for i, b := range m.buffers {
+		if len(b.diagnostics) == 0 {
+			continue
+		}
+		lines := b.Lines()
+		for _, d := range b.diagnostics {
+			first := min(max(d.start.line-grepContextLines, 0), len(lines))
+			items = append(items, listItem{
+				label:   fmt.Sprintf("%s:%d:%d: %s: %s", b.filename, d.start.line+1, d.start.col+1, d.severity, d.message),
+				preview: lines[first:min(first+previewLines, len(lines))],
+			})
+			locations = append(locations, location{buffer: i, pos: d.start})
+		}
+	}
+	if len(items) == 0 {
+		return m.SetInfoMessage("No diagnostics"), nil
+	}
+
+	title := fmt.Sprintf("Diagnostics: %d", len(items))
+	return m.OpenList(title, items, 0, func(m model, i int) (model, tea.Cmd) {
+		l := locations[i]
+		m = m.selectBuffer(l.buffer)
+		m.buffers[m.currBuffer] = m.buffers[m.currBuffer].moveCursorTo(l.pos.col, l.pos.line)
+		return m, nil
+	}), nil
+}
+
+func (c commandDiagnostics) Aliases() []string {
+	return []string{"diagnostics", "diag"}
+}
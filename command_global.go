@@ -0,0 +1,187 @@
+package main
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// commandGlobal is :[range]g/pattern/cmd, running the ex command cmd on
+// every line matching the pattern. With invert, :v and :g!, it runs on the
+// lines that don't match.
+type commandGlobal struct {
+	invert bool
+}
+
+func (c commandGlobal) Update(m model, msg tea.Msg, args []string) (model, tea.Cmd) {
+	return c.UpdateRange(m, msg, lineRange{}, args)
+}
+
+func (c commandGlobal) UpdateRange(m model, msg tea.Msg, r lineRange, args []string) (model, tea.Cmd) {
+	m.commandBuffer = ""
+	m.mode = ModeNormal
+
+	if m.inGlobal {
+		return m.SetErrorMessage("Cannot do :global recursive"), nil
+	}
+	if !r.given {
+		r = lineRange{start: 0, end: m.CurrentBuffer().NoOfLines() - 1}
+	}
+
+	arg := strings.Join(args, " ")
+	if arg == "" {
+		return m.SetErrorMessage("Regular expression missing from :global"), nil
+	}
+	delim := []rune(arg)[0]
+	if unicode.IsLetter(delim) || unicode.IsDigit(delim) || unicode.IsSpace(delim) || delim == '\\' || delim > unicode.MaxASCII {
+		return m.SetErrorMessage("Invalid delimiter: " + string(delim)), nil
+	}
+
+	pattern, cmdLine := splitPattern(arg)
+	if pattern == "" {
+		pattern = m.search.pattern
+	}
+	if pattern == "" {
+		return m.SetErrorMessage("No previous regular expression"), nil
+	}
+	if cmdLine == "" {
+		return m.SetErrorMessage("Command required"), nil
+	}
+	re, err := compileSearch(pattern)
+	if err != nil {
+		return m.SetErrorMessage("Invalid pattern: " + err.Error()), nil
+	}
+	m.search.pattern = pattern
+	m.search.wholeWord = false
+
+	// Lines are selected first, so commands adding lines don't run on them
+	b := m.CurrentBuffer()
+	var lines []int
+	for y := r.start; y <= r.end; y++ {
+		if re.MatchString(b.Line(y)) != c.invert {
+			lines = append(lines, y)
+		}
+	}
+	if len(lines) == 0 {
+		return m.SetErrorMessage("Pattern not found: " + pattern), nil
+	}
+
+	m.inGlobal = true
+	cur, count := m.currBuffer, len(m.buffers)
+	b = m.buffers[cur].beginUndoStep()
+	b.globalLines = trackedLines{lines: lines}
+	m.buffers[cur] = b
+
+	// The commands move the lines left as they insert and delete lines
+	var switched bool
+	var cmds []tea.Cmd
+	for m.buffers[cur].globalLines.len() > 0 {
+		b = m.buffers[cur]
+		var y int
+		y, b.globalLines = b.globalLines.next()
+		m.buffers[cur] = b.moveCursorTo(0, y)
+		var cmd tea.Cmd
+		m, cmd = m.runCommandLine(cmdLine, msg)
+		cmds = append(cmds, cmd)
+		if m.currBuffer != cur || len(m.buffers) != count {
+			switched = true
+			break
+		}
+	}
+
+	// Closing a buffer moves the ones after it, the buffer :g ran in is the
+	// one with its undo step still open. It's gone when it was closed.
+	for i := range m.buffers {
+		if m.buffers[i].history.depth > 0 {
+			b = m.buffers[i]
+			b.globalLines = trackedLines{}
+			m.buffers[i] = b.commitUndoStep()
+		}
+	}
+	m.inGlobal = false
+	m.commandBuffer = ""
+	m.mode = ModeNormal
+	if switched {
+		return m.SetErrorMessage("Cannot switch buffers in :global"), tea.Batch(cmds...)
+	}
+	return m, tea.Batch(cmds...)
+}
+
+func (c commandGlobal) Aliases() []string {
+	if c.invert {
+		return []string{"vglobal", "v", "global!", "g!"}
+	}
+	return []string{"global", "g"}
+}
+
+// trackedLines are line numbers kept up to date while lines are inserted
+// and deleted, like the lines :g has still to run on. The numbers are sorted
+// and a deleted line is dropped. They are stored without the offset, which
+// moves all of them at once when the change is before the first one.
+type trackedLines struct {
+	lines  []int
+	offset int
+}
+
+func (t trackedLines) len() int {
+	return len(t.lines)
+}
+
+// next removes the first line and returns it.
+func (t trackedLines) next() (int, trackedLines) {
+	y := t.lines[0] + t.offset
+	t.lines = t.lines[1:]
+	return y, t
+}
+
+// search returns the index of the first line at or after y.
+func (t trackedLines) search(y int) int {
+	return sort.Search(len(t.lines), func(i int) bool { return t.lines[i]+t.offset >= y })
+}
+
+// inserted moves the lines after a line inserted at y.
+func (t trackedLines) inserted(y int) trackedLines {
+	i := t.search(y)
+	switch {
+	case i == len(t.lines):
+		return t
+	case i == 0:
+		t.offset++
+		return t
+	}
+
+	// Other copies of the buffer share the slice
+	t.lines = slices.Clone(t.lines)
+	for j := i; j < len(t.lines); j++ {
+		t.lines[j]++
+	}
+	return t
+}
+
+// deleted drops the line y and moves the ones after it.
+func (t trackedLines) deleted(y int) trackedLines {
+	i := t.search(y)
+	if i == len(t.lines) {
+		return t
+	}
+	drop := t.lines[i]+t.offset == y
+	if i == 0 {
+		if drop {
+			t.lines = t.lines[1:]
+		}
+		t.offset--
+		return t
+	}
+
+	t.lines = slices.Clone(t.lines)
+	if drop {
+		t.lines = slices.Delete(t.lines, i, i+1)
+	}
+	for j := i; j < len(t.lines); j++ {
+		t.lines[j]--
+	}
+	return t
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestGlobal(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		command string
+		want    []string
+		message string
+	}{
+		{"delete matching", "TODO a\nb\nTODO c\nd", "g/TODO/d", []string{"b", "d"}, ""},
+		{"delete consecutive", "x\nx\nx\ny", "g/x/d", []string{"y"}, ""},
+		{"delete equal lines", "a\na\na", "g/a/d", []string{""}, ""},
+		{"delete not matching", "TODO a\nb\nTODO c\nd", "v/TODO/d", []string{"TODO a", "TODO c"}, ""},
+		{"bang inverts", "a\nb", "g!/a/d", []string{"a"}, ""},
+		{"delete next line", "x\n1\nx\n2\n3", "g/x/+1d", []string{"x", "x", "3"}, ""},
+		{"delete previous line", "1\nx\n2\nx", "g/x/-1d", []string{"x", "x"}, ""},
+		{"delete after next match", "a\na\nb\na\nc", "g/a/+2d", []string{"a", "a", "a"}, ""},
+		{"normal", "func a\nb\nfunc c", "g/^func/normal A // reviewed", []string{"func a // reviewed", "b", "func c // reviewed"}, ""},
+		{"normal adding lines", "x\nx", "g/x/normal oy", []string{"x", "y", "x", "y"}, ""},
+		{"added lines are skipped", "x\ny", "g/x/t.", []string{"x", "x", "y"}, ""},
+		{"reverse lines", "1\n2\n3", "g/^/m0", []string{"3", "2", "1"}, ""},
+		{"copy to the end", "x\ny\nx", "g/x/t$", []string{"x", "y", "x", "x", "x"}, ""},
+		{"substitute", "a x\nb\na y", "g/a/s/a/b/", []string{"b x", "b", "b y"}, ""},
+		{"range", "x\nx\nx", "2,3g/x/d", []string{"x"}, ""},
+		{"other delimiter", "a/b\nc", "g#/#d", []string{"c"}, ""},
+		{"not found", "a", "g/x/d", []string{"a"}, "Pattern not found: x"},
+		{"missing command", "a", "g/a/", []string{"a"}, "Command required"},
+		{"recursive", "a", "g/a/g/a/d", []string{"a"}, "Cannot do :global recursive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := initialModel()
+			m.buffers[0] = newBuffer(m.style, bufferWithContent("", tt.content))
+
+			m = runCommand(m, tt.command)
+			if got := m.CurrentBuffer().Lines(); !slices.Equal(got, tt.want) {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+			if tt.message != "" && (m.currentMessage == nil || m.currentMessage.text != tt.message) {
+				t.Errorf("Expected message %q, got %v", tt.message, m.currentMessage)
+			}
+			if m.mode != ModeNormal || m.inGlobal {
+				t.Errorf("Expected :g to finish in normal mode, got %s", m.mode)
+			}
+		})
+	}
+}
+
+func TestGlobalIsOneUndoStep(t *testing.T) {
+	m := initialModel()
+	m.buffers[0] = newBuffer(m.style, bufferWithContent("", "x\na\nx\nb"))
+
+	m = runCommand(m, "g/x/normal Ay")
+	m = runCommand(m, "g/y/d")
+	if got := m.CurrentBuffer().Lines(); !slices.Equal(got, []string{"a", "b"}) {
+		t.Fatalf("Expected the lines to be deleted, got %q", got)
+	}
+
+	m = pressKeys(m, runeKeys("u")...)
+	if got := m.CurrentBuffer().Lines(); !slices.Equal(got, []string{"xy", "a", "xy", "b"}) {
+		t.Errorf("Expected a single undo to revert :g/y/d, got %q", got)
+	}
+	m = pressKeys(m, runeKeys("u")...)
+	if got := m.CurrentBuffer().Lines(); !slices.Equal(got, []string{"x", "a", "x", "b"}) {
+		t.Errorf("Expected a single undo to revert :g/x/normal, got %q", got)
+	}
+}
+
+func TestTrackedLines(t *testing.T) {
+	l := trackedLines{lines: []int{2, 5, 8}}
+	l = l.inserted(0)  // 3, 6, 9
+	l = l.deleted(6)   // 3, 8
+	l = l.inserted(4)  // 3, 9
+	l = l.deleted(1)   // 2, 8
+	l = l.inserted(20) // 2, 8
+
+	var got []int
+	for l.len() > 0 {
+		var y int
+		y, l = l.next()
+		got = append(got, y)
+	}
+	if !slices.Equal(got, []int{2, 8}) {
+		t.Errorf("Expected [2 8], got %v", got)
+	}
+}
+
+func TestGlobalStopsWhenBufferChanges(t *testing.T) {
+	newModel := func() model {
+		m := initialModel()
+		m.buffers[0] = newBuffer(m.style, bufferWithContent("", "x\nx\ny"))
+		return m.addBuffer(newBuffer(m.style, bufferWithContent("", "other")))
+	}
+	checkStopped := func(t *testing.T, m model) {
+		t.Helper()
+		if m.currentMessage == nil || m.currentMessage.text != "Cannot switch buffers in :global" {
+			t.Errorf("Expected an error, got %+v", m.currentMessage)
+		}
+		for i, b := range m.buffers {
+			if b.history.depth != 0 {
+				t.Errorf("Expected the undo step of buffer %d to be committed", i+1)
+			}
+		}
+	}
+
+	m := runCommand(newModel(), "g/x/bn")
+	checkStopped(t, m)
+	// Later changes in the buffer are undone one at a time
+	m = m.selectBuffer(0)
+	m = pressKeys(m, runeKeys("dddd")...)
+	m = pressKeys(m, runeKeys("u")...)
+	if got := m.CurrentBuffer().NoOfLines(); got != 2 {
+		t.Errorf("Expected undo to restore a single line, got %d lines", got)
+	}
+
+	m = runCommand(newModel(), "g/x/bd!")
+	checkStopped(t, m)
+	if len(m.buffers) != 1 || m.CurrentBuffer().Line(0) != "other" {
+		t.Errorf("Expected the other buffer left, got %d buffers", len(m.buffers))
+	}
+}
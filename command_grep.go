@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// grepSearch is the :grep in progress. Its results are shown in the list as
+// they come.
+type grepSearch struct {
+	pattern string
+	results []grepResult
+	// batches delivers the results, it's closed when the search finishes.
+	// cancel stops the search.
+	batches <-chan []grepResult
+	cancel  context.CancelFunc
+	running bool
+	// truncated is set when the search stopped at grepMaxResults
+	truncated bool
+}
+
+// grepResultMsg carries the results found by :grep.
+type grepResultMsg struct {
+	batches <-chan []grepResult
+	results []grepResult
+	done    bool
+}
+
+// commandGrep is :grep pattern [path]. A pattern with spaces can be put
+// between slashes, like in :grep /func main/ cmd.
+type commandGrep struct {
+}
+
+func (c commandGrep) Update(m model, msg tea.Msg, args []string) (model, tea.Cmd) {
+	m.commandBuffer = ""
+	m.mode = ModeNormal
+
+	line := strings.Join(args, " ")
+	pattern, root, _ := strings.Cut(line, " ")
+	if strings.HasPrefix(line, "/") {
+		pattern, root = splitPattern(line)
+	}
+	root = strings.TrimSpace(root)
+	if root == "" {
+		root = "."
+	}
+	if pattern == "" {
+		return m.SetErrorMessage("Pattern required"), nil
+	}
+
+	re, err := compileSearch(pattern)
+	if err != nil {
+		return m.SetErrorMessage("Invalid pattern: " + err.Error()), nil
+	}
+
+	return m.startGrep(pattern, root, re)
+}
+
+func (c commandGrep) Aliases() []string {
+	return []string{"grep", "gr"}
+}
+
+// startGrep runs the search in the background and opens the list of its
+// results.
+func (m model) startGrep(pattern, root string, re *regexp.Regexp) (model, tea.Cmd) {
+	ctx, cancel := context.WithCancel(context.Background())
+	batches := make(chan []grepResult)
+	go grepProject(ctx, root, re, batches)
+
+	m = m.OpenList("", nil, 0, func(m model, i int) (model, tea.Cmd) {
+		return m.openGrepResult(i)
+	})
+	m.grep = &grepSearch{pattern: pattern, batches: batches, cancel: cancel, running: true}
+	m.list.title = m.grep.title()
+	return m, waitForGrepResults(batches)
+}
+
+// waitForGrepResults reads the next batch of the search.
+func waitForGrepResults(batches <-chan []grepResult) tea.Cmd {
+	return func() tea.Msg {
+		results, ok := <-batches
+		return grepResultMsg{batches: batches, results: results, done: !ok}
+	}
+}
+
+// updateGrepResults adds the results to the list. Messages of a search that
+// was stopped are dropped.
+func (m model) updateGrepResults(msg grepResultMsg) (model, tea.Cmd) {
+	if m.grep == nil || m.grep.batches != msg.batches || !m.grep.running {
+		return m, nil
+	}
+
+	g := *m.grep
+	if msg.done {
+		g.running = false
+		m.grep = &g
+		return m.updateGrepList(), nil
+	}
+
+	g.results = append(g.results, msg.results...)
+	if len(g.results) >= grepMaxResults {
+		g.cancel()
+		g.results = g.results[:grepMaxResults]
+		g.running = false
+		g.truncated = true
+		m.grep = &g
+		return m.updateGrepList(), nil
+	}
+	m.grep = &g
+	return m.updateGrepList(), waitForGrepResults(msg.batches)
+}
+
+// updateGrepList shows the results in the list, if it's still open.
+func (m model) updateGrepList() model {
+	if m.mode != ModeList || m.list == nil || len(m.list.items) > len(m.grep.results) {
+		return m
+	}
+
+	l := *m.list
+	l.title = m.grep.title()
+	for _, r := range m.grep.results[len(l.items):] {
+		l.items = append(l.items, listItem{
+			label:   fmt.Sprintf("%s:%d:%d: %s", r.file, r.line+1, r.col+1, strings.TrimSpace(r.text)),
+			preview: r.preview,
+		})
+	}
+	m.list = &l
+	return m
+}
+
+// stopGrep cancels the search. The results found so far are kept.
+func (m model) stopGrep() model {
+	if m.grep == nil || !m.grep.running {
+		return m
+	}
+
+	m.grep.cancel()
+	g := *m.grep
+	g.running = false
+	m.grep = &g
+	return m.updateGrepList()
+}
+
+func (g grepSearch) title() string {
+	title := fmt.Sprintf("grep %s: %d result%s", g.pattern, len(g.results), plural(len(g.results)))
+	if g.truncated {
+		title += " (truncated)"
+	}
+	if g.running {
+		title += ", searching… (ctrl-c to stop)"
+	}
+	return title
+}
+
+// openGrepResult opens the file of the result with the cursor on the match.
+func (m model) openGrepResult(i int) (model, tea.Cmd) {
+	r := m.grep.results[i]
+	m, err := m.openFile(filepath.Clean(r.file))
+	if err != nil {
+		return m.SetErrorMessage("Failed to open file: " + err.Error()), nil
+	}
+
+	b := m.buffers[m.currBuffer]
+	m.buffers[m.currBuffer] = b.moveCursorTo(r.col, r.line)
+	return m, nil
+}
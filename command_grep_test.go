@@ -0,0 +1,134 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// runGrep runs :grep and waits for the search to finish.
+func runGrep(m model, line string) model {
+	m.mode = ModeCommand
+	m.commandBuffer = line
+	res, cmd := m.updateCommand(tea.KeyMsg{Type: tea.KeyEnter})
+	return runCmds(res.(model), cmd)
+}
+
+func grepTestDir(t *testing.T) string {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".gitignore":       "ignored/\n",
+		"main.go":          "package main\n\nfunc main() {\n\tprintln(\"hello world\")\n}\n",
+		"lib/util.go":      "package lib\n\n// Hello says hello\nfunc Hello() {}\n",
+		"ignored/hello.go": "hello",
+		"data.bin":         "hello\x00world",
+	})
+	return dir
+}
+
+func TestGrep(t *testing.T) {
+	dir := grepTestDir(t)
+	m := runGrep(initialModel(), "grep hello "+dir)
+
+	if m.mode != ModeList || m.grep.running {
+		t.Fatalf("Expected the finished search in the list, got %s", m.mode)
+	}
+
+	var got []string
+	for _, item := range m.list.items {
+		got = append(got, strings.TrimPrefix(item.label, dir+"/"))
+	}
+	slices.Sort(got)
+	want := []string{
+		"lib/util.go:3:4: // Hello says hello",
+		"lib/util.go:4:6: func Hello() {}",
+		"main.go:4:11: println(\"hello world\")",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if !strings.Contains(m.list.title, "3 results") {
+		t.Errorf("Expected the number of results in the title, got %q", m.list.title)
+	}
+}
+
+func TestGrepOpensResult(t *testing.T) {
+	dir := grepTestDir(t)
+	m := runGrep(initialModel(), "grep /hello world/ "+filepath.Join(dir, "main.go"))
+	if len(m.list.items) != 1 {
+		t.Fatalf("Expected a single result, got %d", len(m.list.items))
+	}
+
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.CurrentBuffer().filename != filepath.Join(dir, "main.go") {
+		t.Errorf("Expected the file to be opened, got %q", m.CurrentBuffer().filename)
+	}
+	if got := cursorOf(m); got != (position{line: 3, col: 10}) {
+		t.Errorf("Expected the cursor on the match, got %v", got)
+	}
+}
+
+func TestGrepErrors(t *testing.T) {
+	m := runGrep(initialModel(), "grep")
+	if m.currentMessage == nil || m.currentMessage.text != "Pattern required" {
+		t.Errorf("Expected an error, got %v", m.currentMessage)
+	}
+
+	m = runGrep(initialModel(), "grep a(")
+	if m.currentMessage == nil || !strings.HasPrefix(m.currentMessage.text, "Invalid pattern") {
+		t.Errorf("Expected an error, got %v", m.currentMessage)
+	}
+}
+
+func TestGrepCancel(t *testing.T) {
+	dir := grepTestDir(t)
+	m := initialModel()
+	m.mode = ModeCommand
+	m.commandBuffer = "grep hello " + dir
+	res, cmd := m.updateCommand(tea.KeyMsg{Type: tea.KeyEnter})
+
+	m = pressKeys(res.(model), tea.KeyMsg{Type: tea.KeyCtrlC})
+	if m.mode != ModeList || m.grep.running {
+		t.Fatalf("Expected ctrl-c to stop the search and keep the list")
+	}
+
+	m = runCmds(m, cmd)
+	if len(m.list.items) != 0 || strings.Contains(m.list.title, "searching") {
+		t.Errorf("Expected no results after stopping, got %d", len(m.list.items))
+	}
+
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyCtrlC})
+	if m.mode != ModeNormal {
+		t.Errorf("Expected ctrl-c to close the list of a finished search, got %s", m.mode)
+	}
+}
+
+func TestGrepCRLF(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"dos.txt": "one\r\nhello\r\nthree\r\n"})
+	m := runGrep(initialModel(), "grep hello$ "+dir)
+
+	if len(m.grep.results) != 1 {
+		t.Fatalf("Expected a single result, got %d", len(m.grep.results))
+	}
+	r := m.grep.results[0]
+	if r.text != "hello" || !slices.Equal(r.preview, []string{"one", "hello", "three", ""}) {
+		t.Errorf("Expected the lines without \\r, got %q and %q", r.text, r.preview)
+	}
+}
+
+func TestGrepTruncatesResults(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"many.txt": strings.Repeat("hello\n", grepMaxResults+10)})
+	m := runGrep(initialModel(), "grep hello "+dir)
+
+	if len(m.list.items) != grepMaxResults || m.grep.running {
+		t.Fatalf("Expected %d results, got %d", grepMaxResults, len(m.list.items))
+	}
+	if !strings.Contains(m.list.title, "(truncated)") {
+		t.Errorf("Expected the title to say the results are truncated, got %q", m.list.title)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// historySize is the number of command lines kept in the history.
+const historySize = 200
+
+// commandHistory holds the executed command lines, the oldest first. file
+// is where the history is persisted, nothing is written without it.
+type commandHistory struct {
+	entries []string
+	file    string
+
+	// While browsing with up and down, index is the entry in the command
+	// line and prefix is the text typed before browsing started. Only
+	// entries starting with the prefix are shown.
+	browsing bool
+	index    int
+	prefix   string
+}
+
+// WithHistoryFile loads the command line history from the file and saves
+// it there after every command.
+func WithHistoryFile(filename string) modelOption {
+	return func(m *model) {
+		m.history.file = filename
+		if content, err := os.ReadFile(filename); err == nil {
+			m.history.entries = strings.Split(strings.TrimRight(string(content), "\n"), "\n")
+		}
+	}
+}
+
+// historyFile returns the default history file.
+func historyFile() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "goku", "history"), nil
+}
+
+// add appends the line to the history. An older copy of the line is moved
+// to the end.
+func (h commandHistory) add(line string) commandHistory {
+	h.browsing = false
+	if strings.TrimSpace(line) == "" {
+		return h
+	}
+
+	entries := slices.DeleteFunc(slices.Clone(h.entries), func(e string) bool { return e == line })
+	entries = append(entries, line)
+	if len(entries) > historySize {
+		entries = entries[len(entries)-historySize:]
+	}
+	h.entries = entries
+
+	// Losing the history isn't worth interrupting the user for
+	_ = h.save()
+	return h
+}
+
+func (h commandHistory) save() error {
+	if h.file == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(h.file), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(h.file, []byte(strings.Join(h.entries, "\n")+"\n"), 0644)
+}
+
+// browse moves through the entries starting with the prefix, to older ones
+// when older is set. The returned line is the one to show, after the newest
+// entry it's the prefix itself.
+func (h commandHistory) browse(line string, older bool) (commandHistory, string) {
+	if !h.browsing {
+		h.browsing = true
+		h.index = len(h.entries)
+		h.prefix = line
+	}
+
+	step := 1
+	if older {
+		step = -1
+	}
+	for i := h.index + step; i >= 0 && i < len(h.entries); i += step {
+		if strings.HasPrefix(h.entries[i], h.prefix) {
+			h.index = i
+			return h, h.entries[i]
+		}
+	}
+
+	if older {
+		// Stay at the oldest match
+		return h, line
+	}
+	h.index = len(h.entries)
+	return h, h.prefix
+}
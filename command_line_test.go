@@ -0,0 +1,182 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func commandLineModel() model {
+	m := initialModel()
+	m.viewport = tea.WindowSizeMsg{Width: 80, Height: 24}
+	return pressKeys(m, runeKeys(":")...)
+}
+
+func TestCommandLineEditing(t *testing.T) {
+	tests := []struct {
+		name   string
+		keys   []tea.KeyMsg
+		want   string
+		cursor int
+	}{
+		{"typing", runeKeys("abc"), "abc", 3},
+		{"multi-byte", []tea.KeyMsg{{Type: tea.KeyRunes, Runes: []rune("zażółć")}}, "zażółć", len("zażółć")},
+		{"insert in the middle", append(runeKeys("ac"), tea.KeyMsg{Type: tea.KeyLeft}, runeKeys("b")[0]), "abc", 2},
+		{"left over multi-byte", append(runeKeys("żx"), tea.KeyMsg{Type: tea.KeyLeft}, tea.KeyMsg{Type: tea.KeyLeft}, runeKeys("a")[0]), "ażx", 1},
+		{"backspace", append(runeKeys("ab"), tea.KeyMsg{Type: tea.KeyLeft}, tea.KeyMsg{Type: tea.KeyBackspace}), "b", 0},
+		{"delete", append(runeKeys("ab"), tea.KeyMsg{Type: tea.KeyCtrlA}, tea.KeyMsg{Type: tea.KeyDelete}), "b", 0},
+		{"ctrl-a and ctrl-e", append(runeKeys("b"), tea.KeyMsg{Type: tea.KeyCtrlA}, runeKeys("a")[0], tea.KeyMsg{Type: tea.KeyCtrlE}, runeKeys("c")[0]), "abc", 3},
+		{"ctrl-w", append(runeKeys("e foo/bar  "), tea.KeyMsg{Type: tea.KeyCtrlW}), "e foo/", 6},
+		{"ctrl-w twice", append(runeKeys("e foo/bar"), tea.KeyMsg{Type: tea.KeyCtrlW}, tea.KeyMsg{Type: tea.KeyCtrlW}), "e foo", 5},
+		{"ctrl-u", append(runeKeys("abc"), tea.KeyMsg{Type: tea.KeyLeft}, tea.KeyMsg{Type: tea.KeyCtrlU}), "c", 0},
+		{"space", []tea.KeyMsg{runeKeys("w")[0], {Type: tea.KeySpace, Runes: []rune{' '}}, runeKeys("x")[0]}, "w x", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := pressKeys(commandLineModel(), tt.keys...)
+			if m.commandBuffer != tt.want || m.commandCursor != tt.cursor {
+				t.Errorf("Expected %q with the cursor at %d, got %q at %d", tt.want, tt.cursor, m.commandBuffer, m.commandCursor)
+			}
+		})
+	}
+}
+
+func TestCommandLineBackspaceOnEmptyLine(t *testing.T) {
+	m := pressKeys(commandLineModel(), tea.KeyMsg{Type: tea.KeyBackspace})
+	if m.mode != ModeNormal {
+		t.Errorf("Expected backspace on an empty line to leave the command line, got %s", m.mode)
+	}
+}
+
+func TestCommandHistory(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "history")
+	m := initialModel(WithHistoryFile(file))
+	m.buffers[0] = newBuffer(m.style, bufferWithContent("", "a\nb\nc"))
+
+	for _, line := range []string{"bn", "2", "bp", "3"} {
+		m = pressKeys(m, runeKeys(":"+line)...)
+		m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEnter})
+	}
+
+	up := tea.KeyMsg{Type: tea.KeyUp}
+	down := tea.KeyMsg{Type: tea.KeyDown}
+
+	m = pressKeys(m, runeKeys(":")...)
+	m = pressKeys(m, up, up)
+	if m.commandBuffer != "bp" {
+		t.Errorf("Expected up to go through the history, got %q", m.commandBuffer)
+	}
+	m = pressKeys(m, down, down)
+	if m.commandBuffer != "" {
+		t.Errorf("Expected down to go back to the typed text, got %q", m.commandBuffer)
+	}
+
+	m = pressKeys(m, runeKeys("b")...)
+	m = pressKeys(m, up, up, up)
+	if m.commandBuffer != "bn" {
+		t.Errorf("Expected the history to be filtered by the prefix, got %q", m.commandBuffer)
+	}
+	m = pressKeys(m, down, down)
+	if m.commandBuffer != "b" || m.commandCursor != 1 {
+		t.Errorf("Expected the prefix back, got %q", m.commandBuffer)
+	}
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEsc})
+
+	// Running a command again moves it to the end
+	m = pressKeys(m, runeKeys(":2")...)
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEnter})
+
+	loaded := initialModel(WithHistoryFile(file))
+	if want := []string{"bn", "bp", "3", "2"}; !slices.Equal(loaded.history.entries, want) {
+		t.Errorf("Expected the history %q to be loaded, got %q", want, loaded.history.entries)
+	}
+}
+
+func TestCompleteCommandNames(t *testing.T) {
+	m := pressKeys(commandLineModel(), runeKeys("und")...)
+	tab := tea.KeyMsg{Type: tea.KeyTab}
+
+	m = pressKeys(m, tab)
+	if m.commandBuffer != "undo" {
+		t.Errorf("Expected the first candidate, got %q", m.commandBuffer)
+	}
+	if !strings.Contains(m.View(), "undotree") {
+		t.Errorf("Expected the candidates to be shown")
+	}
+
+	// undo, undol, undolist, undotree and the typed text
+	m = pressKeys(m, tab, tab, tab, tab)
+	if m.commandBuffer != "und" {
+		t.Errorf("Expected tab to cycle back to the typed text, got %q", m.commandBuffer)
+	}
+
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyShiftTab})
+	if m.commandBuffer != "undotree" {
+		t.Errorf("Expected shift-tab to go back to the last candidate, got %q", m.commandBuffer)
+	}
+
+	m = pressKeys(commandLineModel(), runeKeys("%subs")...)
+	m = pressKeys(m, tab)
+	if m.commandBuffer != "%substitute" {
+		t.Errorf("Expected the range to be kept, got %q", m.commandBuffer)
+	}
+}
+
+func TestCompleteArguments(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"main.go", "model.go", ".hidden"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "module"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		line    string
+		buffers []string
+		want    []string
+	}{
+		{"files", "e " + dir + "/m", nil, []string{dir + "/main.go", dir + "/model.go", dir + "/module/"}},
+		{"hidden files", "w " + dir + "/.", nil, []string{dir + "/.hidden"}},
+		{"no completion", "noh x", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := commandLineModel()
+			for _, name := range tt.buffers {
+				m.buffers = append(m.buffers, newBuffer(m.style, bufferWithContent(name, "")))
+			}
+			m = pressKeys(m, runeKeys(tt.line)...)
+
+			var got []string
+			if c := m.newCompletion(); c != nil {
+				got = c.candidates
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCompleteDirectory(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "src", "pkg"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	m := pressKeys(commandLineModel(), runeKeys("e "+dir+"/s")...)
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyTab}, tea.KeyMsg{Type: tea.KeyTab})
+	if want := "e " + dir + "/src/pkg/"; m.commandBuffer != want {
+		t.Errorf("Expected tab to continue in the completed directory, got %q", m.commandBuffer)
+	}
+}
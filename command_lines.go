@@ -0,0 +1,184 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// commandDelete is :[range]d [x] [count], deleting lines into a register.
+type commandDelete struct {
+}
+
+func (c commandDelete) Update(m model, msg tea.Msg, args []string) (model, tea.Cmd) {
+	return c.UpdateRange(m, msg, m.CurrentBuffer().currentLineRange(), args)
+}
+
+func (c commandDelete) UpdateRange(m model, msg tea.Msg, r lineRange, args []string) (model, tea.Cmd) {
+	m.commandBuffer = ""
+	m.mode = ModeNormal
+
+	reg, r, err := m.registerAndCount(r, args)
+	if err != nil {
+		return m.SetErrorMessage(err.Error()), nil
+	}
+
+	return m.applyOperator("d", r.textRange(), reg)
+}
+
+func (c commandDelete) Aliases() []string {
+	return []string{"delete", "d"}
+}
+
+// commandYank is :[range]y [x] [count], yanking lines into a register.
+type commandYank struct {
+}
+
+func (c commandYank) Update(m model, msg tea.Msg, args []string) (model, tea.Cmd) {
+	return c.UpdateRange(m, msg, m.CurrentBuffer().currentLineRange(), args)
+}
+
+func (c commandYank) UpdateRange(m model, msg tea.Msg, r lineRange, args []string) (model, tea.Cmd) {
+	m.commandBuffer = ""
+	m.mode = ModeNormal
+
+	reg, r, err := m.registerAndCount(r, args)
+	if err != nil {
+		return m.SetErrorMessage(err.Error()), nil
+	}
+
+	// Unlike the y operator, :y doesn't move the cursor
+	return m.Yank(reg, m.CurrentBuffer().Text(r.textRange())), nil
+}
+
+func (c commandYank) Aliases() []string {
+	return []string{"yank", "y"}
+}
+
+// commandMove is :[range]m {address}, moving lines below the address. 0
+// moves them to the top of the buffer.
+type commandMove struct {
+}
+
+func (c commandMove) Update(m model, msg tea.Msg, args []string) (model, tea.Cmd) {
+	return c.UpdateRange(m, msg, m.CurrentBuffer().currentLineRange(), args)
+}
+
+func (c commandMove) UpdateRange(m model, msg tea.Msg, r lineRange, args []string) (model, tea.Cmd) {
+	m.commandBuffer = ""
+	m.mode = ModeNormal
+
+	dest, err := m.destinationAddress(args)
+	if err != nil {
+		return m.SetErrorMessage(err.Error()), nil
+	}
+	if dest >= r.start && dest < r.end {
+		return m.SetErrorMessage("Cannot move a range of lines into itself"), nil
+	}
+
+	b := m.buffers[m.currBuffer].beginUndoStep()
+	lines := b.Text(r.textRange()).lines
+	for y := r.end; y >= r.start; y-- {
+		b = b.DeleteLine(y)
+	}
+	// The destination moves up when the lines above it were removed
+	if dest >= r.end {
+		dest -= len(lines)
+	}
+	b = b.insertLines(dest+1, lines)
+
+	m.buffers[m.currBuffer] = b.SetStateModified().commitUndoStep()
+	return m, nil
+}
+
+func (c commandMove) Aliases() []string {
+	return []string{"move", "m"}
+}
+
+// commandCopy is :[range]t {address}, copying lines below the address.
+type commandCopy struct {
+}
+
+func (c commandCopy) Update(m model, msg tea.Msg, args []string) (model, tea.Cmd) {
+	return c.UpdateRange(m, msg, m.CurrentBuffer().currentLineRange(), args)
+}
+
+func (c commandCopy) UpdateRange(m model, msg tea.Msg, r lineRange, args []string) (model, tea.Cmd) {
+	m.commandBuffer = ""
+	m.mode = ModeNormal
+
+	dest, err := m.destinationAddress(args)
+	if err != nil {
+		return m.SetErrorMessage(err.Error()), nil
+	}
+
+	b := m.buffers[m.currBuffer].beginUndoStep()
+	b = b.insertLines(dest+1, b.Text(r.textRange()).lines)
+
+	m.buffers[m.currBuffer] = b.SetStateModified().commitUndoStep()
+	return m, nil
+}
+
+func (c commandCopy) Aliases() []string {
+	return []string{"copy", "co", "t"}
+}
+
+// textRange returns the lines of the range as a linewise text range.
+func (r lineRange) textRange() textRange {
+	return textRange{start: position{line: r.start}, end: position{line: r.end}, kind: rangeLinewise}
+}
+
+// registerAndCount parses the [x] [count] arguments of :d and :y. With a
+// count the range starts at its last line and covers count lines.
+func (m model) registerAndCount(r lineRange, args []string) (rune, lineRange, error) {
+	var reg rune
+	if len(args) > 0 {
+		if name := []rune(args[0]); len(name) == 1 && (name[0] < '0' || name[0] > '9') {
+			if !isValidRegister(name[0]) {
+				return 0, r, fmt.Errorf("Invalid register: %c", name[0])
+			}
+			reg, args = name[0], args[1:]
+		}
+	}
+
+	if len(args) > 0 && args[0] != "" {
+		count, err := strconv.Atoi(args[0])
+		if err != nil || count <= 0 {
+			return 0, r, errors.New("Invalid argument: " + strings.Join(args, " "))
+		}
+		r.start = r.end
+		r.end = min(r.end+count-1, m.CurrentBuffer().NoOfLines()-1)
+	}
+
+	return reg, r, nil
+}
+
+// destinationAddress parses the address of :m and :t. The lines are put
+// below it, 0 means above the first line.
+func (m model) destinationAddress(args []string) (int, error) {
+	arg := strings.Join(args, " ")
+	if arg == "" {
+		return 0, errors.New("Destination address required")
+	}
+
+	dest, rest, ok, err := m.parseAddress(arg, m.CurrentBuffer().cursorY)
+	if err != nil {
+		return 0, err
+	}
+	if !ok || strings.TrimSpace(rest) != "" || dest < -1 || dest >= m.CurrentBuffer().NoOfLines() {
+		return 0, errInvalidRange
+	}
+	return dest, nil
+}
+
+// insertLines inserts the lines at y and moves the cursor to the last one.
+func (b buffer) insertLines(y int, lines []string) buffer {
+	for i, line := range lines {
+		b = b.InsertLine(y+i, line)
+	}
+	last := y + len(lines) - 1
+	return b.moveCursorTo(firstNonBlank(b.Line(last)), last)
+}
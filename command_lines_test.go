@@ -0,0 +1,167 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestLineCommands(t *testing.T) {
+	tests := []struct {
+		name    string
+		cursor  int
+		command string
+		want    []string
+		message string
+	}{
+		{name: "delete current line", cursor: 1, command: "d", want: []string{"one", "three", "four"}},
+		{name: "delete range", command: "2,3d", want: []string{"one", "four"}},
+		{name: "delete with count", command: "2d 2", want: []string{"one", "four"}},
+		{name: "delete pattern range", command: "/two/,/four/d", want: []string{"one"}},
+		{name: "move to top", cursor: 2, command: "m0", want: []string{"three", "one", "two", "four"}},
+		{name: "move below last line", command: "1,2m$", want: []string{"three", "four", "one", "two"}},
+		{name: "move up", command: "$m1", want: []string{"one", "four", "two", "three"}},
+		{name: "move into itself", command: "1,3m2", want: []string{"one", "two", "three", "four"}, message: "Cannot move a range of lines into itself"},
+		{name: "copy below current line", command: "3,4t.", want: []string{"one", "three", "four", "two", "three", "four"}},
+		{name: "copy to top", command: "$co0", want: []string{"four", "one", "two", "three", "four"}},
+		{name: "missing address", command: "t", want: []string{"one", "two", "three", "four"}, message: "Destination address required"},
+		{name: "normal on range", command: "%normal ix", want: []string{"xone", "xtwo", "xthree", "xfour"}},
+		{name: "normal deleting lines", command: "1,3normal dd", want: []string{"four"}},
+		{name: "normal adding lines", command: "%norm oy", want: []string{"one", "y", "two", "y", "three", "y", "four", "y"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := initialModel()
+			m.buffers[0] = newBuffer(m.style, bufferWithContent("", "one\ntwo\nthree\nfour"))
+			m.buffers[0] = m.buffers[0].SetCursorY(tt.cursor)
+
+			m = runCommand(m, tt.command)
+			if got := m.CurrentBuffer().Lines(); !slices.Equal(got, tt.want) {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+			if tt.message != "" && (m.currentMessage == nil || m.currentMessage.text != tt.message) {
+				t.Errorf("Expected message %q, got %v", tt.message, m.currentMessage)
+			}
+			if m.mode != ModeNormal {
+				t.Errorf("Expected normal mode, got %s", m.mode)
+			}
+		})
+	}
+}
+
+func TestLineCommandsAreOneUndoStep(t *testing.T) {
+	for _, command := range []string{"2,3d", "1,2m$", "%t0", "%normal ix"} {
+		t.Run(command, func(t *testing.T) {
+			m := initialModel()
+			m.buffers[0] = newBuffer(m.style, bufferWithContent("", "one\ntwo\nthree"))
+
+			m = runCommand(m, command)
+			if m.CurrentBuffer().state != bufferStateModified {
+				t.Errorf("Expected the buffer to be modified")
+			}
+			m = pressKeys(m, runeKeys("u")...)
+			if got := m.CurrentBuffer().Lines(); !slices.Equal(got, []string{"one", "two", "three"}) {
+				t.Errorf("Expected a single undo to revert the command, got %q", got)
+			}
+		})
+	}
+}
+
+func TestDeleteAndYankIntoRegister(t *testing.T) {
+	m := initialModel()
+	m.buffers[0] = newBuffer(m.style, bufferWithContent("", "one\ntwo\nthree"))
+
+	m = runCommand(m, "2,3y a")
+	if r, _ := m.Register('a'); !r.linewise || !slices.Equal(r.lines, []string{"two", "three"}) {
+		t.Errorf("Expected the lines to be yanked into a, got %v", r)
+	}
+	if got := cursorOf(m); got != (position{}) {
+		t.Errorf("Expected :y not to move the cursor, got %v", got)
+	}
+
+	m = runCommand(m, "1d b")
+	if r, _ := m.Register('b'); !slices.Equal(r.lines, []string{"one"}) {
+		t.Errorf("Expected the line to be deleted into b, got %v", r)
+	}
+}
+
+func TestWriteRange(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "file.txt")
+	part := filepath.Join(dir, "part.txt")
+
+	m := initialModel()
+	m.buffers[0] = newBuffer(m.style, bufferWithContent(filename, "one\ntwo\nthree")).SetStateModified()
+	m.buffers[0] = m.buffers[0].SetCursorY(1)
+
+	m = runCommand(m, ".,$w "+part)
+	content, err := os.ReadFile(part)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "two\nthree" {
+		t.Errorf("Expected the range to be written, got %q", content)
+	}
+	if b := m.CurrentBuffer(); b.filename != filename || b.state != bufferStateModified {
+		t.Errorf("Expected the buffer to keep its name and state, got %s %s", b.filename, b.state)
+	}
+
+	m = runCommand(m, "1w")
+	if m.currentMessage == nil || m.currentMessage.msgType != MessageError {
+		t.Errorf("Expected writing a part to the buffer's own file to fail")
+	}
+
+	m = runCommand(m, "%w")
+	if content, _ := os.ReadFile(filename); string(content) != "one\ntwo\nthree" {
+		t.Errorf("Expected the whole buffer to be written, got %q", content)
+	}
+}
+
+func TestVisualRange(t *testing.T) {
+	m := initialModel()
+	m.buffers[0] = newBuffer(m.style, bufferWithContent("", "one\ntwo\nthree\nfour"))
+	m.buffers[0] = m.buffers[0].SetCursorY(1)
+
+	m = pressKeys(m, runeKeys("Vj:")...)
+	if m.mode != ModeCommand || m.commandBuffer != "'<,'>" {
+		t.Fatalf("Expected the command line with the selection, got %s %q", m.mode, m.commandBuffer)
+	}
+
+	m = pressKeys(m, runeKeys("d")...)
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if got := m.CurrentBuffer().Lines(); !slices.Equal(got, []string{"one", "four"}) {
+		t.Errorf("Expected the selected lines to be deleted, got %q", got)
+	}
+}
+
+func TestMarks(t *testing.T) {
+	m := initialModel()
+	m.buffers[0] = newBuffer(m.style, bufferWithContent("", "one\n  two\nthree"))
+	m.buffers[0] = m.buffers[0].moveCursorTo(3, 1)
+
+	m = pressKeys(m, runeKeys("ma")...)
+	m = pressKeys(m, runeKeys("gg")...)
+	m = pressKeys(m, runeKeys("'a")...)
+	if got := cursorOf(m); got != (position{line: 1, col: 2}) {
+		t.Errorf("Expected 'a to jump to the first non-blank, got %v", got)
+	}
+
+	m = pressKeys(m, runeKeys("gg`a")...)
+	if got := cursorOf(m); got != (position{line: 1, col: 3}) {
+		t.Errorf("Expected `a to jump to the mark, got %v", got)
+	}
+
+	m = pressKeys(m, runeKeys("ggd'a")...)
+	if got := m.CurrentBuffer().Lines(); !slices.Equal(got, []string{"three"}) {
+		t.Errorf("Expected d'a to delete to the mark linewise, got %q", got)
+	}
+
+	m = pressKeys(m, runeKeys("'b")...)
+	if m.currentMessage == nil || m.currentMessage.text != "Mark not set: b" {
+		t.Errorf("Expected an error for a missing mark, got %v", m.currentMessage)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// commandNormal is :[range]normal {keys}, running the keys in normal mode on
+// every line of the range. The whole command is undone as a single step.
+type commandNormal struct {
+}
+
+func (c commandNormal) Update(m model, msg tea.Msg, args []string) (model, tea.Cmd) {
+	return c.UpdateRange(m, msg, m.CurrentBuffer().currentLineRange(), args)
+}
+
+func (c commandNormal) UpdateRange(m model, msg tea.Msg, r lineRange, args []string) (model, tea.Cmd) {
+	m.commandBuffer = ""
+	m.mode = ModeNormal
+
+	keys := strings.Join(args, " ")
+	if keys == "" {
+		return m.SetErrorMessage("Argument required"), nil
+	}
+
+	m.buffers[m.currBuffer] = m.buffers[m.currBuffer].beginUndoStep()
+
+	var cmds []tea.Cmd
+	end := r.end
+	for y := r.start; y <= end && y < m.CurrentBuffer().NoOfLines(); y++ {
+		lines := m.CurrentBuffer().NoOfLines()
+		m.buffers[m.currBuffer] = m.buffers[m.currBuffer].moveCursorTo(0, y)
+		var cmd tea.Cmd
+		m, cmd = m.runNormalKeys(keys)
+		cmds = append(cmds, cmd)
+
+		// Keep working on the original lines when the keys added or
+		// removed some
+		delta := m.CurrentBuffer().NoOfLines() - lines
+		y += delta
+		end += delta
+	}
+
+	// The keys mark the buffer as modified themselves
+	m.buffers[m.currBuffer] = m.buffers[m.currBuffer].commitUndoStep()
+	return m, tea.Batch(cmds...)
+}
+
+func (c commandNormal) Aliases() []string {
+	return []string{"normal", "norm"}
+}
+
+// runNormalKeys feeds the keys to normal mode as if they were typed. An
+// unfinished insert is ended like with esc. The commands of the keys, like
+// copying to the clipboard, are returned.
+func (m model) runNormalKeys(keys string) (model, tea.Cmd) {
+	var cmds []tea.Cmd
+	m.normalmode.buffer = ""
+	for _, r := range keys {
+		key := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}}
+		if r == ' ' {
+			key = tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{r}}
+		}
+		res, cmd := m.Update(key)
+		m = res.(model)
+		cmds = append(cmds, cmd)
+	}
+
+	if m.mode != ModeNormal {
+		res, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+		m = res.(model)
+		cmds = append(cmds, cmd)
+	}
+	m.mode = ModeNormal
+	m.commandBuffer = ""
+	m.normalmode.buffer = ""
+	return m, tea.Batch(cmds...)
+}
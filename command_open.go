@@ -1,8 +1,11 @@
 package main
 
 import (
-	"io"
+	"errors"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -11,25 +14,19 @@ type commandOpen struct {
 }
 
 func (c commandOpen) Update(m model, msg tea.Msg, args []string) (model, tea.Cmd) {
-	if len(args) == 0 {
-		return m, nil
-	}
+	m.commandBuffer = ""
+	m.mode = ModeNormal
 
-	for _, filepath := range args {
-		f, err := os.OpenFile(filepath, os.O_RDWR, 0666)
-		if err != nil {
-			panic(err)
+	for _, path := range args {
+		if path == "" {
+			continue
 		}
 
-		cont, err := io.ReadAll(f)
+		var err error
+		m, err = m.openFile(path)
 		if err != nil {
-			panic(err)
+			return m.SetErrorMessage("Failed to open file: " + err.Error()), nil
 		}
-
-		m = m.addBuffer(newBuffer(m.style, bufferStateSavedOpt, bufferWithContent(filepath, string(cont))))
-		m.commandBuffer = ""
-		m.mode = ModeNormal
-		m.currBuffer++
 	}
 
 	return m, nil
@@ -38,3 +35,79 @@ func (c commandOpen) Update(m model, msg tea.Msg, args []string) (model, tea.Cmd
 func (c commandOpen) Aliases() []string {
 	return []string{"open", "o", "e", "edit"}
 }
+
+func (c commandOpen) Complete(m model, arg string) []string {
+	return completeFiles(arg)
+}
+
+// openFile switches to the buffer of the file. The file is loaded into a
+// new buffer only if it isn't open yet.
+func (m model) openFile(path string) (model, error) {
+	if i := m.findBufferByFile(path); i >= 0 {
+		return m.selectBuffer(i), nil
+	}
+
+	cont, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		// The file is created when the buffer is written
+		m = m.addBuffer(newBuffer(m.style, bufferWithContent(path, "")))
+		return m.selectBuffer(len(m.buffers) - 1), nil
+	}
+	if err != nil {
+		return m, err
+	}
+
+	b := newBuffer(m.style, bufferStateSavedOpt, bufferWithContent(path, string(cont)))
+	m = m.addBuffer(b.loadUndoFile(string(cont)))
+	return m.selectBuffer(len(m.buffers) - 1), nil
+}
+
+// findBufferByFile returns the index of the buffer holding the file, or -1.
+// Paths are compared after making them absolute.
+func (m model) findBufferByFile(path string) int {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return -1
+	}
+
+	for i, b := range m.buffers {
+		if b.filename == "" {
+			continue
+		}
+		if other, err := filepath.Abs(b.filename); err == nil && other == abs {
+			return i
+		}
+	}
+	return -1
+}
+
+// commandReload is :e!, loading the file of the current buffer again and
+// dropping the changes. The reload can be undone.
+type commandReload struct {
+}
+
+func (c commandReload) Update(m model, msg tea.Msg, args []string) (model, tea.Cmd) {
+	m.commandBuffer = ""
+	m.mode = ModeNormal
+
+	b := m.buffers[m.currBuffer]
+	if b.filename == "" {
+		return m.SetErrorMessage("No file name"), nil
+	}
+
+	cont, err := os.ReadFile(b.filename)
+	if err != nil {
+		return m.SetErrorMessage("Failed to reload file: " + err.Error()), nil
+	}
+
+	b = b.beginUndoStep()
+	b.lines = newRope(strings.Split(string(cont), "\n"))
+	b = b.moveCursorTo(b.cursorX, b.cursorY).commitUndoStep()
+	m.buffers[m.currBuffer] = b.SetStateSaved()
+
+	return m.SetInfoMessage("Reloaded " + b.filename), nil
+}
+
+func (c commandReload) Aliases() []string {
+	return []string{"edit!", "e!"}
+}
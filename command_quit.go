@@ -10,6 +10,20 @@ type commandQuit struct {
 }
 
 func (c commandQuit) Update(m model, msg tea.Msg, args []string) (model, tea.Cmd) {
+	// With more windows or tab pages only the focused one is closed
+	if m.hasSplits() {
+		m.commandBuffer = ""
+		m.mode = ModeNormal
+		m, _ = m.closeWindow(m.windows.curr)
+		return m, nil
+	}
+	if len(m.tabs) > 1 {
+		m.commandBuffer = ""
+		m.mode = ModeNormal
+		m, _ = m.closeTab(m.currTab)
+		return m, nil
+	}
+
 	// Check for unsaved buffers
 	unsavedBuffers := []string{}
 
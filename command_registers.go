@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type commandRegisters struct {
+}
+
+func (c commandRegisters) Update(m model, msg tea.Msg, args []string) (model, tea.Cmd) {
+	m.commandBuffer = ""
+	m.mode = ModeNormal
+
+	names := make([]rune, 0, len(m.registers))
+	for name := range m.registers {
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return m.SetInfoMessage("All registers are empty"), nil
+	}
+
+	// The unnamed register first, then numbered, named and special ones
+	slices.SortFunc(names, func(a, b rune) int {
+		if a == unnamedRegister || b == unnamedRegister {
+			return boolToInt(b == unnamedRegister) - boolToInt(a == unnamedRegister)
+		}
+		return int(a) - int(b)
+	})
+
+	var items []listItem
+	for _, name := range names {
+		r := m.registers[name]
+		kind := "c"
+		if r.linewise {
+			kind = "l"
+		}
+		text := strings.ReplaceAll(strings.Join(r.lines, "^J"), "\t", "^I")
+		items = append(items, listItem{
+			label:   fmt.Sprintf("%s  \"%c  %s", kind, name, text),
+			preview: r.lines,
+		})
+	}
+
+	return m.OpenList("Registers", items, 0, nil), nil
+}
+
+func (c commandRegisters) Aliases() []string {
+	return []string{"registers", "reg", "display", "di"}
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// commandRename is :rename newName, renaming the symbol under the cursor in
+// all the files using it.
+type commandRename struct {
+}
+
+type lspRenameParams struct {
+	lspTextDocumentPositionParams
+	NewName string `json:"newName"`
+}
+
+func (c commandRename) Update(m model, msg tea.Msg, args []string) (model, tea.Cmd) {
+	m.commandBuffer = ""
+	m.mode = ModeNormal
+
+	name := strings.TrimSpace(strings.Join(args, " "))
+	if name == "" {
+		return m.SetErrorMessage("New name required"), nil
+	}
+
+	handle := func(m model, result json.RawMessage, err error) (model, tea.Cmd) {
+		if m.mode != ModeNormal {
+			return m, nil
+		}
+		if err != nil {
+			return m.SetErrorMessage(err.Error()), nil
+		}
+		var edit *lspWorkspaceEdit
+		if err := json.Unmarshal(result, &edit); err != nil {
+			return m.SetErrorMessage("Invalid answer of the language server: " + err.Error()), nil
+		}
+		if edit == nil {
+			return m.SetErrorMessage("Nothing to rename"), nil
+		}
+		m, _ = m.workspaceEditMessage(*edit)
+		return m, nil
+	}
+
+	b := m.CurrentBuffer()
+	params := lspRenameParams{lspTextDocumentPositionParams: b.cursorPositionParams(), NewName: name}
+	if err := m.lsp.request(b.filename, "textDocument/rename", params, handle); err != nil {
+		return m.SetErrorMessage(err.Error()), nil
+	}
+	return m, nil
+}
+
+func (c commandRename) Aliases() []string {
+	return []string{"rename"}
+}
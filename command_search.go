@@ -0,0 +1,20 @@
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type commandNoHighlight struct {
+}
+
+func (c commandNoHighlight) Update(m model, msg tea.Msg, args []string) (model, tea.Cmd) {
+	m.commandBuffer = ""
+	m.mode = ModeNormal
+	m.search.highlight = false
+
+	return m, nil
+}
+
+func (c commandNoHighlight) Aliases() []string {
+	return []string{"nohlsearch", "noh"}
+}
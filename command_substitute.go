@@ -0,0 +1,349 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// substitution is a parsed :s/pattern/replacement/flags command.
+type substitution struct {
+	pattern  string
+	re       *regexp.Regexp
+	template string
+	global   bool
+	confirm  bool
+}
+
+// substituteState is a substitution waiting for the user to confirm the
+// current match.
+type substituteState struct {
+	substitution
+	r lineRange
+	// pos is where the next match is searched from
+	pos position
+	// match holds the submatch indexes of the current match on pos.line
+	match []int
+	// count and lines are the substitutions made so far and the number of
+	// lines they were made on. lastLine is the last changed line.
+	count, lines, lastLine int
+}
+
+type commandSubstitute struct {
+}
+
+func (c commandSubstitute) Update(m model, msg tea.Msg, args []string) (model, tea.Cmd) {
+	return c.UpdateRange(m, msg, m.CurrentBuffer().currentLineRange(), args)
+}
+
+func (c commandSubstitute) UpdateRange(m model, msg tea.Msg, r lineRange, args []string) (model, tea.Cmd) {
+	m.commandBuffer = ""
+	m.mode = ModeNormal
+
+	sub, err := parseSubstitute(strings.Join(args, " "), m.search.pattern)
+	if err != nil {
+		return m.SetErrorMessage(err.Error()), nil
+	}
+	m.search.pattern = sub.pattern
+	m.search.wholeWord = false
+
+	m.buffers[m.currBuffer] = m.buffers[m.currBuffer].beginUndoStep()
+	state := &substituteState{substitution: sub, r: r, pos: position{line: r.start}, lastLine: -1}
+
+	if sub.confirm {
+		m.substitute = state
+		m.mode = ModeConfirm
+		return m.nextSubstituteMatch(), nil
+	}
+
+	b := m.buffers[m.currBuffer]
+	for y := r.start; y <= r.end; y++ {
+		line, n := sub.replaceLine(b.Line(y))
+		if n == 0 {
+			continue
+		}
+		b = b.ReplaceLine(y, line)
+		state.count += n
+		state.lines++
+		state.lastLine = y
+	}
+	m.buffers[m.currBuffer] = b
+
+	return m.finishSubstitute(state), nil
+}
+
+func (c commandSubstitute) Aliases() []string {
+	return []string{"substitute", "s"}
+}
+
+// parseSubstitute parses the /pattern/replacement/flags argument. Any
+// character other than a letter, a digit or a backslash can be used instead
+// of /. An empty pattern uses the last search pattern.
+func parseSubstitute(arg, lastPattern string) (substitution, error) {
+	var sub substitution
+	if arg == "" {
+		return sub, errors.New("Usage: s/pattern/replacement/flags")
+	}
+
+	delim := []rune(arg)[0]
+	if unicode.IsLetter(delim) || unicode.IsDigit(delim) || unicode.IsSpace(delim) || delim == '\\' {
+		return sub, fmt.Errorf("Invalid delimiter: %c", delim)
+	}
+
+	parts := splitDelimited(arg[len(string(delim)):], delim)
+	for len(parts) < 3 {
+		parts = append(parts, "")
+	}
+
+	sub.pattern = parts[0]
+	if sub.pattern == "" {
+		sub.pattern = lastPattern
+	}
+	if sub.pattern == "" {
+		return sub, errors.New("No previous regular expression")
+	}
+	sub.template = replacementTemplate(parts[1])
+
+	ignoreCase := false
+	for _, f := range parts[2] {
+		switch f {
+		case 'g':
+			sub.global = true
+		case 'i':
+			ignoreCase = true
+		case 'I':
+			ignoreCase = false
+		case 'c':
+			sub.confirm = true
+		default:
+			return sub, fmt.Errorf("Invalid flag: %c", f)
+		}
+	}
+
+	pattern := sub.pattern
+	if ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return sub, fmt.Errorf("Invalid pattern: %w", err)
+	}
+	sub.re = re
+
+	return sub, nil
+}
+
+// splitDelimited splits the text on the delimiter into at most three parts.
+// An escaped delimiter is a part of the text.
+func splitDelimited(s string, delim rune) []string {
+	var parts []string
+	var current strings.Builder
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped && r == delim:
+			current.WriteRune(r)
+		case escaped:
+			current.WriteRune('\\')
+			current.WriteRune(r)
+		case r == '\\':
+			escaped = true
+			continue
+		case r == delim && len(parts) < 2:
+			parts = append(parts, current.String())
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+		escaped = false
+	}
+	if escaped {
+		current.WriteRune('\\')
+	}
+	return append(parts, current.String())
+}
+
+// replacementTemplate converts the replacement to the template used by
+// regexp.Expand. Besides $1 and ${name}, \1 and & can be used for the
+// groups and the whole match.
+func replacementTemplate(s string) string {
+	var t strings.Builder
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped && unicode.IsDigit(r):
+			t.WriteString("${" + string(r) + "}")
+		case escaped:
+			t.WriteRune(r)
+		case r == '\\':
+			escaped = true
+			continue
+		case r == '&':
+			t.WriteString("${0}")
+		default:
+			t.WriteRune(r)
+		}
+		escaped = false
+	}
+	return t.String()
+}
+
+// replaceLine replaces the first match, or all of them with the g flag.
+func (s substitution) replaceLine(line string) (string, int) {
+	limit := 1
+	if s.global {
+		limit = -1
+	}
+
+	matches := s.re.FindAllStringSubmatchIndex(line, limit)
+	var out []byte
+	last := 0
+	for _, loc := range matches {
+		out = append(out, line[last:loc[0]]...)
+		out = s.re.ExpandString(out, s.template, line, loc)
+		last = loc[1]
+	}
+	out = append(out, line[last:]...)
+
+	return string(out), len(matches)
+}
+
+// nextSubstituteMatch moves the cursor to the next match waiting for
+// confirmation, or finishes the substitution if there are none left.
+func (m model) nextSubstituteMatch() model {
+	s := m.substitute
+	b := m.CurrentBuffer()
+
+	for s.pos.line <= s.r.end {
+		line := b.Line(s.pos.line)
+		for _, loc := range s.re.FindAllStringSubmatchIndex(line, -1) {
+			if loc[0] >= s.pos.col {
+				s.match = loc
+				m.buffers[m.currBuffer] = b.moveCursorTo(loc[0], s.pos.line)
+				return m
+			}
+		}
+		s.pos = position{line: s.pos.line + 1}
+	}
+
+	m.substitute = nil
+	m.mode = ModeNormal
+	return m.finishSubstitute(s)
+}
+
+// replaceSubstituteMatch replaces the current match.
+func (m model) replaceSubstituteMatch() model {
+	s := m.substitute
+	b := m.CurrentBuffer()
+	line := b.Line(s.pos.line)
+
+	replacement := string(s.re.ExpandString(nil, s.template, line, s.match))
+	m.buffers[m.currBuffer] = b.ReplaceLine(s.pos.line, line[:s.match[0]]+replacement+line[s.match[1]:])
+
+	s.count++
+	if s.lastLine != s.pos.line {
+		s.lines++
+		s.lastLine = s.pos.line
+	}
+
+	return m.skipSubstituteMatch(s.match[0] + len(replacement))
+}
+
+// skipSubstituteMatch continues after the current match, which ends at end.
+// Without the g flag it continues on the next line.
+func (m model) skipSubstituteMatch(end int) model {
+	s := m.substitute
+	switch {
+	case !s.global:
+		s.pos = position{line: s.pos.line + 1}
+	case s.match[0] == s.match[1]:
+		// Don't match an empty string at the same place again
+		s.pos.col = end + 1
+	default:
+		s.pos.col = end
+	}
+	return m.nextSubstituteMatch()
+}
+
+// finishSubstitute records the substitution as a single undo step and
+// reports the number of substitutions.
+func (m model) finishSubstitute(s *substituteState) model {
+	b := m.buffers[m.currBuffer]
+	if s.count == 0 {
+		m.buffers[m.currBuffer] = b.commitUndoStep()
+		if s.match != nil {
+			// All matches were skipped
+			return m
+		}
+		return m.SetErrorMessage("Pattern not found: " + s.pattern)
+	}
+
+	b = b.moveCursorTo(firstNonBlank(b.Line(s.lastLine)), s.lastLine)
+	m.buffers[m.currBuffer] = b.SetStateModified().commitUndoStep()
+
+	return m.SetInfoMessage(fmt.Sprintf("%d substitution%s on %d line%s",
+		s.count, plural(s.count), s.lines, plural(s.lines)))
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
+
+// updateConfirm handles the answer to "replace with ...?" asked for every
+// match of :s with the c flag.
+func (m model) updateConfirm(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok || m.substitute == nil {
+		return m, nil
+	}
+
+	s := m.substitute
+	switch keyMsg.String() {
+	case "y":
+		return m.replaceSubstituteMatch(), nil
+	case "n":
+		return m.skipSubstituteMatch(s.match[1]), nil
+	case "l":
+		// Replace this one and stop
+		m = m.replaceSubstituteMatch()
+		if m.substitute == nil {
+			return m, nil
+		}
+	case "a":
+		for m.substitute != nil {
+			m = m.replaceSubstituteMatch()
+		}
+		return m, nil
+	case "q", "esc", "ctrl+c":
+	default:
+		return m, nil
+	}
+
+	m.substitute = nil
+	m.mode = ModeNormal
+	return m.finishSubstitute(s), nil
+}
+
+// substitutePrompt is shown in the status bar while waiting for
+// confirmation.
+func (m model) substitutePrompt() string {
+	s := m.substitute
+	line := m.CurrentBuffer().Line(s.pos.line)
+	replacement := string(s.re.ExpandString(nil, s.template, line, s.match))
+	return fmt.Sprintf("replace with %s (y/n/a/q/l)?", replacement)
+}
+
+// currentMatch returns the match waiting for confirmation.
+func (s *substituteState) currentMatch() textRange {
+	return textRange{
+		start: position{line: s.pos.line, col: s.match[0]},
+		end:   position{line: s.pos.line, col: s.match[1]},
+	}
+}
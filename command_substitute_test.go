@@ -0,0 +1,148 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func runCommand(m model, line string) model {
+	m.mode = ModeCommand
+	m.commandBuffer = line
+	newModel, _ := m.updateCommand(tea.KeyMsg{Type: tea.KeyEnter})
+	return newModel.(model)
+}
+
+func TestSubstitute(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		cursor  int
+		command string
+		want    []string
+		message string
+	}{
+		{"current line", "a a\na a", 1, "s/a/b/", []string{"a a", "b a"}, "1 substitution on 1 line"},
+		{"global flag", "a a\na a", 0, "s/a/b/g", []string{"b b", "a a"}, "2 substitutions on 1 line"},
+		{"whole buffer", "a a\nx\na a", 0, "%s/a/b/g", []string{"b b", "x", "b b"}, "4 substitutions on 2 lines"},
+		{"line range", "a\na\na\na", 0, "2,3s/a/b/", []string{"a", "b", "b", "a"}, "2 substitutions on 2 lines"},
+		{"to the last line", "a\na\na", 0, ".,$s/a/b/", []string{"b", "b", "b"}, "3 substitutions on 3 lines"},
+		{"capture groups", "key=value", 0, `s/(\w+)=(\w+)/$2=\1/`, []string{"value=key"}, "1 substitution on 1 line"},
+		{"whole match", "abc", 0, `s/b/[&]/`, []string{"a[b]c"}, "1 substitution on 1 line"},
+		{"named groups", "john smith", 0, `s/(?P<first>\w+) (?P<last>\w+)/${last}, ${first}/`, []string{"smith, john"}, "1 substitution on 1 line"},
+		{"ignore case", "Abc abc", 0, "s/abc/x/gi", []string{"x x"}, "2 substitutions on 1 line"},
+		{"match case", "Abc abc", 0, "s/abc/x/g", []string{"Abc x"}, "1 substitution on 1 line"},
+		{"other delimiter", "a/b", 0, "s#/#-#", []string{"a-b"}, "1 substitution on 1 line"},
+		{"escaped delimiter", "a/b", 0, `s/\//-/`, []string{"a-b"}, "1 substitution on 1 line"},
+		{"spaces", "a b c", 0, "s/ b /_/", []string{"a_c"}, "1 substitution on 1 line"},
+		{"not found", "abc", 0, "s/x/y/", []string{"abc"}, "Pattern not found: x"},
+		{"invalid flag", "abc", 0, "s/a/b/z", []string{"abc"}, "Invalid flag: z"},
+		{"invalid range", "abc", 0, "1,5s/a/b/", []string{"abc"}, "Invalid range"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := initialModel()
+			m.buffers[0] = newBuffer(m.style, bufferWithContent("", tt.content))
+			m.buffers[0] = m.buffers[0].SetCursorY(tt.cursor)
+
+			m = runCommand(m, tt.command)
+			if got := m.CurrentBuffer().Lines(); !slices.Equal(got, tt.want) {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+			if m.currentMessage == nil || m.currentMessage.text != tt.message {
+				t.Errorf("Expected message %q, got %v", tt.message, m.currentMessage)
+			}
+		})
+	}
+}
+
+func TestSubstituteIsOneUndoStep(t *testing.T) {
+	m := initialModel()
+	m.buffers[0] = newBuffer(m.style, bufferWithContent("", "a\na\na"))
+
+	m = runCommand(m, "%s/a/b/")
+	if m.CurrentBuffer().state != bufferStateModified {
+		t.Errorf("Expected the buffer to be modified, got %s", m.CurrentBuffer().state)
+	}
+
+	m = pressKeys(m, runeKeys("u")...)
+	if got := m.CurrentBuffer().Lines(); !slices.Equal(got, []string{"a", "a", "a"}) {
+		t.Errorf("Expected a single undo to revert all lines, got %q", got)
+	}
+}
+
+func TestSubstituteUsesLastSearch(t *testing.T) {
+	m := initialModel()
+	m.buffers[0] = newBuffer(m.style, bufferWithContent("", "foo bar"))
+
+	m = pressKeys(m, runeKeys("/bar")...)
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEnter})
+	m = runCommand(m, "s//baz/")
+	if got := m.CurrentBuffer().Line(0); got != "foo baz" {
+		t.Errorf("Expected the last search pattern to be used, got %q", got)
+	}
+}
+
+func TestSubstituteConfirm(t *testing.T) {
+	m := initialModel()
+	m.buffers[0] = newBuffer(m.style, bufferWithContent("", "a a a\na\na"))
+
+	m = runCommand(m, "%s/a/b/gc")
+	if m.mode != ModeConfirm {
+		t.Fatalf("Expected to wait for confirmation, got %s", m.mode)
+	}
+	if got := m.substitute.currentMatch(); got.start != (position{}) {
+		t.Errorf("Expected the first match to be current, got %v", got)
+	}
+
+	// Replace the first, skip the second and replace the third one
+	m = pressKeys(m, runeKeys("yny")...)
+	if got := m.CurrentBuffer().Line(0); got != "b a b" {
+		t.Errorf("Expected b a b, got %q", got)
+	}
+	if got := m.substitute.currentMatch(); got.start != (position{line: 1}) {
+		t.Errorf("Expected the match on the second line to be current, got %v", got)
+	}
+
+	// Replace the rest
+	m = pressKeys(m, runeKeys("a")...)
+	if m.mode != ModeNormal || m.substitute != nil {
+		t.Fatalf("Expected the substitution to finish, got %s", m.mode)
+	}
+	if got := m.CurrentBuffer().Lines(); !slices.Equal(got, []string{"b a b", "b", "b"}) {
+		t.Errorf("Expected all remaining matches to be replaced, got %q", got)
+	}
+	if m.currentMessage == nil || m.currentMessage.text != "4 substitutions on 3 lines" {
+		t.Errorf("Expected the number of substitutions, got %v", m.currentMessage)
+	}
+
+	m = pressKeys(m, runeKeys("u")...)
+	if got := m.CurrentBuffer().Lines(); !slices.Equal(got, []string{"a a a", "a", "a"}) {
+		t.Errorf("Expected a single undo to revert the substitution, got %q", got)
+	}
+}
+
+func TestSubstituteConfirmQuit(t *testing.T) {
+	m := initialModel()
+	m.buffers[0] = newBuffer(m.style, bufferWithContent("", "a\na"))
+
+	m = runCommand(m, "%s/a/b/c")
+	m = pressKeys(m, runeKeys("q")...)
+	if m.mode != ModeNormal {
+		t.Fatalf("Expected q to stop the substitution, got %s", m.mode)
+	}
+	if got := m.CurrentBuffer().Lines(); !slices.Equal(got, []string{"a", "a"}) {
+		t.Errorf("Expected nothing to be replaced, got %q", got)
+	}
+	if m.CurrentBuffer().state == bufferStateModified {
+		t.Error("Expected the buffer not to be modified")
+	}
+
+	m = runCommand(m, "%s/a/b/c")
+	m = pressKeys(m, runeKeys("l")...)
+	if got := m.CurrentBuffer().Lines(); !slices.Equal(got, []string{"b", "a"}) {
+		t.Errorf("Expected l to replace only the first match, got %q", got)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// commandTabNew is :tabnew, opening a tab page with an empty buffer or with
+// the file from the argument.
+type commandTabNew struct {
+}
+
+func (c commandTabNew) Update(m model, msg tea.Msg, args []string) (model, tea.Cmd) {
+	m.commandBuffer = ""
+	m.mode = ModeNormal
+
+	if len(args) > 0 && args[0] != "" {
+		// The file is shown in place of the empty buffer
+		m = m.newTab()
+		empty := m.currBuffer
+		var err error
+		m, err = m.openFile(args[0])
+		if err != nil {
+			return m.SetErrorMessage("Failed to open file: " + err.Error()), nil
+		}
+		return m.deleteBuffer(empty), nil
+	}
+	return m.newTab(), nil
+}
+
+func (c commandTabNew) Aliases() []string {
+	return []string{"tabnew", "tabe", "tabedit"}
+}
+
+func (c commandTabNew) Complete(m model, arg string) []string {
+	return completeFiles(arg)
+}
+
+// commandTabNext is :tabnext, going to the next tab page or to the one with
+// the number.
+type commandTabNext struct {
+}
+
+func (c commandTabNext) Update(m model, msg tea.Msg, args []string) (model, tea.Cmd) {
+	m.commandBuffer = ""
+	m.mode = ModeNormal
+
+	if len(args) > 0 && args[0] != "" {
+		i, err := m.findTabArg(args[0])
+		if err != nil {
+			return m.SetErrorMessage(err.Error()), nil
+		}
+		return m.selectTab(i), nil
+	}
+	return m.cycleTab(1), nil
+}
+
+func (c commandTabNext) Aliases() []string {
+	return []string{"tabnext", "tabn"}
+}
+
+// commandTabPrev is :tabprevious, going to the previous tab page.
+type commandTabPrev struct {
+}
+
+func (c commandTabPrev) Update(m model, msg tea.Msg, args []string) (model, tea.Cmd) {
+	m.commandBuffer = ""
+	m.mode = ModeNormal
+	return m.cycleTab(-1), nil
+}
+
+func (c commandTabPrev) Aliases() []string {
+	return []string{"tabprevious", "tabp", "tabNext", "tabN"}
+}
+
+// commandTabClose is :tabclose, closing the current tab page or the one
+// with the number.
+type commandTabClose struct {
+}
+
+func (c commandTabClose) Update(m model, msg tea.Msg, args []string) (model, tea.Cmd) {
+	m.commandBuffer = ""
+	m.mode = ModeNormal
+
+	i := m.currTab
+	if len(args) > 0 && args[0] != "" {
+		var err error
+		if i, err = m.findTabArg(args[0]); err != nil {
+			return m.SetErrorMessage(err.Error()), nil
+		}
+	}
+
+	m, err := m.closeTab(i)
+	if err != nil {
+		return m.SetErrorMessage(err.Error()), nil
+	}
+	return m, nil
+}
+
+func (c commandTabClose) Aliases() []string {
+	return []string{"tabclose", "tabc"}
+}
+
+// findTabArg returns the tab page with the number.
+func (m model) findTabArg(arg string) (int, error) {
+	n, err := strconv.Atoi(arg)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid tab page number: %s", arg)
+	}
+	if n < 1 || n > max(len(m.tabs), 1) {
+		return 0, fmt.Errorf("Tab page %d does not exist", n)
+	}
+	return n - 1, nil
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type commandUndo struct {
+}
+
+func (c commandUndo) Update(m model, msg tea.Msg, args []string) (model, tea.Cmd) {
+	m.commandBuffer = ""
+	m.mode = ModeNormal
+
+	b := m.buffers[m.currBuffer]
+	if !b.CanUndo() {
+		return m.SetInfoMessage("Already at oldest change"), nil
+	}
+
+	m.buffers[m.currBuffer] = b.Undo()
+
+	return m, nil
+}
+
+func (c commandUndo) Aliases() []string {
+	return []string{"undo", "u"}
+}
+
+type commandRedo struct {
+}
+
+func (c commandRedo) Update(m model, msg tea.Msg, args []string) (model, tea.Cmd) {
+	m.commandBuffer = ""
+	m.mode = ModeNormal
+
+	b := m.buffers[m.currBuffer]
+	if !b.CanRedo() {
+		return m.SetInfoMessage("Already at newest change"), nil
+	}
+
+	m.buffers[m.currBuffer] = b.Redo()
+
+	return m, nil
+}
+
+func (c commandRedo) Aliases() []string {
+	return []string{"redo", "red"}
+}
+
+// parseUndoTravel parses the argument of :earlier and :later. It is either a
+// number of changes or a duration with one of the s, m, h or d suffixes.
+func parseUndoTravel(args []string) (int, time.Duration, error) {
+	if len(args) == 0 || args[0] == "" {
+		return 1, 0, nil
+	}
+
+	arg := args[0]
+	if n, err := strconv.Atoi(arg); err == nil && n > 0 {
+		return n, 0, nil
+	}
+
+	units := map[byte]time.Duration{
+		's': time.Second,
+		'm': time.Minute,
+		'h': time.Hour,
+		'd': 24 * time.Hour,
+	}
+	unit, ok := units[arg[len(arg)-1]]
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid argument: %s", arg)
+	}
+	n, err := strconv.Atoi(arg[:len(arg)-1])
+	if err != nil || n <= 0 {
+		return 0, 0, fmt.Errorf("invalid argument: %s", arg)
+	}
+
+	return 0, time.Duration(n) * unit, nil
+}
+
+type commandEarlier struct {
+}
+
+func (c commandEarlier) Update(m model, msg tea.Msg, args []string) (model, tea.Cmd) {
+	m.commandBuffer = ""
+	m.mode = ModeNormal
+
+	count, d, err := parseUndoTravel(args)
+	if err != nil {
+		return m.SetErrorMessage(err.Error()), nil
+	}
+
+	b := m.buffers[m.currBuffer]
+	if len(b.history.nodes) == 0 {
+		return m.SetInfoMessage("Already at oldest change"), nil
+	}
+	cur, _ := b.UndoSeq()
+	target := max(0, cur-count)
+	if d > 0 {
+		target = min(cur, b.undoSeqAt(b.history.nodes[cur].time.Add(-d)))
+	}
+	if target == cur {
+		return m.SetInfoMessage("Already at oldest change"), nil
+	}
+
+	m.buffers[m.currBuffer] = b.UndoJump(target)
+
+	return m.SetInfoMessage(undoSeqInfo(m.buffers[m.currBuffer])), nil
+}
+
+func (c commandEarlier) Aliases() []string {
+	return []string{"earlier", "ea"}
+}
+
+type commandLater struct {
+}
+
+func (c commandLater) Update(m model, msg tea.Msg, args []string) (model, tea.Cmd) {
+	m.commandBuffer = ""
+	m.mode = ModeNormal
+
+	count, d, err := parseUndoTravel(args)
+	if err != nil {
+		return m.SetErrorMessage(err.Error()), nil
+	}
+
+	b := m.buffers[m.currBuffer]
+	if len(b.history.nodes) == 0 {
+		return m.SetInfoMessage("Already at newest change"), nil
+	}
+	cur, last := b.UndoSeq()
+	target := min(last, cur+count)
+	if d > 0 {
+		target = max(cur, b.undoSeqAt(b.history.nodes[cur].time.Add(d)))
+	}
+	if target == cur {
+		return m.SetInfoMessage("Already at newest change"), nil
+	}
+
+	m.buffers[m.currBuffer] = b.UndoJump(target)
+
+	return m.SetInfoMessage(undoSeqInfo(m.buffers[m.currBuffer])), nil
+}
+
+func (c commandLater) Aliases() []string {
+	return []string{"later", "lat"}
+}
+
+func undoSeqInfo(b buffer) string {
+	cur, last := b.UndoSeq()
+	return fmt.Sprintf("Change #%d of %d", cur, last)
+}
+
+type commandUndoTree struct {
+}
+
+// Update opens the undo tree as a list. Branches are indented below the
+// state they were started from, so the main line of changes stays on the
+// left.
+func (c commandUndoTree) Update(m model, msg tea.Msg, args []string) (model, tea.Cmd) {
+	m.commandBuffer = ""
+	m.mode = ModeNormal
+
+	// Make sure the tree has a root and includes changes made outside of an
+	// undo step
+	b := m.buffers[m.currBuffer].beginUndoStep().commitUndoStep()
+	m.buffers[m.currBuffer] = b
+	h := b.history
+
+	children := make([][]int, len(h.nodes))
+	for i, n := range h.nodes {
+		if n.parent >= 0 {
+			children[n.parent] = append(children[n.parent], i)
+		}
+	}
+
+	var items []listItem
+	var seqs []int
+	selected := 0
+
+	var walk func(n, level int)
+	walk = func(n, level int) {
+		node := h.nodes[n]
+		marker := " "
+		if n == h.cur {
+			marker = "*"
+			selected = len(items)
+		}
+		label := fmt.Sprintf("%s%s #%d  %s  %d lines",
+			strings.Repeat("  ", level), marker, n, node.time.Format("2006-01-02 15:04:05"), node.state.lines.Len())
+		if n == 0 {
+			label += "  (original)"
+		}
+
+		items = append(items, listItem{label: label, preview: undoPreview(node.state)})
+		seqs = append(seqs, n)
+
+		if len(children[n]) == 0 {
+			return
+		}
+		for _, child := range children[n][1:] {
+			walk(child, level+1)
+		}
+		walk(children[n][0], level)
+	}
+	walk(0, 0)
+
+	return m.OpenList("Undo tree", items, selected, func(m model, i int) (model, tea.Cmd) {
+		m.buffers[m.currBuffer] = m.buffers[m.currBuffer].UndoJump(seqs[i])
+		return m.SetInfoMessage(undoSeqInfo(m.buffers[m.currBuffer])), nil
+	}), nil
+}
+
+func (c commandUndoTree) Aliases() []string {
+	return []string{"undotree", "undol", "undolist"}
+}
+
+// undoPreview returns the lines around the place where the change was made.
+func undoPreview(s undoState) []string {
+	start := max(0, s.cursorY-3)
+	end := min(s.lines.Len(), start+10)
+
+	var lines []string
+	for i := start; i < end; i++ {
+		lines = append(lines, s.lines.Line(i))
+	}
+	return lines
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// commandSplit is :split and :vsplit, opening a new window on the current
+// buffer or on the file from the argument.
+type commandSplit struct {
+	vertical bool
+}
+
+func (c commandSplit) Update(m model, msg tea.Msg, args []string) (model, tea.Cmd) {
+	m.commandBuffer = ""
+	m.mode = ModeNormal
+
+	m = m.splitWindow(c.vertical)
+	if len(args) > 0 && args[0] != "" {
+		var err error
+		m, err = m.openFile(args[0])
+		if err != nil {
+			return m.SetErrorMessage("Failed to open file: " + err.Error()), nil
+		}
+	}
+	return m, nil
+}
+
+func (c commandSplit) Aliases() []string {
+	if c.vertical {
+		return []string{"vsplit", "vs"}
+	}
+	return []string{"split", "sp"}
+}
+
+func (c commandSplit) Complete(m model, arg string) []string {
+	return completeFiles(arg)
+}
+
+// commandClose is :close, closing the focused window.
+type commandClose struct {
+}
+
+func (c commandClose) Update(m model, msg tea.Msg, args []string) (model, tea.Cmd) {
+	m.commandBuffer = ""
+	m.mode = ModeNormal
+
+	m, err := m.closeWindow(m.windows.curr)
+	if err != nil {
+		return m.SetErrorMessage(err.Error()), nil
+	}
+	return m, nil
+}
+
+func (c commandClose) Aliases() []string {
+	return []string{"close", "clo"}
+}
+
+// commandOnly is :only, closing all windows but the focused one.
+type commandOnly struct {
+}
+
+func (c commandOnly) Update(m model, msg tea.Msg, args []string) (model, tea.Cmd) {
+	m.commandBuffer = ""
+	m.mode = ModeNormal
+
+	if !m.hasSplits() {
+		return m.SetInfoMessage("Already only one window"), nil
+	}
+	return m.onlyWindow(), nil
+}
+
+func (c commandOnly) Aliases() []string {
+	return []string{"only", "on"}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"strings"
 
@@ -20,7 +21,7 @@ func (c commandWrite) Update(m model, msg tea.Msg, args []string) (model, tea.Cm
 			return m.SetErrorMessage("No filename specified"), nil
 		}
 		
-		content := strings.Join(buf.lines, "\n")
+		content := buf.Content()
 		err := os.WriteFile(buf.filename, []byte(content), 0644)
 		if err != nil {
 			m.commandBuffer = ""
@@ -29,7 +30,13 @@ func (c commandWrite) Update(m model, msg tea.Msg, args []string) (model, tea.Cm
 		}
 		
 		// Mark buffer as saved
-		m.buffers[m.currBuffer] = buf.SetStateSaved()
+		buf = buf.SetStateSaved()
+		buf.writes++
+		m.buffers[m.currBuffer] = buf
+
+		// The file is already written, failing to persist the history only
+		// means it won't be available after a restart
+		_ = buf.saveUndoFile()
 		
 		// Clear command buffer and switch to normal mode
 		m.commandBuffer = ""
@@ -40,7 +47,7 @@ func (c commandWrite) Update(m model, msg tea.Msg, args []string) (model, tea.Cm
 	// Write to specified filename
 	filename := args[0]
 	buf := m.buffers[m.currBuffer]
-	content := strings.Join(buf.lines, "\n")
+	content := buf.Content()
 	
 	err := os.WriteFile(filename, []byte(content), 0644)
 	if err != nil {
@@ -52,13 +59,43 @@ func (c commandWrite) Update(m model, msg tea.Msg, args []string) (model, tea.Cm
 	// Update buffer filename and mark as saved
 	buf = buf.SetFileName(filename)
 	buf = buf.SetStateSaved()
+	buf.writes++
 	m.buffers[m.currBuffer] = buf
+	_ = buf.saveUndoFile()
 	
 	m.commandBuffer = ""
 	m.mode = ModeNormal
 	return m.SetInfoMessage("File written successfully to " + filename), nil
 }
 
+// UpdateRange writes only the lines in the range to the given file, like
+// :.,$w part.txt. The buffer keeps its name and state.
+func (c commandWrite) UpdateRange(m model, msg tea.Msg, r lineRange, args []string) (model, tea.Cmd) {
+	buf := m.buffers[m.currBuffer]
+	if !r.given || (r.start == 0 && r.end == buf.NoOfLines()-1) {
+		return c.Update(m, msg, args)
+	}
+
+	m.commandBuffer = ""
+	m.mode = ModeNormal
+	if len(args) == 0 || args[0] == "" || args[0] == buf.filename {
+		return m.SetErrorMessage("Use a file name to write a part of the buffer"), nil
+	}
+
+	filename := args[0]
+	lines := buf.LineSlice(r.start, r.end+1)
+	err := os.WriteFile(filename, []byte(strings.Join(lines, "\n")), 0644)
+	if err != nil {
+		return m.SetErrorMessage("Failed to write file: " + err.Error()), nil
+	}
+
+	return m.SetInfoMessage(fmt.Sprintf("%d line%s written to %s", len(lines), plural(len(lines)), filename)), nil
+}
+
 func (c commandWrite) Aliases() []string {
 	return []string{"write", "w", "save"}
-} 
\ No newline at end of file
+}
+
+func (c commandWrite) Complete(m model, arg string) []string {
+	return completeFiles(arg)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestExpandSnippet(t *testing.T) {
+	tests := []struct {
+		snippet string
+		text    string
+		cursor  int
+	}{
+		{"plain", "plain", 5},
+		{"f($1)$0", "f()", 2},
+		{"f(${1:a}, ${2:b})", "f(a, b)", 2},
+		{"for ${1:i} := range ${2:n} {\n\t$0\n}", "for i := range n {\n\t\n}", 4},
+		{"x := $0", "x := ", 5},
+		{"${1|one,two|}", "one", 0},
+		{"${1:f(${2:x})}", "f(x)", 0},
+		{`\$1 ${TM_FILENAME}end`, "$1 end", 6},
+	}
+	for _, tt := range tests {
+		text, cursor := expandSnippet(tt.snippet)
+		if text != tt.text || cursor != tt.cursor {
+			t.Errorf("expandSnippet(%q) = %q, %d; want %q, %d", tt.snippet, text, cursor, tt.text, tt.cursor)
+		}
+	}
+}
+
+// completionTestModel is in insert mode on an empty line of main.
+func completionTestModel(t *testing.T) model {
+	m, _ := lspTestModel(t, os.Args[0], map[string]string{
+		"main.go": "package main\n\nfunc main() {\n\t\n}\n",
+	})
+	m = waitUntil(t, m, func(m model) bool { return m.currentMessage != nil })
+	return pressKeys(m, runeKeys("jjjA")...)
+}
+
+func completionShown(m model) bool {
+	return m.insertCompletion != nil && len(m.insertCompletion.matches) > 0
+}
+
+func TestCompletionSnippet(t *testing.T) {
+	m := completionTestModel(t)
+	defer m.lsp.Shutdown()
+
+	m = pressKeys(m, runeKeys("he")...)
+	m = waitUntil(t, m, completionShown)
+	if got := len(m.insertCompletion.matches); got != 2 {
+		t.Fatalf("Expected 2 items, got %d", got)
+	}
+	view := m.View()
+	for _, want := range []string{"helper", "hello", "func", "var", "Docs of helper"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected %q in the menu", want)
+		}
+	}
+
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyCtrlN})
+	if m.insertCompletion.selected != 1 {
+		t.Errorf("Expected the second item selected, got %d", m.insertCompletion.selected)
+	}
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyCtrlP}, tea.KeyMsg{Type: tea.KeyEnter})
+	if line := m.CurrentBuffer().Line(3); line != "\thelper(a, b)" || cursorOf(m) != (position{line: 3, col: 8}) {
+		t.Errorf("Expected the snippet with the cursor on a, got %q %v", line, cursorOf(m))
+	}
+	if m.insertCompletion != nil || m.mode != ModeInsert {
+		t.Errorf("Expected the menu closed in insert mode")
+	}
+}
+
+func TestCompletionFiltering(t *testing.T) {
+	m := completionTestModel(t)
+	defer m.lsp.Shutdown()
+
+	m = pressKeys(m, runeKeys("h")...)
+	m = waitUntil(t, m, completionShown)
+	m = pressKeys(m, runeKeys("lo")...)
+	if len(m.insertCompletion.matches) != 1 || m.insertCompletion.matches[0].text != "hello" {
+		t.Fatalf("Expected only hello to match, got %+v", m.insertCompletion.matches)
+	}
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyBackspace}, tea.KeyMsg{Type: tea.KeyBackspace}, tea.KeyMsg{Type: tea.KeyBackspace})
+	if !completionShown(m) || len(m.insertCompletion.matches) != 2 {
+		t.Fatalf("Expected all the items without a word")
+	}
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyBackspace})
+	if m.insertCompletion != nil {
+		t.Errorf("Expected the menu closed after leaving the word")
+	}
+
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyCtrlN})
+	m = waitUntil(t, m, completionShown)
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyCtrlE})
+	if m.insertCompletion != nil {
+		t.Errorf("Expected ctrl+e to close the menu")
+	}
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.CurrentBuffer().Line(4) != "" {
+		t.Errorf("Expected enter to break the line without the menu, got %q", m.CurrentBuffer().Line(4))
+	}
+}
+
+func TestCompletionAdditionalEdits(t *testing.T) {
+	m := completionTestModel(t)
+	defer m.lsp.Shutdown()
+
+	m = pressKeys(m, runeKeys("fmt.")...)
+	m = waitUntil(t, m, completionShown)
+	m = pressKeys(m, runeKeys("Pr")...)
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyTab})
+
+	want := "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println\n}\n"
+	if got := strings.Join(m.CurrentBuffer().Lines(), "\n"); got != want {
+		t.Errorf("Expected the import added, got %q", got)
+	}
+	if cursorOf(m) != (position{line: 5, col: 12}) {
+		t.Errorf("Expected the cursor after Println, got %v", cursorOf(m))
+	}
+}
+
+func TestPositionAfterEdits(t *testing.T) {
+	edits := []textEdit{
+		{start: position{line: 0, col: 0}, end: position{line: 0, col: 0}, text: "a\nb"},
+		{start: position{line: 2, col: 1}, end: position{line: 2, col: 3}, text: "x"},
+		{start: position{line: 5, col: 0}, end: position{line: 5, col: 0}, text: "later"},
+	}
+	if got := positionAfterEdits(position{line: 2, col: 4}, edits); got != (position{line: 3, col: 3}) {
+		t.Errorf("Expected (3, 3), got %v", got)
+	}
+}
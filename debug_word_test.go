@@ -15,7 +15,7 @@ func TestDebugWordNavigation(t *testing.T) {
 	m.viewport = tea.WindowSizeMsg{Width: 80, Height: 24}
 	
 	lines := strings.Split(content, "\n")
-	m.buffers[0].lines = lines
+	m.buffers[0].lines = newRope(lines)
 	m.buffers[0].cursorX = 10 // position at 't' in "test"
 	m.buffers[0].viewport = m.viewport
 
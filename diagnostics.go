@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+type diagnosticSeverity int
+
+// The severities as numbered by the protocol
+const (
+	severityError diagnosticSeverity = iota + 1
+	severityWarning
+	severityInformation
+	severityHint
+)
+
+// diagnostic is a problem in the buffer reported by a language server. The
+// range is in bytes, like the cursor.
+type diagnostic struct {
+	textRange
+	severity diagnosticSeverity
+	message  string
+	source   string
+}
+
+type lspDiagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"`
+	Message  string   `json:"message"`
+	Source   string   `json:"source"`
+}
+
+type lspPublishDiagnosticsParams struct {
+	URI         string          `json:"uri"`
+	Diagnostics []lspDiagnostic `json:"diagnostics"`
+}
+
+func (s diagnosticSeverity) String() string {
+	switch s {
+	case severityError:
+		return "error"
+	case severityWarning:
+		return "warning"
+	case severityInformation:
+		return "info"
+	}
+	return "hint"
+}
+
+// sign is the letter shown in the gutter.
+func (s diagnosticSeverity) sign() string {
+	return strings.ToUpper(s.String()[:1])
+}
+
+func (s diagnosticSeverity) style(style editorStyle) lipgloss.Style {
+	switch s {
+	case severityError:
+		return style.diagnosticError
+	case severityWarning:
+		return style.diagnosticWarning
+	case severityInformation:
+		return style.diagnosticInfo
+	}
+	return style.diagnosticHint
+}
+
+// publishDiagnostics replaces the diagnostics of the buffer holding the
+// file. Files which aren't open are ignored.
+func (m model) publishDiagnostics(raw json.RawMessage) model {
+	var params lspPublishDiagnosticsParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return m
+	}
+
+	for i, b := range m.buffers {
+		if b.filename == "" || fileURI(b.filename) != params.URI {
+			continue
+		}
+		b.diagnostics = nil
+		for _, d := range params.Diagnostics {
+			b.diagnostics = append(b.diagnostics, b.diagnosticFromLSP(d))
+		}
+		slices.SortStableFunc(b.diagnostics, func(a, b diagnostic) int {
+			if a.start.before(b.start) {
+				return -1
+			}
+			if b.start.before(a.start) {
+				return 1
+			}
+			return int(a.severity - b.severity)
+		})
+		m.buffers[i] = b
+	}
+	return m
+}
+
+func (b buffer) diagnosticFromLSP(d lspDiagnostic) diagnostic {
+	severity := diagnosticSeverity(d.Severity)
+	if severity < severityError || severity > severityHint {
+		severity = severityError
+	}
+
+	r := textRange{start: b.bytePosition(d.Range.Start), end: b.bytePosition(d.Range.End)}
+	if r.start == r.end {
+		// An empty range still marks the character it's at
+		r.end.col++
+	}
+	return diagnostic{textRange: r, severity: severity, message: d.Message, source: d.Source}
+}
+
+// bytePosition converts a position of the protocol, counting UTF-16 code
+// units, to the byte offset in the line.
+func (b buffer) bytePosition(p lspPosition) position {
+	return position{line: p.Line, col: utf16ToByteOffset(b.Line(p.Line), p.Character)}
+}
+
+// lineSeverity is the most severe diagnostic on the line, if there's one.
+func (b buffer) lineSeverity(y int) (diagnosticSeverity, bool) {
+	var severity diagnosticSeverity
+	for _, d := range b.diagnostics {
+		if d.start.line <= y && y <= d.end.line && (severity == 0 || d.severity < severity) {
+			severity = d.severity
+		}
+	}
+	return severity, severity != 0
+}
+
+// gutter is drawn left of the line: the sign of its diagnostics, when the
+// buffer has any, and the line number.
+func (b buffer) gutter(y int) string {
+	number := lineNumber(y+1, b.NoOfLines())
+	if len(b.diagnostics) == 0 {
+		return number
+	}
+	if severity, ok := b.lineSeverity(y); ok {
+		return severity.style(b.style).Render(severity.sign()) + number
+	}
+	return " " + number
+}
+
+// gutterWidth is the width of the gutter in columns.
+func (b buffer) gutterWidth() int {
+	width := len(fmt.Sprintf("%d", b.NoOfLines())) + 1
+	if len(b.diagnostics) > 0 {
+		width++
+	}
+	return width
+}
+
+// diagnosticHighlights underline the ranges of the diagnostics.
+func (b buffer) diagnosticHighlights() []highlight {
+	var highlights []highlight
+	for _, d := range slices.Backward(b.diagnostics) {
+		highlights = append(highlights, highlight{textRange: d.textRange, style: d.severity.style(b.style).Underline(true)})
+	}
+	return highlights
+}
+
+// cursorDiagnostic is the diagnostic under the cursor, or the first one on
+// its line.
+func (b buffer) cursorDiagnostic() (diagnostic, bool) {
+	cur := position{line: b.cursorY, col: b.cursorX}
+	for _, d := range b.diagnostics {
+		if !cur.before(d.start) && cur.before(d.end) {
+			return d, true
+		}
+	}
+	for _, d := range b.diagnostics {
+		if d.start.line <= cur.line && cur.line <= d.end.line {
+			return d, true
+		}
+	}
+	return diagnostic{}, false
+}
+
+// showCursorDiagnostic shows the message of the diagnostic under the cursor
+// unless another message is shown.
+func (m model) showCursorDiagnostic() model {
+	if m.mode != ModeNormal || m.currentMessage != nil {
+		return m
+	}
+	if d, ok := m.CurrentBuffer().cursorDiagnostic(); ok {
+		return m.SetErrorMessage(d.message)
+	}
+	return m
+}
+
+// diagnosticTarget returns a motion target jumping to the start of the next
+// (or previous) diagnostic.
+func diagnosticTarget(forward bool) func(m model, count int) position {
+	return func(m model, count int) position {
+		b := m.CurrentBuffer()
+		starts := make([]position, 0, len(b.diagnostics))
+		for _, d := range b.diagnostics {
+			starts = append(starts, d.start)
+		}
+
+		target := position{line: b.cursorY, col: b.cursorX}
+		for range max(1, count) {
+			next, ok := nextPosition(starts, target, forward)
+			if !ok {
+				break
+			}
+			target = next
+		}
+		return target
+	}
+}
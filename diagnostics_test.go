@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func publishTestDiagnostics(t *testing.T, m model, params lspPublishDiagnosticsParams) model {
+	t.Helper()
+	raw, err := json.Marshal(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, _ := m.Update(lspServerMsg{method: "textDocument/publishDiagnostics", params: raw})
+	return res.(model)
+}
+
+func diagnosticsTestModel(t *testing.T) model {
+	m := initialModel()
+	m.viewport = tea.WindowSizeMsg{Width: 80, Height: 24}
+	m.buffers[0] = newBuffer(m.style, bufferWithContent("main.go", "package main\n\nfunc main() {\n\tfmt.Println(\"héllo\", x)\n}"))
+	m.buffers[0].viewport = m.viewport
+
+	return publishTestDiagnostics(t, m, lspPublishDiagnosticsParams{
+		URI: fileURI("main.go"),
+		Diagnostics: []lspDiagnostic{
+			{Range: lspRange{Start: lspPosition{Line: 3, Character: 21}, End: lspPosition{Line: 3, Character: 22}}, Severity: 1, Message: "undefined: x"},
+			{Range: lspRange{Start: lspPosition{Line: 3, Character: 1}, End: lspPosition{Line: 3, Character: 4}}, Severity: 1, Message: "undefined: fmt"},
+			{Range: lspRange{Start: lspPosition{Line: 0, Character: 8}, End: lspPosition{Line: 0, Character: 12}}, Severity: 2, Message: "unused package"},
+		},
+	})
+}
+
+func TestDiagnosticsPublished(t *testing.T) {
+	m := diagnosticsTestModel(t)
+
+	b := m.CurrentBuffer()
+	if len(b.diagnostics) != 3 {
+		t.Fatalf("Expected 3 diagnostics, got %v", b.diagnostics)
+	}
+	// The UTF-16 column is after "é", which takes 2 bytes
+	want := textRange{start: position{line: 3, col: 22}, end: position{line: 3, col: 23}}
+	if b.diagnostics[2].textRange != want {
+		t.Errorf("Expected the range %v, got %v", want, b.diagnostics[2].textRange)
+	}
+
+	lines := strings.Split(m.View(), "\n")
+	if !strings.HasPrefix(lines[0], "W1 ") || !strings.HasPrefix(lines[1], " 2 ") || !strings.HasPrefix(lines[3], "E4 ") {
+		t.Errorf("Expected the signs in the gutter, got %q", lines[:4])
+	}
+}
+
+func TestDiagnosticUnderCursor(t *testing.T) {
+	m := diagnosticsTestModel(t)
+
+	m = pressKeys(m, runeKeys("jjj")...)
+	if m.currentMessage == nil || m.currentMessage.text != "undefined: fmt" || m.currentMessage.msgType != MessageError {
+		t.Fatalf("Expected the diagnostic of the line, got %+v", m.currentMessage)
+	}
+
+	m = pressKeys(m, runeKeys("]d]d")...)
+	if cursorOf(m) != (position{line: 3, col: 22}) || m.currentMessage.text != "undefined: x" {
+		t.Errorf("Expected to jump to the next diagnostic, got %v %+v", cursorOf(m), m.currentMessage)
+	}
+
+	m = pressKeys(m, runeKeys("2[d")...)
+	if cursorOf(m) != (position{line: 0, col: 8}) || m.currentMessage.text != "unused package" {
+		t.Errorf("Expected to jump 2 diagnostics back, got %v %+v", cursorOf(m), m.currentMessage)
+	}
+
+	m = pressKeys(m, runeKeys("j")...)
+	if m.currentMessage != nil {
+		t.Errorf("Expected no message without a diagnostic, got %+v", m.currentMessage)
+	}
+}
+
+func TestDiagnosticsList(t *testing.T) {
+	m := diagnosticsTestModel(t)
+	m = m.addBuffer(newBuffer(m.style, bufferWithContent("other.go", "package other")))
+
+	m = runCommand(m, "diagnostics")
+	if m.mode != ModeList || len(m.list.items) != 3 {
+		t.Fatalf("Expected the list of diagnostics, got %v", m.list)
+	}
+	if m.list.items[0].label != "main.go:1:9: warning: unused package" {
+		t.Errorf("Unexpected label %q", m.list.items[0].label)
+	}
+
+	m = pressKeys(m, runeKeys("jj")...)
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.currBuffer != 0 || cursorOf(m) != (position{line: 3, col: 22}) {
+		t.Errorf("Expected the cursor on the diagnostic, got buffer %d %v", m.currBuffer, cursorOf(m))
+	}
+
+	m = m.selectBuffer(1)
+	m = pressKeys(m, runeKeys("]d")...)
+	if m.currentMessage == nil || m.currentMessage.text != "No diagnostics" {
+		t.Errorf("Expected no diagnostics in the other buffer, got %+v", m.currentMessage)
+	}
+}
+
+func TestDiagnosticsFromServer(t *testing.T) {
+	m, dir := lspTestModel(t, os.Args[0], nil)
+	defer m.lsp.Shutdown()
+
+	m = pressKeys(m, runeKeys("obad")...)
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEsc})
+	m = waitUntil(t, m, func(m model) bool { return len(m.CurrentBuffer().diagnostics) > 0 })
+
+	d := m.CurrentBuffer().diagnostics[0]
+	if d.start != (position{line: 1, col: 0}) || d.message != "bad word" {
+		t.Errorf("Expected the diagnostic of the server, got %+v", d)
+	}
+	if m.CurrentBuffer().filename != filepath.Join(dir, "main.go") {
+		t.Errorf("Expected the diagnostic in main.go, got %s", m.CurrentBuffer().filename)
+	}
+}
+
+func TestUTF16ToByteOffset(t *testing.T) {
+	line := "héllo 😀 x"
+	for character, want := range map[int]int{0: 0, 2: 3, 6: 7, 8: 11, 9: 12, 20: len(line)} {
+		if got := utf16ToByteOffset(line, character); got != want {
+			t.Errorf("Expected character %d at byte %d, got %d", character, want, got)
+		}
+	}
+}
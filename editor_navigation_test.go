@@ -65,7 +65,7 @@ func TestBasicNavigation(t *testing.T) {
 			if len(lines) == 0 {
 				lines = []string{""}
 			}
-			m.buffers[0].lines = lines
+			m.buffers[0].lines = newRope(lines)
 			m.buffers[0].viewport = m.viewport
 
 			keyMsg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(tt.key)}
@@ -125,7 +125,7 @@ func TestWordNavigation(t *testing.T) {
 			if len(lines) == 0 {
 				lines = []string{""}
 			}
-			m.buffers[0].lines = lines
+			m.buffers[0].lines = newRope(lines)
 			m.buffers[0].cursorX = tt.initialX
 			m.buffers[0].viewport = m.viewport
 
@@ -189,7 +189,7 @@ func TestBoundaryNavigation(t *testing.T) {
 			if len(lines) == 0 {
 				lines = []string{""}
 			}
-			m.buffers[0].lines = lines
+			m.buffers[0].lines = newRope(lines)
 			m.buffers[0].cursorX = tt.initialX
 			m.buffers[0].cursorY = tt.initialY
 			m.buffers[0].viewport = m.viewport
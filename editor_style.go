@@ -7,8 +7,23 @@ import (
 type editorStyle struct {
 	cursor      lipgloss.Style
 	statusBar   lipgloss.Style
+	statusBarInactive lipgloss.Style
+	windowSeparator lipgloss.Style
+	tabline     lipgloss.Style
+	tablineSelected lipgloss.Style
+	fuzzyMatch  lipgloss.Style
+	diagnosticError lipgloss.Style
+	diagnosticWarning lipgloss.Style
+	diagnosticInfo lipgloss.Style
+	diagnosticHint lipgloss.Style
+	popup       lipgloss.Style
 	messageInfo lipgloss.Style
 	messageError lipgloss.Style
+	selection   lipgloss.Style
+	visual      lipgloss.Style
+	listTitle   lipgloss.Style
+	search      lipgloss.Style
+	searchCurrent lipgloss.Style
 	keyword     lipgloss.Style
 	string      lipgloss.Style
 	comment     lipgloss.Style
@@ -24,8 +39,23 @@ func newEditorStyle() editorStyle {
 	return editorStyle{
 		cursor:      lipgloss.NewStyle().Foreground(lipgloss.Color("#383838")).Background(lipgloss.Color("#d8d8d8")), // ui.cursor.primary (grey02 on grey05)
 		statusBar:   lipgloss.NewStyle().Foreground(lipgloss.Color("#b8b8b8")).Background(lipgloss.Color("#383838")),   // ui.statusline (grey04 on grey02)
+		statusBarInactive: lipgloss.NewStyle().Foreground(lipgloss.Color("#808080")).Background(lipgloss.Color("#282828")), // ui.statusline.inactive (grey on grey01)
+		windowSeparator: lipgloss.NewStyle().Foreground(lipgloss.Color("#505050")),                                // grey03
+		tabline:     lipgloss.NewStyle().Foreground(lipgloss.Color("#808080")).Background(lipgloss.Color("#282828")),   // grey on grey01
+		fuzzyMatch:  lipgloss.NewStyle().Foreground(lipgloss.Color("#eedd82")).Bold(true),                            // yellow
+		diagnosticError: lipgloss.NewStyle().Foreground(lipgloss.Color("#ff5555")),                                // red
+		diagnosticWarning: lipgloss.NewStyle().Foreground(lipgloss.Color("#eedd82")),                              // yellow
+		diagnosticInfo: lipgloss.NewStyle().Foreground(lipgloss.Color("#8be9fd")),                                 // cyan
+		diagnosticHint: lipgloss.NewStyle().Foreground(lipgloss.Color("#808080")),                                 // grey
+		popup:       lipgloss.NewStyle().Foreground(lipgloss.Color("#d8d8d8")).Background(lipgloss.Color("#282828")),   // grey05 on grey01
+		tablineSelected: lipgloss.NewStyle().Foreground(lipgloss.Color("#d8d8d8")).Background(lipgloss.Color("#383838")).Bold(true), // grey05 on grey02
 		messageInfo: lipgloss.NewStyle().Foreground(lipgloss.Color("#8be9fd")).Background(lipgloss.Color("#383838")),   // cyan on grey02
 		messageError: lipgloss.NewStyle().Foreground(lipgloss.Color("#ff5555")).Background(lipgloss.Color("#383838")),  // red on grey02
+		selection:   lipgloss.NewStyle().Foreground(lipgloss.Color("#d8d8d8")).Background(lipgloss.Color("#505050")),   // ui.selection (grey05 on grey03)
+		visual:      lipgloss.NewStyle().Background(lipgloss.Color("#505050")),                                     // ui.selection (grey03)
+		listTitle:   lipgloss.NewStyle().Foreground(lipgloss.Color("#eedd82")).Bold(true),                            // yellow
+		search:      lipgloss.NewStyle().Foreground(lipgloss.Color("#383838")).Background(lipgloss.Color("#8be9fd")),   // grey02 on cyan
+		searchCurrent: lipgloss.NewStyle().Foreground(lipgloss.Color("#383838")).Background(lipgloss.Color("#eedd82")), // grey02 on yellow
 		keyword:     lipgloss.NewStyle().Foreground(lipgloss.Color("#cc7832")),                                     // orange
 		string:      lipgloss.NewStyle().Foreground(lipgloss.Color("#629755")),                                     // darkgreen
 		comment:     lipgloss.NewStyle().Foreground(lipgloss.Color("#808080")).Italic(true),                         // grey
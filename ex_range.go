@@ -0,0 +1,197 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// lineRange is a range of lines given in front of an ex command, like % or
+// 3,5. Lines are counted from 0 and end is inclusive. given is false when the
+// command got the current line because no range was typed.
+type lineRange struct {
+	start, end int
+	given      bool
+}
+
+var errInvalidRange = errors.New("Invalid range")
+
+// currentLineRange is the range used by commands when no range was given.
+func (b buffer) currentLineRange() lineRange {
+	return lineRange{start: b.cursorY, end: b.cursorY}
+}
+
+// parseRange parses the range at the beginning of the command line and
+// returns the rest of the line. ok is false when there's no range.
+//
+// A range is % for the whole buffer or up to two addresses separated by ,
+// or ;. After ; the first address becomes the current line for the second
+// one. An address is one of
+//
+//	N        line number
+//	.        current line
+//	$        last line
+//	'x       line of the mark x, '< and '> are the last visual selection
+//	/pat/    next line matching the pattern, ?pat? searches backward
+//
+// followed by any number of +N or -N offsets.
+func (m model) parseRange(line string) (r lineRange, rest string, ok bool, err error) {
+	b := m.CurrentBuffer()
+	rest = strings.TrimLeft(line, " ")
+	if strings.HasPrefix(rest, "%") {
+		return lineRange{start: 0, end: b.NoOfLines() - 1, given: true}, rest[1:], true, nil
+	}
+
+	cur := b.cursorY
+	start, rest, ok, err := m.parseAddress(rest, cur)
+	if err != nil {
+		return lineRange{}, rest, false, err
+	}
+	if !ok && !strings.HasPrefix(rest, ",") && !strings.HasPrefix(rest, ";") {
+		return lineRange{}, rest, false, nil
+	}
+	if !ok {
+		// ,5 is the same as .,5
+		start = cur
+	}
+	r = lineRange{start: start, end: start, given: true}
+
+	if strings.HasPrefix(rest, ",") || strings.HasPrefix(rest, ";") {
+		if rest[0] == ';' {
+			cur = start
+		}
+		end, after, ok, err := m.parseAddress(rest[1:], cur)
+		if err != nil {
+			return lineRange{}, rest, false, err
+		}
+		if !ok {
+			// 5, is the same as 5,.
+			end = cur
+		}
+		r.end, rest = end, after
+	}
+
+	if r.start > r.end {
+		r.start, r.end = r.end, r.start
+	}
+	if r.start < 0 || r.end >= b.NoOfLines() {
+		return lineRange{}, rest, false, errInvalidRange
+	}
+
+	return r, rest, true, nil
+}
+
+// parseAddress parses a single line address relative to the line cur.
+func (m model) parseAddress(s string, cur int) (int, string, bool, error) {
+	b := m.CurrentBuffer()
+	line, ok := cur, true
+
+	switch {
+	case s == "":
+		return cur, s, false, nil
+	case s[0] == '.':
+		s = s[1:]
+	case s[0] == '$':
+		line, s = b.NoOfLines()-1, s[1:]
+	case s[0] == '\'':
+		if len(s) < 2 {
+			return 0, s, false, errInvalidRange
+		}
+		name, size := []rune(s[1:])[0], len(string([]rune(s[1:])[0]))
+		p, found := b.Mark(name)
+		if !found {
+			return 0, s, false, errors.New("Mark not set: " + string(name))
+		}
+		line, s = p.line, s[1+size:]
+	case s[0] == '/' || s[0] == '?':
+		var err error
+		line, s, err = m.searchAddress(s, cur)
+		if err != nil {
+			return 0, s, false, err
+		}
+	case unicode.IsDigit(rune(s[0])):
+		n, rest := leadingNumber(s)
+		line, s = n-1, rest
+	case s[0] == '+' || s[0] == '-':
+		// An offset alone is relative to the current line
+	default:
+		ok = false
+	}
+
+	for len(s) > 0 && (s[0] == '+' || s[0] == '-') {
+		sign := 1
+		if s[0] == '-' {
+			sign = -1
+		}
+		n, rest := leadingNumber(s[1:])
+		if rest == s[1:] {
+			n = 1
+		}
+		line, s, ok = line+sign*n, rest, true
+	}
+
+	return line, s, ok, nil
+}
+
+// searchAddress finds the line matching the /pattern/ or ?pattern? at the
+// beginning of s. The search starts after (or before) the line cur and wraps
+// around. An empty pattern uses the last search pattern.
+func (m model) searchAddress(s string, cur int) (int, string, error) {
+	delim := s[0]
+	pattern, rest := splitPattern(s)
+	if pattern == "" {
+		pattern = m.search.pattern
+	}
+	if pattern == "" {
+		return 0, s, errors.New("No previous regular expression")
+	}
+
+	re, err := compileSearch(pattern)
+	if err != nil {
+		return 0, s, errors.New("Invalid pattern: " + err.Error())
+	}
+
+	b := m.CurrentBuffer()
+	n := b.NoOfLines()
+	for i := 1; i <= n; i++ {
+		y := (cur + i) % n
+		if delim == '?' {
+			y = ((cur-i)%n + n) % n
+		}
+		if re.MatchString(b.Line(y)) {
+			return y, rest, nil
+		}
+	}
+	return 0, s, errors.New("Pattern not found: " + pattern)
+}
+
+// splitPattern splits /pattern/rest into the pattern and the text after
+// the closing delimiter, which is the first character of s. The closing
+// delimiter can be left out at the end of s.
+func splitPattern(s string) (string, string) {
+	delim := s[0]
+	end := 1
+	for end < len(s) && s[end] != delim {
+		if s[end] == '\\' {
+			end++
+		}
+		end++
+	}
+
+	pattern, rest := s[1:min(end, len(s))], ""
+	if end < len(s) {
+		rest = s[end+1:]
+	}
+	return strings.ReplaceAll(pattern, `\`+string(delim), string(delim)), rest
+}
+
+// leadingNumber parses the digits at the beginning of s.
+func leadingNumber(s string) (int, string) {
+	i := 0
+	for i < len(s) && unicode.IsDigit(rune(s[i])) {
+		i++
+	}
+	n, _ := strconv.Atoi(s[:i])
+	return n, s[i:]
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseRange(t *testing.T) {
+	content := "zero\none\ntwo foo\nthree\nfour foo\nfive"
+	tests := []struct {
+		line  string
+		want  lineRange
+		rest  string
+		found bool
+		err   string
+	}{
+		{line: "d", rest: "d"},
+		{line: "3d", want: lineRange{2, 2, true}, rest: "d", found: true},
+		{line: "2,4d", want: lineRange{1, 3, true}, rest: "d", found: true},
+		{line: "4,2d", want: lineRange{1, 3, true}, rest: "d", found: true},
+		{line: "%y", want: lineRange{0, 5, true}, rest: "y", found: true},
+		{line: ".,$w part.txt", want: lineRange{1, 5, true}, rest: "w part.txt", found: true},
+		{line: ".+1,$-1d", want: lineRange{2, 4, true}, rest: "d", found: true},
+		{line: "+,++d", want: lineRange{2, 3, true}, rest: "d", found: true},
+		{line: "-d", want: lineRange{0, 0, true}, rest: "d", found: true},
+		{line: ",3d", want: lineRange{1, 2, true}, rest: "d", found: true},
+		{line: "/foo/d", want: lineRange{2, 2, true}, rest: "d", found: true},
+		{line: "/foo/;/foo/d", want: lineRange{2, 4, true}, rest: "d", found: true},
+		{line: "/foo/,/foo/d", want: lineRange{2, 2, true}, rest: "d", found: true},
+		{line: "?foo?d", want: lineRange{4, 4, true}, rest: "d", found: true},
+		{line: "/foo/+1d", want: lineRange{3, 3, true}, rest: "d", found: true},
+		{line: "'a,'bd", want: lineRange{3, 5, true}, rest: "d", found: true},
+		{line: "'<,'>d", want: lineRange{0, 2, true}, rest: "d", found: true},
+		{line: "1,10d", err: "Invalid range"},
+		{line: "/bar/d", err: "Pattern not found: bar"},
+		{line: "'xd", err: "Mark not set: x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			m := initialModel()
+			b := newBuffer(m.style, bufferWithContent("", content)).SetCursorY(1)
+			b = b.SetMark('a', position{line: 3}).SetMark('b', position{line: 5})
+			b = b.SetMark('<', position{line: 0}).SetMark('>', position{line: 2, col: 3})
+			m.buffers[0] = b
+
+			r, rest, found, err := m.parseRange(tt.line)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("Expected error %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if r != tt.want || rest != tt.rest || found != tt.found {
+				t.Errorf("Expected %v %q %v, got %v %q %v", tt.want, tt.rest, tt.found, r, rest, found)
+			}
+		})
+	}
+}
+
+func TestRangeAlone(t *testing.T) {
+	m := initialModel()
+	m.buffers[0] = newBuffer(m.style, bufferWithContent("", "a\n  b\nc"))
+
+	m = runCommand(m, "2")
+	if got := cursorOf(m); got != (position{line: 1, col: 2}) {
+		t.Errorf("Expected :2 to jump to the second line, got %v", got)
+	}
+
+	m = runCommand(m, "$")
+	if got := cursorOf(m); got != (position{line: 2}) {
+		t.Errorf("Expected :$ to jump to the last line, got %v", got)
+	}
+}
+
+func TestNoRangeAllowed(t *testing.T) {
+	m := initialModel()
+	m.buffers[0] = newBuffer(m.style, bufferWithContent("", "a\nb"))
+
+	m = runCommand(m, "1,2noh")
+	if m.currentMessage == nil || m.currentMessage.text != "No range allowed" {
+		t.Errorf("Expected an error, got %v", m.currentMessage)
+	}
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// fakeLSPServer is a language server for the tests. It tells the editor
+// about every document notification it gets by sending it back with a
+// "fake/" prefix. Every "bad" word in a document is an error. The symbols
+// it knows are the functions declared in the files of the directory of the
+// documents.
+type fakeLSPServer struct {
+	out io.Writer
+	// docs are the texts of the open documents by their URI
+	docs map[string]string
+}
+
+func runFakeLSPServer(in io.Reader, out io.Writer) {
+	s := &fakeLSPServer{out: out, docs: make(map[string]string)}
+	r := bufio.NewReader(in)
+	for {
+		msg, err := readLSPMessage(r)
+		if err != nil {
+			return
+		}
+		s.handle(msg)
+	}
+}
+
+func (s *fakeLSPServer) send(msg lspMessage) {
+	msg.JSONRPC = "2.0"
+	body, _ := json.Marshal(msg)
+	fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+func (s *fakeLSPServer) notify(method string, params any) {
+	raw, _ := json.Marshal(params)
+	s.send(lspMessage{Method: method, Params: raw})
+}
+
+func (s *fakeLSPServer) reply(msg lspMessage, result any) {
+	raw, _ := json.Marshal(result)
+	s.send(lspMessage{ID: msg.ID, Result: raw})
+}
+
+func (s *fakeLSPServer) handle(msg lspMessage) {
+	switch msg.Method {
+	case "initialize":
+		s.reply(msg, map[string]any{"capabilities": map[string]any{
+			"textDocumentSync":   1,
+			"completionProvider": lspCompletionOptions{TriggerCharacters: []string{"."}},
+		}})
+	case "initialized":
+		id := json.RawMessage(`"config"`)
+		s.send(lspMessage{ID: &id, Method: "workspace/configuration", Params: json.RawMessage(`{"items":[{},{}]}`)})
+	case "":
+		if string(*msg.ID) == `"edit"` {
+			// The answer to workspace/applyEdit is sent back
+			s.send(lspMessage{Method: "fake/workspace/applyEdit", Params: msg.Result})
+			return
+		}
+		var items []any
+		json.Unmarshal(msg.Result, &items)
+		s.notify("window/showMessage", lspShowMessageParams{Type: 3, Message: fmt.Sprintf("configured %d", len(items))})
+	case "textDocument/didOpen", "textDocument/didChange", "textDocument/didSave", "textDocument/didClose":
+		s.send(lspMessage{Method: "fake/" + msg.Method, Params: msg.Params})
+		s.sync(msg)
+	case "textDocument/definition":
+		uri, word, _ := s.symbol(msg.Params)
+		s.reply(msg, s.find(uri, regexp.MustCompile(`func (`+word+`)\b`)))
+	case "textDocument/typeDefinition":
+		// Answered with location links
+		uri, word, _ := s.symbol(msg.Params)
+		var links []lspLocationLink
+		for _, loc := range s.find(uri, regexp.MustCompile(`func (`+word+`)\b`)) {
+			links = append(links, lspLocationLink{TargetURI: loc.URI, TargetSelectionRange: loc.Range})
+		}
+		s.reply(msg, links)
+	case "textDocument/references":
+		uri, word, _ := s.symbol(msg.Params)
+		s.reply(msg, s.find(uri, regexp.MustCompile(`\b(`+word+`)\b`)))
+	case "textDocument/implementation":
+		s.reply(msg, nil)
+	case "textDocument/hover":
+		_, word, _ := s.symbol(msg.Params)
+		s.reply(msg, map[string]any{"contents": lspMarkupContent{
+			Kind:  "markdown",
+			Value: fmt.Sprintf("```go\nfunc %s()\n```\nDocs of %s", word, word),
+		}})
+	case "textDocument/signatureHelp":
+		_, _, before := s.symbol(msg.Params)
+		if strings.Count(before, "(") <= strings.Count(before, ")") {
+			s.reply(msg, nil)
+			return
+		}
+		s.reply(msg, lspSignatureHelp{Signatures: []lspSignature{{Label: "helper(a int, b string)"}}})
+	case "textDocument/completion":
+		s.reply(msg, fakeCompletion(msg.Params))
+	case "textDocument/rename":
+		var params lspRenameParams
+		json.Unmarshal(msg.Params, &params)
+		uri, word, _ := s.symbol(msg.Params)
+		edit := lspWorkspaceEdit{Changes: make(map[string][]lspTextEdit)}
+		for _, loc := range s.find(uri, regexp.MustCompile(`\b(`+word+`)\b`)) {
+			edit.Changes[loc.URI] = append(edit.Changes[loc.URI], lspTextEdit{Range: loc.Range, NewText: params.NewName})
+		}
+		s.reply(msg, edit)
+	case "textDocument/codeAction":
+		s.reply(msg, fakeCodeActions(msg.Params))
+	case "workspace/executeCommand":
+		// fake.hi adds a comment to the top of the document
+		var params lspCommand
+		json.Unmarshal(msg.Params, &params)
+		var uri string
+		json.Unmarshal(params.Arguments[0], &uri)
+		edit := lspWorkspaceEdit{Changes: map[string][]lspTextEdit{uri: {{NewText: "// hi\n"}}}}
+		id := json.RawMessage(`"edit"`)
+		raw, _ := json.Marshal(lspApplyWorkspaceEditParams{Edit: edit})
+		s.send(lspMessage{ID: &id, Method: "workspace/applyEdit", Params: raw})
+		s.reply(msg, nil)
+	case "shutdown":
+		s.reply(msg, nil)
+	case "exit":
+		os.Exit(0)
+	}
+}
+
+// sync keeps the text of the documents and publishes their diagnostics.
+func (s *fakeLSPServer) sync(msg lspMessage) {
+	var doc struct {
+		TextDocument   lspTextDocumentItem `json:"textDocument"`
+		ContentChanges []lspContentChange  `json:"contentChanges"`
+	}
+	json.Unmarshal(msg.Params, &doc)
+	uri := doc.TextDocument.URI
+
+	switch msg.Method {
+	case "textDocument/didOpen":
+		s.docs[uri] = doc.TextDocument.Text
+	case "textDocument/didChange":
+		s.docs[uri] = doc.ContentChanges[0].Text
+	case "textDocument/didClose":
+		delete(s.docs, uri)
+		return
+	default:
+		return
+	}
+	s.notify("textDocument/publishDiagnostics", fakeDiagnostics(uri, s.docs[uri]))
+}
+
+// symbol returns the word at the position of the request and the text of
+// its line before the position.
+func (s *fakeLSPServer) symbol(raw json.RawMessage) (string, string, string) {
+	var params lspTextDocumentPositionParams
+	json.Unmarshal(raw, &params)
+	lines := strings.Split(s.docs[params.TextDocument.URI], "\n")
+	if params.Position.Line >= len(lines) {
+		return params.TextDocument.URI, "", ""
+	}
+
+	line := lines[params.Position.Line]
+	x := utf16ToByteOffset(line, params.Position.Character)
+	start, end := x, x
+	for start > 0 && fakeWordChar(line[start-1]) {
+		start--
+	}
+	for end < len(line) && fakeWordChar(line[end]) {
+		end++
+	}
+	return params.TextDocument.URI, line[start:end], line[:x]
+}
+
+func fakeWordChar(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+// find returns the first group of the matches in the files of the directory
+// of the document. Open documents are used instead of their files.
+func (s *fakeLSPServer) find(uri string, re *regexp.Regexp) []lspLocation {
+	names, _ := filepath.Glob(filepath.Join(filepath.Dir(uriToPath(uri)), "*.go"))
+	var locations []lspLocation
+	for _, name := range names {
+		text, ok := s.docs[fileURI(name)]
+		if !ok {
+			content, _ := os.ReadFile(name)
+			text = string(content)
+		}
+		for y, line := range strings.Split(text, "\n") {
+			for _, m := range re.FindAllStringSubmatchIndex(line, -1) {
+				locations = append(locations, lspLocation{URI: fileURI(name), Range: lspRange{
+					Start: lspPosition{Line: y, Character: byteOffsetToUTF16(line, m[2])},
+					End:   lspPosition{Line: y, Character: byteOffsetToUTF16(line, m[3])},
+				}})
+			}
+		}
+	}
+	return locations
+}
+
+func fakeDiagnostics(uri, text string) lspPublishDiagnosticsParams {
+	params := lspPublishDiagnosticsParams{URI: uri, Diagnostics: []lspDiagnostic{}}
+	for y, line := range strings.Split(text, "\n") {
+		if x := strings.Index(line, "bad"); x >= 0 {
+			params.Diagnostics = append(params.Diagnostics, lspDiagnostic{
+				Range:    lspRange{Start: lspPosition{Line: y, Character: x}, End: lspPosition{Line: y, Character: x + 3}},
+				Severity: 1,
+				Message:  "bad word",
+				Source:   "fake",
+			})
+		}
+	}
+	return params
+}
+
+// fakeCompletion completes the members of fmt after a dot, adding its
+// import, and a snippet and a variable otherwise.
+func fakeCompletion(raw json.RawMessage) lspCompletionList {
+	var params lspCompletionParams
+	json.Unmarshal(raw, &params)
+	if params.Context.TriggerCharacter == "." {
+		return lspCompletionList{Items: []lspCompletionItem{{
+			Label:               "Println",
+			Kind:                3,
+			AdditionalTextEdits: []lspTextEdit{{Range: lspRange{Start: lspPosition{Line: 2}, End: lspPosition{Line: 2}}, NewText: "import \"fmt\"\n\n"}},
+		}}}
+	}
+	return lspCompletionList{Items: []lspCompletionItem{
+		{
+			Label:            "helper",
+			Kind:             3,
+			Detail:           "func(a int, b string)",
+			Documentation:    json.RawMessage(`{"kind":"markdown","value":"Docs of helper"}`),
+			InsertText:       "helper(${1:a}, ${2:b})",
+			InsertTextFormat: insertTextFormatSnippet,
+		},
+		{Label: "hello", Kind: 6},
+	}}
+}
+
+// fakeCodeActions fixes the diagnostics by replacing the bad words with good
+// ones with versioned edits. The fake.hi command is always offered.
+func fakeCodeActions(raw json.RawMessage) []any {
+	var params lspCodeActionParams
+	json.Unmarshal(raw, &params)
+	uri := params.TextDocument.URI
+
+	var actions []any
+	if len(params.Context.Diagnostics) > 0 {
+		var edits []lspTextEdit
+		for _, d := range params.Context.Diagnostics {
+			edits = append(edits, lspTextEdit{Range: d.Range, NewText: "good"})
+		}
+		var change lspTextDocumentEdit
+		change.TextDocument.URI = uri
+		change.Edits = edits
+		doc, _ := json.Marshal(change)
+		actions = append(actions, lspCodeAction{
+			Title: "Fix the bad word",
+			Kind:  "quickfix",
+			Edit:  &lspWorkspaceEdit{DocumentChanges: []json.RawMessage{doc}},
+		})
+	}
+	arg, _ := json.Marshal(uri)
+	actions = append(actions,
+		lspCodeAction{Title: "Disabled", Disabled: &struct {
+			Reason string `json:"reason"`
+		}{Reason: "never"}},
+		lspCommand{Title: "Say hi", Command: "fake.hi", Arguments: []json.RawMessage{arg}},
+	)
+	return actions
+}
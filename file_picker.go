@@ -0,0 +1,374 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+)
+
+const (
+	// indexBatchSize is how many files the indexing sends at most at once
+	indexBatchSize = 512
+	// indexBatchDelay is how long the indexing waits before sending the
+	// files found so far
+	indexBatchDelay = 50 * time.Millisecond
+	// previewBytes is how much of the selected file is read for the preview
+	previewBytes = 32 * 1024
+)
+
+// filePicker is the fuzzy finder of the project files. The files are
+// indexed in the background and come in batches while the user types.
+type filePicker struct {
+	root     string
+	files    []string
+	query    string
+	matches  []fuzzyMatch
+	selected int
+
+	// batches delivers the indexed files, it's closed when the indexing
+	// finishes. cancel stops the indexing.
+	batches  <-chan []string
+	cancel   context.CancelFunc
+	indexing bool
+
+	// preview is the beginning of the selected file, highlighted by its
+	// buffer
+	preview     buffer
+	previewName string
+	previewNote string
+}
+
+// fileIndexMsg carries the files found by the indexing of the file picker.
+type fileIndexMsg struct {
+	batches <-chan []string
+	files   []string
+	done    bool
+}
+
+// OpenFilePicker starts indexing the files under the root and shows the
+// picker.
+func (m model) OpenFilePicker(root string) (model, tea.Cmd) {
+	if m.picker != nil {
+		m.picker.cancel()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	batches := make(chan []string)
+	go indexFiles(ctx, root, batches)
+
+	m.picker = &filePicker{root: root, batches: batches, cancel: cancel, indexing: true}
+	m.mode = ModePicker
+	return m, waitForFiles(batches)
+}
+
+// ClosePicker stops the indexing and goes back to normal mode.
+func (m model) ClosePicker() model {
+	if m.picker != nil {
+		m.picker.cancel()
+	}
+	m.picker = nil
+	m.mode = ModeNormal
+	return m
+}
+
+// indexFiles walks the project and sends the files in batches. A batch is
+// sent when it's full or when the walk is slow, so the first files show up
+// right away.
+func indexFiles(ctx context.Context, root string, batches chan<- []string) {
+	defer close(batches)
+
+	var batch []string
+	last := time.Now()
+	send := func() bool {
+		select {
+		case batches <- batch:
+			batch = nil
+			last = time.Now()
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	walkProject(ctx, root, func(name string) bool {
+		batch = append(batch, name)
+		if len(batch) >= indexBatchSize || time.Since(last) > indexBatchDelay {
+			return send()
+		}
+		return true
+	})
+	if len(batch) > 0 {
+		send()
+	}
+}
+
+// waitForFiles reads the next batch of the indexing.
+func waitForFiles(batches <-chan []string) tea.Cmd {
+	return func() tea.Msg {
+		files, ok := <-batches
+		return fileIndexMsg{batches: batches, files: files, done: !ok}
+	}
+}
+
+// updateFileIndex adds the indexed files to the picker. Messages of a
+// picker that was closed are dropped.
+func (m model) updateFileIndex(msg fileIndexMsg) (model, tea.Cmd) {
+	if m.picker == nil || m.picker.batches != msg.batches {
+		return m, nil
+	}
+
+	p := *m.picker
+	if msg.done {
+		p.indexing = false
+		m.picker = &p
+		return m, nil
+	}
+
+	p.files = append(p.files, msg.files...)
+	p.matches = slices.Concat(p.matches, filterFuzzy(p.query, msg.files))
+	slices.SortFunc(p.matches, compareFuzzyMatches)
+	m.picker = p.updatePreview(m.style)
+	return m, waitForFiles(msg.batches)
+}
+
+// filterFuzzy returns the texts matching the query.
+func filterFuzzy(query string, texts []string) []fuzzyMatch {
+	var matches []fuzzyMatch
+	for _, t := range texts {
+		if fm, ok := fuzzyScore(query, t); ok {
+			matches = append(matches, fm)
+		}
+	}
+	return matches
+}
+
+// setQuery filters the files again. When the query only got longer the
+// current matches are enough.
+func (p filePicker) setQuery(query string, style editorStyle) *filePicker {
+	texts := p.files
+	if query != "" && strings.HasPrefix(query, p.query) {
+		texts = make([]string, len(p.matches))
+		for i, fm := range p.matches {
+			texts[i] = fm.text
+		}
+	}
+
+	p.query = query
+	p.matches = filterFuzzy(query, texts)
+	slices.SortFunc(p.matches, compareFuzzyMatches)
+	p.selected = 0
+	return p.updatePreview(style)
+}
+
+func (m model) updatePicker(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if m.picker == nil {
+		return m.ClosePicker(), nil
+	}
+
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	p := *m.picker
+	switch key.String() {
+	case "esc", "ctrl+c":
+		return m.ClosePicker(), nil
+	case "enter":
+		if p.selected >= len(p.matches) {
+			return m, nil
+		}
+		name := filepath.Join(p.root, filepath.FromSlash(p.matches[p.selected].text))
+		m = m.ClosePicker()
+		var err error
+		m, err = m.openFile(name)
+		if err != nil {
+			return m.SetErrorMessage("Failed to open file: " + err.Error()), nil
+		}
+		return m, nil
+	case "down", "ctrl+n", "ctrl+j":
+		p.selected = min(p.selected+1, max(len(p.matches)-1, 0))
+		m.picker = p.updatePreview(m.style)
+	case "up", "ctrl+p", "ctrl+k":
+		p.selected = max(p.selected-1, 0)
+		m.picker = p.updatePreview(m.style)
+	case "backspace", "ctrl+h":
+		if p.query == "" {
+			return m, nil
+		}
+		_, size := utf8.DecodeLastRuneInString(p.query)
+		m.picker = p.setQuery(p.query[:len(p.query)-size], m.style)
+	case "ctrl+w":
+		m.picker = p.setQuery(p.query[:wordStartBefore(p.query, len(p.query))], m.style)
+	case "ctrl+u":
+		m.picker = p.setQuery("", m.style)
+	case " ":
+		m.picker = p.setQuery(p.query+" ", m.style)
+	default:
+		if key.Type == tea.KeyRunes && !key.Alt {
+			m.picker = p.setQuery(p.query+string(key.Runes), m.style)
+		}
+	}
+	return m, nil
+}
+
+// updatePreview loads the selected file when the selection changed.
+func (p filePicker) updatePreview(style editorStyle) *filePicker {
+	name := ""
+	if p.selected < len(p.matches) {
+		name = p.matches[p.selected].text
+	}
+	if name == p.previewName {
+		return &p
+	}
+
+	p.previewName = name
+	p.previewNote = ""
+	p.preview = newBuffer(style)
+	if name == "" {
+		return &p
+	}
+
+	content, err := readFileHead(filepath.Join(p.root, filepath.FromSlash(name)), previewBytes)
+	switch {
+	case err != nil:
+		p.previewNote = "Cannot read the file: " + err.Error()
+	case isBinary(content):
+		p.previewNote = "Binary file"
+	default:
+		p.preview = newBuffer(style, bufferWithContent(name, string(content)))
+	}
+	return &p
+}
+
+// readFileHead reads up to n bytes from the beginning of the file.
+func readFileHead(name string, n int64) ([]byte, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(io.LimitReader(f, n))
+}
+
+// View renders the prompt, the matching files and the preview of the
+// selected one.
+func (p filePicker) View(style editorStyle, width, height int) string {
+	var b strings.Builder
+
+	status := fmt.Sprintf("%d/%d", len(p.matches), len(p.files))
+	if p.indexing {
+		status += " indexing…"
+	}
+	prompt := "> " + p.query
+	pad := max(width-runewidth.StringWidth(prompt)-runewidth.StringWidth(status), 1)
+	b.WriteString(style.listTitle.Render(truncateWidth(prompt+strings.Repeat(" ", pad)+status, width)))
+	b.WriteRune('\n')
+
+	listHeight := max((height-2)/2, 1)
+
+	// Keep the selected file visible
+	start := 0
+	if p.selected >= listHeight {
+		start = p.selected - listHeight + 1
+	}
+	end := min(start+listHeight, len(p.matches))
+
+	for i := start; i < end; i++ {
+		label := p.matchView(p.matches[i], style, width-2, i == p.selected)
+		if i == p.selected {
+			b.WriteString(style.selection.Render("> ") + label)
+		} else {
+			b.WriteString("  " + label)
+		}
+		b.WriteRune('\n')
+	}
+	for i := end - start; i < listHeight; i++ {
+		b.WriteRune('\n')
+	}
+
+	b.WriteString(style.comment.Render(strings.Repeat("─", max(0, width))))
+	b.WriteRune('\n')
+
+	if p.previewNote != "" {
+		b.WriteString(style.comment.Render(truncateWidth(p.previewNote, width)))
+		b.WriteRune('\n')
+		return b.String()
+	}
+	if p.previewName == "" {
+		return b.String()
+	}
+	for y := 0; y < min(height-listHeight-2, p.preview.NoOfLines()); y++ {
+		line := truncateWidth(expandTabs(p.preview.Line(y)), width)
+		for _, chunk := range p.preview.HighlightString(line) {
+			b.WriteString(chunk.Style.Render(chunk.Content))
+		}
+		b.WriteRune('\n')
+	}
+
+	return b.String()
+}
+
+// matchView renders the file name with the matched characters highlighted.
+func (p filePicker) matchView(fm fuzzyMatch, style editorStyle, width int, selected bool) string {
+	text := style.text
+	if selected {
+		text = style.selection
+	}
+
+	label, used := fuzzyMatchView(fm, text, style.fuzzyMatch.Inherit(text), width)
+	if selected {
+		label += text.Render(strings.Repeat(" ", max(width-used, 0)))
+	}
+	return label
+}
+
+// fuzzyMatchView renders at most width columns of the text with the matched
+// characters highlighted. It returns the width used.
+func fuzzyMatchView(fm fuzzyMatch, text, matched lipgloss.Style, width int) (string, int) {
+	// Runs of matched and not matched characters are rendered together
+	var b, run strings.Builder
+	runMatched := false
+	flush := func() {
+		if runMatched {
+			b.WriteString(matched.Render(run.String()))
+		} else {
+			b.WriteString(text.Render(run.String()))
+		}
+		run.Reset()
+	}
+
+	used := 0
+	next := 0
+	for i, r := range []rune(fm.text) {
+		w := runewidth.RuneWidth(r)
+		if used+w > width {
+			break
+		}
+		used += w
+
+		isMatched := next < len(fm.positions) && fm.positions[next] == i
+		if isMatched {
+			next++
+		}
+		if isMatched != runMatched && run.Len() > 0 {
+			flush()
+		}
+		runMatched = isMatched
+		run.WriteRune(r)
+	}
+	if run.Len() > 0 {
+		flush()
+	}
+	return b.String(), used
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// runCmds feeds the messages of the commands back into the model until no
+// command is left.
+func runCmds(m model, cmd tea.Cmd) model {
+	for cmd != nil {
+		var res tea.Model
+		res, cmd = m.Update(cmd())
+		m = res.(model)
+	}
+	return m
+}
+
+func pickerTestModel(t *testing.T) (model, string) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".gitignore":         "*.log\n",
+		"main.go":            "package main\n\nfunc main() {}\n",
+		"model.go":           "package main\n",
+		"debug.log":          "",
+		"docs/mode_list.txt": "modes",
+		"image.bin":          "\x00\x01",
+	})
+
+	m := initialModel()
+	m.viewport = tea.WindowSizeMsg{Width: 80, Height: 24}
+	m, cmd := m.OpenFilePicker(dir)
+	return runCmds(m, cmd), dir
+}
+
+func TestFilePicker(t *testing.T) {
+	m, dir := pickerTestModel(t)
+	if m.mode != ModePicker || m.picker.indexing || len(m.picker.files) != 5 {
+		t.Fatalf("Expected the files to be indexed, got %q", m.picker.files)
+	}
+
+	m = pressKeys(m, runeKeys("mod")...)
+	if len(m.picker.matches) != 2 || m.picker.matches[0].text != "model.go" {
+		t.Errorf("Expected model.go to be the best match, got %v", m.picker.matches)
+	}
+	if !strings.Contains(m.View(), "docs/mode") {
+		t.Errorf("Expected the matches to be shown")
+	}
+
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyBackspace}, tea.KeyMsg{Type: tea.KeyBackspace})
+	if len(m.picker.matches) != 4 {
+		t.Errorf("Expected the matches of a shorter query, got %v", m.picker.matches)
+	}
+
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyCtrlU})
+	m = pressKeys(m, runeKeys("main")...)
+	if !strings.Contains(m.View(), "func main() {}") {
+		t.Errorf("Expected the preview of main.go, got\n%s", m.View())
+	}
+
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.mode != ModeNormal || m.CurrentBuffer().filename != filepath.Join(dir, "main.go") {
+		t.Errorf("Expected main.go to be opened, got %q", m.CurrentBuffer().filename)
+	}
+}
+
+func TestFilePickerBinaryPreview(t *testing.T) {
+	m, _ := pickerTestModel(t)
+	m = pressKeys(m, runeKeys("image")...)
+	if !strings.Contains(m.View(), "Binary file") {
+		t.Errorf("Expected no preview of a binary file")
+	}
+}
+
+func TestFilePickerCancel(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a": "", "b": ""})
+
+	t.Chdir(dir)
+
+	res, cmd := initialModel().Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+	res, cmd = res.(model).Update(runeKeys("f")[0])
+	m := res.(model)
+	if m.mode != ModePicker || m.picker.root != "." {
+		t.Fatalf("Expected space-f to open the picker, got %s", m.mode)
+	}
+
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEsc})
+	if m.mode != ModeNormal || m.picker != nil {
+		t.Fatalf("Expected esc to close the picker")
+	}
+
+	// The indexing stops, its messages are dropped
+	m = runCmds(m, cmd)
+	if m.mode != ModeNormal {
+		t.Errorf("Expected the closed picker to stay closed, got %s", m.mode)
+	}
+}
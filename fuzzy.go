@@ -0,0 +1,122 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// fuzzyMatch is a text matching a fuzzy pattern.
+type fuzzyMatch struct {
+	text  string
+	score int
+	// positions are the indexes of the matched runes in the text
+	positions []int
+}
+
+const (
+	fuzzyScoreMatch       = 16
+	fuzzyBonusConsecutive = 8
+	fuzzyBonusBoundary    = 10
+	fuzzyBonusBaseName    = 2
+	fuzzyPenaltyGapStart  = 3
+	fuzzyPenaltyGapExtend = 1
+)
+
+// fuzzyScore matches the pattern as a subsequence of the text. Characters at
+// the start of words and right after each other score more, gaps between
+// them score less. The match is case insensitive unless the pattern has an
+// upper case letter.
+func fuzzyScore(pattern, text string) (fuzzyMatch, bool) {
+	p := []rune(pattern)
+	t := []rune(text)
+	caseSensitive := strings.IndexFunc(pattern, unicode.IsUpper) >= 0
+	eq := func(a, b rune) bool {
+		if caseSensitive {
+			return a == b
+		}
+		return unicode.ToLower(a) == unicode.ToLower(b)
+	}
+
+	if len(p) == 0 {
+		return fuzzyMatch{text: text}, true
+	}
+
+	// The first occurrence of the whole pattern ends the match
+	end, pi := -1, 0
+	for i, r := range t {
+		if eq(r, p[pi]) {
+			pi++
+			if pi == len(p) {
+				end = i
+				break
+			}
+		}
+	}
+	if end < 0 {
+		return fuzzyMatch{}, false
+	}
+
+	// Going back from the end finds the shortest match
+	positions := make([]int, len(p))
+	pi = len(p) - 1
+	for i := end; i >= 0 && pi >= 0; i-- {
+		if eq(t[i], p[pi]) {
+			positions[pi] = i
+			pi--
+		}
+	}
+
+	// Matches in the file name count more than in the directories
+	baseName := -1
+	for i, r := range t {
+		if r == '/' {
+			baseName = i
+		}
+	}
+
+	score := 0
+	for i, pos := range positions {
+		score += fuzzyScoreMatch
+		if isWordStart(t, pos) {
+			score += fuzzyBonusBoundary
+		}
+		if pos > baseName {
+			score += fuzzyBonusBaseName
+		}
+		if i == 0 {
+			continue
+		}
+		if gap := pos - positions[i-1] - 1; gap == 0 {
+			score += fuzzyBonusConsecutive
+		} else {
+			score -= fuzzyPenaltyGapStart + (gap-1)*fuzzyPenaltyGapExtend
+		}
+	}
+
+	return fuzzyMatch{text: text, score: score, positions: positions}, true
+}
+
+// isWordStart tells if the rune starts a word, a path element or a part of
+// a camel case name.
+func isWordStart(t []rune, i int) bool {
+	if i == 0 {
+		return true
+	}
+	prev := t[i-1]
+	switch prev {
+	case '/', '_', '-', '.', ' ':
+		return true
+	}
+	return unicode.IsLower(prev) && unicode.IsUpper(t[i])
+}
+
+// compareFuzzyMatches orders better matches first. Shorter texts win ties.
+func compareFuzzyMatches(a, b fuzzyMatch) int {
+	if a.score != b.score {
+		return b.score - a.score
+	}
+	if len(a.text) != len(b.text) {
+		return len(a.text) - len(b.text)
+	}
+	return strings.Compare(a.text, b.text)
+}
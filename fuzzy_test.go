@@ -0,0 +1,60 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	tests := []struct {
+		pattern   string
+		text      string
+		ok        bool
+		positions []int
+	}{
+		{"", "main.go", true, nil},
+		{"mgo", "main.go", true, []int{0, 5, 6}},
+		{"MG", "main.go", false, nil},
+		{"mdl", "model.go", true, []int{0, 2, 4}},
+		{"xyz", "model.go", false, nil},
+		// The shortest match ending at the first full occurrence
+		{"ab", "a_a_b", true, []int{2, 4}},
+		{"żó", "zażółć.txt", true, []int{2, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.text, func(t *testing.T) {
+			got, ok := fuzzyScore(tt.pattern, tt.text)
+			if ok != tt.ok {
+				t.Fatalf("Expected ok to be %v", tt.ok)
+			}
+			if ok && !slices.Equal(got.positions, tt.positions) {
+				t.Errorf("Expected positions %v, got %v", tt.positions, got.positions)
+			}
+		})
+	}
+}
+
+func TestFuzzyRanking(t *testing.T) {
+	tests := []struct {
+		pattern string
+		better  string
+		worse   string
+	}{
+		{"fp", "file_picker.go", "fooprint.go"},
+		{"model", "model.go", "mode_list.go"},
+		{"bt", "BufferTest.go", "abstract.go"},
+		{"main", "cmd/main.go", "main/cmd.go"},
+		{"go", "a.go", "abc.go"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			better, _ := fuzzyScore(tt.pattern, tt.better)
+			worse, ok := fuzzyScore(tt.pattern, tt.worse)
+			if ok && compareFuzzyMatches(better, worse) >= 0 {
+				t.Errorf("Expected %s (%d) to rank above %s (%d)", tt.better, better.score, tt.worse, worse.score)
+			}
+		})
+	}
+}
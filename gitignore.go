@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// ignoreRule is a single pattern from a .gitignore file.
+type ignoreRule struct {
+	re       *regexp.Regexp
+	negate   bool
+	onlyDirs bool
+}
+
+// ignoreRules are the rules of a .gitignore file. Paths are matched relative
+// to the directory of the file.
+type ignoreRules struct {
+	dir   string
+	rules []ignoreRule
+}
+
+// parseIgnoreRules reads the patterns of a .gitignore file found in dir,
+// which is relative to the walked root and uses slashes.
+func parseIgnoreRules(dir string, r io.Reader) ignoreRules {
+	rules := ignoreRules{dir: dir}
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimRight(s.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var rule ignoreRule
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\`) {
+			// \# and \! stand for the characters themselves
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.onlyDirs = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		// A pattern with a slash is relative to the directory of the
+		// .gitignore file, otherwise it matches at any depth
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+
+		expr := globToRegexp(line)
+		if !anchored {
+			expr = "(?:.*/)?" + expr
+		}
+		re, err := regexp.Compile("^" + expr + "$")
+		if err != nil {
+			continue
+		}
+		rule.re = re
+		rules.rules = append(rules.rules, rule)
+	}
+	return rules
+}
+
+// globToRegexp translates a gitignore glob. * and ? don't match a slash, **
+// matches across directories.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			b.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	return b.String()
+}
+
+// match tells if the rules ignore the path, which is relative to the walked
+// root. matched is false when no rule applies to the path.
+func (r ignoreRules) match(name string, isDir bool) (ignored, matched bool) {
+	if r.dir != "" {
+		if !strings.HasPrefix(name, r.dir+"/") {
+			return false, false
+		}
+		name = name[len(r.dir)+1:]
+	}
+
+	// The last matching rule wins
+	for i := len(r.rules) - 1; i >= 0; i-- {
+		rule := r.rules[i]
+		if rule.onlyDirs && !isDir {
+			continue
+		}
+		if rule.re.MatchString(name) {
+			return !rule.negate, true
+		}
+	}
+	return false, false
+}
+
+// isIgnored checks the path against the .gitignore files of its directory
+// and the ones above it. The deeper files take precedence.
+func isIgnored(stack []ignoreRules, name string, isDir bool) bool {
+	if path.Base(name) == ".git" {
+		return true
+	}
+
+	for i := len(stack) - 1; i >= 0; i-- {
+		if ignored, matched := stack[i].match(name, isDir); matched {
+			return ignored
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestIgnoreRules(t *testing.T) {
+	rules := parseIgnoreRules("", strings.NewReader(`
+# comment
+*.log
+!keep.log
+build/
+/root.txt
+docs/*.md
+**/tmp
+a/**/z
+\#hash
+`))
+
+	tests := []struct {
+		name    string
+		isDir   bool
+		ignored bool
+	}{
+		{"app.log", false, true},
+		{"src/app.log", false, true},
+		{"src/keep.log", false, false},
+		{"build", true, true},
+		{"build", false, false},
+		{"src/build", true, true},
+		{"root.txt", false, true},
+		{"src/root.txt", false, false},
+		{"docs/a.md", false, true},
+		{"docs/sub/a.md", false, false},
+		{"x/y/tmp", true, true},
+		{"a/z", false, true},
+		{"a/b/c/z", false, true},
+		{"#hash", false, true},
+		{"main.go", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isIgnored([]ignoreRules{rules}, tt.name, tt.isDir); got != tt.ignored {
+				t.Errorf("Expected ignored to be %v", tt.ignored)
+			}
+		})
+	}
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestWalkProject(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".gitignore":         "*.tmp\nvendor/\n",
+		"main.go":            "",
+		"a.tmp":              "",
+		"vendor/lib.go":      "",
+		".git/config":        "",
+		"sub/.gitignore":     "!keep.tmp\nlocal.go\n",
+		"sub/keep.tmp":       "",
+		"sub/drop.tmp":       "",
+		"sub/local.go":       "",
+		"sub/deep/local.go":  "",
+		"sub/deep/nested.go": "",
+		"other/local.go":     "",
+	})
+
+	var got []string
+	err := walkProject(context.Background(), dir, func(name string) bool {
+		got = append(got, name)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{".gitignore", "main.go", "other/local.go", "sub/.gitignore", "sub/deep/nested.go", "sub/keep.tmp"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestWalkProjectCancel(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a": "", "b": "", "c": ""})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var got []string
+	walkProject(ctx, dir, func(name string) bool {
+		got = append(got, name)
+		cancel()
+		return true
+	})
+	if len(got) != 1 {
+		t.Errorf("Expected the walk to stop after cancelling, got %q", got)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// grepBatchSize is how many results the search sends at most at once
+	grepBatchSize = 256
+	// grepMaxFileSize is the size of the biggest file searched
+	grepMaxFileSize = 16 * 1024 * 1024
+	// grepContextLines is how many lines before the match the preview shows
+	grepContextLines = 5
+	// grepMaxResults is how many results :grep keeps before it stops
+	grepMaxResults = 10000
+)
+
+// grepResult is a line matching the searched pattern.
+type grepResult struct {
+	file      string
+	line, col int
+	text      string
+	// preview holds the lines around the match
+	preview []string
+}
+
+// grepProject searches the files under the root, or the root itself when
+// it's a file. The files are read by a worker per CPU and the results are
+// sent in batches. The batches channel is closed when the search finishes or
+// the context is cancelled.
+func grepProject(ctx context.Context, root string, re *regexp.Regexp, batches chan<- []grepResult) {
+	defer close(batches)
+
+	files := make(chan string)
+	found := make(chan []grepResult)
+
+	go func() {
+		defer close(files)
+		send := func(name string) bool {
+			select {
+			case files <- name:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		if info, err := os.Stat(root); err == nil && !info.IsDir() {
+			send(root)
+			return
+		}
+		walkProject(ctx, root, func(name string) bool {
+			return send(filepath.Join(root, filepath.FromSlash(name)))
+		})
+	}()
+
+	var wg sync.WaitGroup
+	for range runtime.NumCPU() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range files {
+				results := grepFile(name, re)
+				if len(results) == 0 {
+					continue
+				}
+				select {
+				case found <- results:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(found)
+	}()
+
+	var batch []grepResult
+	last := time.Now()
+	send := func() bool {
+		select {
+		case batches <- batch:
+			batch = nil
+			last = time.Now()
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for results := range found {
+		batch = append(batch, results...)
+		if len(batch) >= grepBatchSize || time.Since(last) > indexBatchDelay {
+			if !send() {
+				return
+			}
+		}
+	}
+	if len(batch) > 0 {
+		send()
+	}
+}
+
+// grepFile returns the first match on every line of the file. Binary files,
+// the ones too big and the ones that can't be read are skipped. The results
+// copy their lines so they don't keep the whole file in memory.
+func grepFile(name string, re *regexp.Regexp) []grepResult {
+	info, err := os.Stat(name)
+	if err != nil || info.Size() > grepMaxFileSize {
+		return nil
+	}
+	content, err := os.ReadFile(name)
+	if err != nil || isBinary(content) {
+		return nil
+	}
+
+	var results []grepResult
+	lines := strings.Split(string(content), "\n")
+	for y, line := range lines {
+		lines[y] = strings.TrimSuffix(line, "\r")
+	}
+	for y, line := range lines {
+		loc := re.FindStringIndex(line)
+		if loc == nil {
+			continue
+		}
+
+		first := max(y-grepContextLines, 0)
+		last := min(first+previewLines, len(lines))
+		results = append(results, grepResult{
+			file:    name,
+			line:    y,
+			col:     loc[0],
+			text:    strings.Clone(line),
+			preview: slices.Clone(lines[first:last]),
+		})
+	}
+	return results
+}
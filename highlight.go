@@ -0,0 +1,84 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+)
+
+// highlight marks a part of the buffer that is drawn with a different style
+// on top of the syntax highlighting, e.g. the visual selection.
+type highlight struct {
+	textRange
+	style lipgloss.Style
+}
+
+// span is a highlighted range of visual columns on a rendered line.
+type span struct {
+	start, end int
+	style      lipgloss.Style
+}
+
+// lineSpans converts highlights covering the line y to visual columns
+// relative to the horizontal scroll offset.
+func (b buffer) lineSpans(y int, line string) []span {
+	var spans []span
+	for _, h := range b.highlights {
+		start, end, ok := h.columns(y, line)
+		if !ok {
+			continue
+		}
+		start, end = clampColumns(line, start, end)
+		if start == end {
+			continue
+		}
+		spans = append(spans, span{
+			start: visualCursorX(line, start) - b.cursorXOffset,
+			end:   visualCursorX(line, end) - b.cursorXOffset,
+			style: h.style,
+		})
+	}
+	return spans
+}
+
+// applySpans splits the chunks on span boundaries and draws the spans on
+// top of the chunk styles. Later spans win over earlier ones.
+func applySpans(chunks []StyledChunk, spans []span) []StyledChunk {
+	if len(spans) == 0 {
+		return chunks
+	}
+
+	var result []StyledChunk
+	col := 0
+	for _, chunk := range chunks {
+		var current strings.Builder
+		currentSpan := -2
+		for _, r := range chunk.Content {
+			s := -1
+			for i, sp := range spans {
+				if col >= sp.start && col < sp.end {
+					s = i
+				}
+			}
+			if s != currentSpan && current.Len() > 0 {
+				result = append(result, spanChunk(current.String(), chunk.Style, spans, currentSpan))
+				current.Reset()
+			}
+			currentSpan = s
+			current.WriteRune(r)
+			col += runewidth.RuneWidth(r)
+		}
+		if current.Len() > 0 {
+			result = append(result, spanChunk(current.String(), chunk.Style, spans, currentSpan))
+		}
+	}
+	return result
+}
+
+func spanChunk(content string, style lipgloss.Style, spans []span, i int) StyledChunk {
+	if i >= 0 {
+		style = spans[i].style.Inherit(style)
+	}
+	return StyledChunk{Content: content, Style: style}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mattn/go-runewidth"
+)
+
+type listItem struct {
+	label   string
+	preview []string
+}
+
+// listPopup is a navigable list rendered in place of the buffer area. It is
+// used by commands that need the user to pick one of many entries.
+type listPopup struct {
+	title    string
+	items    []listItem
+	selected int
+	onSelect func(m model, i int) (model, tea.Cmd)
+}
+
+// OpenList shows the list and switches to list mode. onSelect is called with
+// the index of the chosen item after the list is closed.
+func (m model) OpenList(title string, items []listItem, selected int, onSelect func(m model, i int) (model, tea.Cmd)) model {
+	// A new list replaces the results of a running :grep
+	m = m.stopGrep()
+	m.list = &listPopup{
+		title:    title,
+		items:    items,
+		selected: max(0, min(selected, len(items)-1)),
+		onSelect: onSelect,
+	}
+	m.mode = ModeList
+	m.commandBuffer = ""
+	return m
+}
+
+func (m model) CloseList() model {
+	m.list = nil
+	m.mode = ModeNormal
+	return m
+}
+
+func (m model) updateList(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if m.list == nil {
+		return m.CloseList(), nil
+	}
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" && m.grep != nil && m.grep.running {
+			return m.stopGrep(), nil
+		}
+
+		l := *m.list
+		switch msg.String() {
+		case "esc", "q", "ctrl+c":
+			return m.CloseList().stopGrep(), nil
+		case "j", "down", "ctrl+n":
+			if l.selected < len(l.items)-1 {
+				l.selected++
+			}
+		case "k", "up", "ctrl+p":
+			if l.selected > 0 {
+				l.selected--
+			}
+		case "g", "home":
+			l.selected = 0
+		case "G", "end":
+			l.selected = max(0, len(l.items)-1)
+		case "enter":
+			m = m.CloseList().stopGrep()
+			if l.onSelect == nil || len(l.items) == 0 {
+				return m, nil
+			}
+			return l.onSelect(m, l.selected)
+		}
+		m.list = &l
+	}
+
+	return m, nil
+}
+
+// View renders the list in the top part of the given area and the preview
+// of the selected item below it.
+func (l listPopup) View(style editorStyle, width, height int) string {
+	var b strings.Builder
+
+	listHeight := height - 1
+	var preview []string
+	if l.selected < len(l.items) {
+		preview = l.items[l.selected].preview
+	}
+	if len(preview) > 0 {
+		listHeight = (height - 1) / 2
+	}
+
+	b.WriteString(style.listTitle.Render(truncateWidth(l.title, width)))
+	b.WriteRune('\n')
+
+	// Keep the selected item visible
+	start := 0
+	if l.selected >= listHeight {
+		start = l.selected - listHeight + 1
+	}
+	end := min(start+listHeight, len(l.items))
+
+	for i := start; i < end; i++ {
+		label := truncateWidth(l.items[i].label, width-2)
+		if i == l.selected {
+			label = style.selection.Render("> " + label + strings.Repeat(" ", max(0, width-2-runewidth.StringWidth(label))))
+		} else {
+			label = "  " + label
+		}
+		b.WriteString(label)
+		b.WriteRune('\n')
+	}
+	for i := end - start; i < listHeight; i++ {
+		b.WriteRune('\n')
+	}
+
+	if len(preview) > 0 {
+		b.WriteString(style.comment.Render(strings.Repeat("─", max(0, width))))
+		b.WriteRune('\n')
+		for _, line := range preview {
+			b.WriteString(truncateWidth(expandTabs(line), width))
+			b.WriteRune('\n')
+		}
+	}
+
+	return b.String()
+}
+
+func truncateWidth(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	return runewidth.Truncate(s, width, "")
+}
@@ -0,0 +1,437 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	// lspInitializeTimeout is how long a server gets to answer the handshake
+	lspInitializeTimeout = 10 * time.Second
+	// lspRequestTimeout is how long a server gets to answer a request
+	lspRequestTimeout = 30 * time.Second
+)
+
+var errNoLanguageServer = errors.New("No language server for this file")
+
+// lspRootMarkers are the files found at the root of a project. Every
+// project gets its own server.
+var lspRootMarkers = []string{
+	".git", "go.mod", "package.json", "Cargo.toml", "pyproject.toml", "setup.py", "compile_commands.json",
+}
+
+// lspManager runs the language servers and keeps them in sync with the
+// buffers. Talking to the servers happens on a worker goroutine, one job
+// after another, so the editor never waits for them. What the servers send
+// comes back to the editor as messages.
+type lspManager struct {
+	// docs are the files the servers were told about, keyed by URI. They
+	// are only used by the editor's goroutine.
+	docs map[string]lspDocument
+
+	mu   sync.Mutex
+	jobs []func()
+	wake chan struct{}
+
+	// clients are the running servers, keyed by lspServerKey. failed are
+	// the ones that couldn't start, they aren't tried again. Both are only
+	// used by the worker.
+	clients map[string]*lspClient
+	failed  map[string]bool
+	// capabilities of the servers that started, guarded by mu
+	capabilities map[string]lspServerCapabilities
+
+	events  chan tea.Msg
+	closed  chan struct{}
+	stopped chan struct{}
+}
+
+// lspDocument is the last state of a buffer sent to its server.
+type lspDocument struct {
+	server  string
+	lines   rope
+	version int
+	writes  int
+}
+
+func newLSPManager() *lspManager {
+	l := &lspManager{
+		docs:    make(map[string]lspDocument),
+		wake:    make(chan struct{}, 1),
+		clients: make(map[string]*lspClient),
+		failed:  make(map[string]bool),
+		events:  make(chan tea.Msg, 64),
+		closed:  make(chan struct{}),
+		stopped: make(chan struct{}),
+
+		capabilities: make(map[string]lspServerCapabilities),
+	}
+	go l.work()
+	return l
+}
+
+// WithLSP makes the editor use the language servers of the Languages.
+func WithLSP(l *lspManager) modelOption {
+	return func(m *model) {
+		m.lsp = l
+	}
+}
+
+// enqueue adds a job for the worker.
+func (l *lspManager) enqueue(job func()) {
+	l.mu.Lock()
+	l.jobs = append(l.jobs, job)
+	l.mu.Unlock()
+
+	select {
+	case l.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (l *lspManager) work() {
+	defer close(l.stopped)
+
+	for {
+		l.mu.Lock()
+		jobs := l.jobs
+		l.jobs = nil
+		l.mu.Unlock()
+
+		for _, job := range jobs {
+			job()
+		}
+
+		select {
+		case <-l.wake:
+		case <-l.closed:
+			return
+		}
+	}
+}
+
+// send delivers a message to the editor, unless it's shutting down.
+func (l *lspManager) send(msg tea.Msg) {
+	select {
+	case l.events <- msg:
+	case <-l.closed:
+	}
+}
+
+// listen waits for the next message of the servers.
+func (l *lspManager) listen() tea.Cmd {
+	if l == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		select {
+		case msg := <-l.events:
+			return msg
+		case <-l.closed:
+			return nil
+		}
+	}
+}
+
+// lspServer is the language server for the file, if there's one installed.
+func (m model) lspServer(filename string) (toolInfo, bool) {
+	support, ok := m.Languages[strings.TrimPrefix(filepath.Ext(filename), ".")]
+	if !ok || support.LSPServer.Name == "" || !support.LSPServer.IsInstalled {
+		return toolInfo{}, false
+	}
+	return support.LSPServer, true
+}
+
+// lspRoot is the root of the project holding the file. Without any of the
+// lspRootMarkers it's the directory of the file.
+func lspRoot(filename string) string {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return filepath.Dir(filename)
+	}
+
+	for dir := filepath.Dir(abs); ; {
+		for _, marker := range lspRootMarkers {
+			if _, err := os.Stat(filepath.Join(dir, marker)); err == nil {
+				return dir
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return filepath.Dir(abs)
+		}
+		dir = parent
+	}
+}
+
+func lspServerKey(server toolInfo, root string) string {
+	return server.Name + "\x00" + root
+}
+
+// sync tells the servers about the buffers opened, changed, written and
+// closed since the last time.
+func (l *lspManager) sync(m model) {
+	if l == nil {
+		return
+	}
+
+	open := make(map[string]bool)
+	for _, b := range m.buffers {
+		if b.filename == "" {
+			continue
+		}
+		server, ok := m.lspServer(b.filename)
+		if !ok {
+			continue
+		}
+
+		uri := fileURI(b.filename)
+		open[uri] = true
+		doc, known := l.docs[uri]
+		switch {
+		case !known:
+			root := lspRoot(b.filename)
+			doc = lspDocument{server: lspServerKey(server, root), lines: b.lines, version: 1, writes: b.writes}
+			l.didOpen(server, root, lspTextDocumentItem{
+				URI:        uri,
+				LanguageID: lspLanguageID(b.filename),
+				Version:    doc.version,
+				Text:       b.Content(),
+			})
+		case !doc.lines.Equal(b.lines):
+			doc.lines = b.lines
+			doc.version++
+			l.notify(doc.server, "textDocument/didChange", lspDidChangeParams{
+				TextDocument:   lspVersionedTextDocumentIdentifier{URI: uri, Version: doc.version},
+				ContentChanges: []lspContentChange{{Text: b.Content()}},
+			})
+		}
+		if doc.writes != b.writes {
+			doc.writes = b.writes
+			l.notify(doc.server, "textDocument/didSave", lspDidSaveParams{
+				TextDocument: lspTextDocumentIdentifier{URI: uri},
+			})
+		}
+		l.docs[uri] = doc
+	}
+
+	for uri, doc := range l.docs {
+		if open[uri] {
+			continue
+		}
+		delete(l.docs, uri)
+		l.notify(doc.server, "textDocument/didClose", lspDidCloseParams{
+			TextDocument: lspTextDocumentIdentifier{URI: uri},
+		})
+	}
+}
+
+// didOpen starts the server of the project if it isn't running yet and
+// opens the document.
+func (l *lspManager) didOpen(server toolInfo, root string, item lspTextDocumentItem) {
+	key := lspServerKey(server, root)
+	l.enqueue(func() {
+		if _, ok := l.clients[key]; !ok && !l.failed[key] {
+			l.start(key, server, root)
+		}
+	})
+	l.notify(key, "textDocument/didOpen", lspDidOpenParams{TextDocument: item})
+}
+
+// start runs the server and does the handshake. A server which fails is
+// reported and not tried again.
+func (l *lspManager) start(key string, server toolInfo, root string) {
+	c, err := startLSPClient(server.Name, server.Args, root, func(id *json.RawMessage, method string, params json.RawMessage) {
+		l.send(lspServerMsg{server: server.Name, key: key, id: id, method: method, params: params})
+	})
+	var capabilities lspServerCapabilities
+	if err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), lspInitializeTimeout)
+		capabilities, err = c.initialize(ctx)
+		cancel()
+		if err != nil {
+			c.cmd.Process.Kill()
+			c.cmd.Wait()
+		}
+	}
+	if err != nil {
+		l.failed[key] = true
+		l.send(lspErrorMsg{server: server.Name, err: err})
+		return
+	}
+	l.clients[key] = c
+	l.mu.Lock()
+	l.capabilities[key] = capabilities
+	l.mu.Unlock()
+}
+
+// completionTriggers are the characters starting a completion in the file.
+// They are known once its server started.
+func (l *lspManager) completionTriggers(filename string) []string {
+	if l == nil {
+		return nil
+	}
+	doc, ok := l.docs[fileURI(filename)]
+	if !ok {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if p := l.capabilities[doc.server].CompletionProvider; p != nil {
+		return p.TriggerCharacters
+	}
+	return nil
+}
+
+// version is the version of the file the servers were told about last.
+func (l *lspManager) version(filename string) (int, bool) {
+	if l == nil {
+		return 0, false
+	}
+	doc, ok := l.docs[fileURI(filename)]
+	return doc.version, ok
+}
+
+// notify sends a notification to the server. A server which stopped is
+// reported once.
+func (l *lspManager) notify(key, method string, params any) {
+	l.enqueue(func() {
+		c, ok := l.clients[key]
+		if !ok {
+			return
+		}
+		if err := c.notification(method, params); err != nil {
+			delete(l.clients, key)
+			l.failed[key] = true
+			l.send(lspErrorMsg{server: c.name, err: err})
+		}
+	})
+}
+
+// reply answers a request of the server.
+func (l *lspManager) reply(key string, id *json.RawMessage, result any) {
+	if l == nil {
+		return
+	}
+	l.enqueue(func() {
+		if c, ok := l.clients[key]; ok {
+			c.reply(id, result)
+		}
+	})
+}
+
+// lspResponseMsg is the answer of a server to a request of the editor.
+type lspResponseMsg struct {
+	result json.RawMessage
+	err    error
+	handle lspResponseHandler
+}
+
+// lspResponseHandler updates the editor with the answer to a request.
+type lspResponseHandler func(m model, result json.RawMessage, err error) (model, tea.Cmd)
+
+// request asks the server of the file. The request is sent after the
+// changes made so far and its answer is given to handle on the editor's
+// goroutine.
+func (l *lspManager) request(filename, method string, params any, handle lspResponseHandler) error {
+	if l == nil || filename == "" {
+		return errNoLanguageServer
+	}
+	doc, ok := l.docs[fileURI(filename)]
+	if !ok {
+		return errNoLanguageServer
+	}
+
+	l.enqueue(func() {
+		c, ok := l.clients[doc.server]
+		if !ok {
+			l.send(lspResponseMsg{err: errNoLanguageServer, handle: handle})
+			return
+		}
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), lspRequestTimeout)
+			defer cancel()
+			var result json.RawMessage
+			err := c.call(ctx, method, params, &result)
+			l.send(lspResponseMsg{result: result, err: err, handle: handle})
+		}()
+	})
+	return nil
+}
+
+// Shutdown stops the servers. The ones that don't exit on their own are
+// killed.
+func (l *lspManager) Shutdown() {
+	if l == nil {
+		return
+	}
+
+	close(l.closed)
+	<-l.stopped
+
+	var wg sync.WaitGroup
+	for _, c := range l.clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.shutdown()
+		}()
+	}
+	wg.Wait()
+}
+
+// updateLSP handles a message of a language server and waits for the next
+// one.
+func (m model) updateLSP(msg tea.Msg) (model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case lspErrorMsg:
+		m = m.SetErrorMessage(fmt.Sprintf("%s: %s", filepath.Base(msg.server), msg.err))
+	case lspServerMsg:
+		switch msg.method {
+		case "window/showMessage":
+			var params lspShowMessageParams
+			if err := json.Unmarshal(msg.params, &params); err == nil {
+				m = m.showLSPMessage(params)
+			}
+		case "textDocument/publishDiagnostics":
+			m = m.publishDiagnostics(msg.params)
+		case "workspace/applyEdit":
+			var params lspApplyWorkspaceEditParams
+			err := json.Unmarshal(msg.params, &params)
+			if err == nil {
+				m, err = m.workspaceEditMessage(params.Edit)
+			}
+			result := lspApplyWorkspaceEditResult{Applied: err == nil}
+			if err != nil {
+				result.FailureReason = err.Error()
+			}
+			m.lsp.reply(msg.key, msg.id, result)
+		}
+	case lspResponseMsg:
+		m, cmd := msg.handle(m, msg.result, msg.err)
+		return m, tea.Batch(cmd, m.lsp.listen())
+	}
+	return m, m.lsp.listen()
+}
+
+// showLSPMessage shows the errors, warnings and information of a server.
+// Log messages aren't shown.
+func (m model) showLSPMessage(params lspShowMessageParams) model {
+	switch params.Type {
+	case lspMessageTypeError:
+		return m.SetErrorMessage(params.Message)
+	case lspMessageTypeLog:
+		return m
+	}
+	return m.SetInfoMessage(params.Message)
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// lspShutdownTimeout is how long a server gets to exit before it's killed.
+const lspShutdownTimeout = 3 * time.Second
+
+var errLSPClosed = errors.New("language server stopped")
+
+// lspClient talks JSON-RPC to a language server over its stdin and stdout.
+// It's used from many goroutines: requests wait for their responses while
+// a goroutine reads the messages of the server.
+type lspClient struct {
+	name string
+	root string
+	cmd  *exec.Cmd
+
+	writeMu sync.Mutex
+	stdin   io.WriteCloser
+
+	mu      sync.Mutex
+	nextID  int
+	pending map[int]chan lspMessage
+
+	// notify gets the notifications of the server and the requests answered
+	// by the editor, which have an id
+	notify func(id *json.RawMessage, method string, params json.RawMessage)
+	// done is closed when the server stops sending messages
+	done chan struct{}
+}
+
+// startLSPClient runs the server and starts reading its messages. The
+// server isn't initialized yet.
+func startLSPClient(name string, args []string, root string, notify func(id *json.RawMessage, method string, params json.RawMessage)) (*lspClient, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = root
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	c := &lspClient{
+		name:    name,
+		root:    root,
+		cmd:     cmd,
+		stdin:   stdin,
+		pending: make(map[int]chan lspMessage),
+		notify:  notify,
+		done:    make(chan struct{}),
+	}
+	go c.readLoop(bufio.NewReader(stdout))
+	return c, nil
+}
+
+// initialize does the handshake with the server and returns what it's
+// capable of.
+func (c *lspClient) initialize(ctx context.Context) (lspServerCapabilities, error) {
+	params := lspInitializeParams{
+		ProcessID:        os.Getpid(),
+		RootURI:          fileURI(c.root),
+		WorkspaceFolders: []lspWorkspaceFolder{{URI: fileURI(c.root), Name: filepath.Base(c.root)}},
+		Capabilities:     lspClientCapabilities(),
+		ClientInfo:       map[string]string{"name": "goku"},
+	}
+	var result lspInitializeResult
+	if err := c.call(ctx, "initialize", params, &result); err != nil {
+		return lspServerCapabilities{}, err
+	}
+	return result.Capabilities, c.notification("initialized", struct{}{})
+}
+
+// lspClientCapabilities tells the server what the editor supports.
+func lspClientCapabilities() map[string]any {
+	return map[string]any{
+		"textDocument": map[string]any{
+			"synchronization":    map[string]any{"didSave": true},
+			"publishDiagnostics": map[string]any{},
+			"definition":         map[string]any{"linkSupport": true},
+			"typeDefinition":     map[string]any{"linkSupport": true},
+			"implementation":     map[string]any{"linkSupport": true},
+			"references":         map[string]any{},
+			"hover":              map[string]any{"contentFormat": []string{"markdown", "plaintext"}},
+			"completion": map[string]any{
+				"contextSupport": true,
+				"completionItem": map[string]any{
+					"snippetSupport":      true,
+					"documentationFormat": []string{"markdown", "plaintext"},
+				},
+			},
+			"signatureHelp": map[string]any{
+				"signatureInformation": map[string]any{"documentationFormat": []string{"markdown", "plaintext"}},
+			},
+			"rename": map[string]any{},
+			"codeAction": map[string]any{
+				"codeActionLiteralSupport": map[string]any{
+					"codeActionKind": map[string]any{"valueSet": []string{"", "quickfix", "refactor", "source"}},
+				},
+			},
+		},
+		"workspace": map[string]any{
+			"applyEdit":     true,
+			"workspaceEdit": map[string]any{"documentChanges": true},
+		},
+		"window": map[string]any{"showMessage": map[string]any{}},
+	}
+}
+
+// call sends a request and waits for its response. The result is decoded
+// into result unless it's nil.
+func (c *lspClient) call(ctx context.Context, method string, params, result any) error {
+	c.mu.Lock()
+	c.nextID++
+	id := c.nextID
+	ch := make(chan lspMessage, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	rawID := json.RawMessage(strconv.Itoa(id))
+	if err := c.send(lspMessage{ID: &rawID, Method: method}, params); err != nil {
+		return err
+	}
+
+	select {
+	case msg := <-ch:
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if result == nil || len(msg.Result) == 0 {
+			return nil
+		}
+		return json.Unmarshal(msg.Result, result)
+	case <-c.done:
+		return errLSPClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// notification sends a notification, which has no response.
+func (c *lspClient) notification(method string, params any) error {
+	return c.send(lspMessage{Method: method}, params)
+}
+
+// reply answers a request of the server.
+func (c *lspClient) reply(id *json.RawMessage, result any) error {
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return c.send(lspMessage{ID: id, Result: raw}, nil)
+}
+
+// send writes the message with its Content-Length header.
+func (c *lspClient) send(msg lspMessage, params any) error {
+	msg.JSONRPC = "2.0"
+	if params != nil {
+		raw, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		msg.Params = raw
+	}
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if _, err := fmt.Fprintf(c.stdin, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = c.stdin.Write(body)
+	return err
+}
+
+// readLoop reads the messages of the server until its output is closed.
+func (c *lspClient) readLoop(r *bufio.Reader) {
+	defer close(c.done)
+
+	for {
+		msg, err := readLSPMessage(r)
+		if err != nil {
+			return
+		}
+
+		switch {
+		case msg.ID != nil && msg.Method == "":
+			id, _ := strconv.Atoi(string(*msg.ID))
+			c.mu.Lock()
+			ch := c.pending[id]
+			c.mu.Unlock()
+			if ch != nil {
+				ch <- msg
+			}
+		case msg.ID != nil && msg.Method == "workspace/applyEdit":
+			// The edit is made by the editor, which answers once it's done
+			c.notify(msg.ID, msg.Method, msg.Params)
+		case msg.ID != nil:
+			c.reply(msg.ID, serverRequestResult(msg))
+		default:
+			c.notify(nil, msg.Method, msg.Params)
+		}
+	}
+}
+
+// serverRequestResult answers the requests a server may send. The editor
+// has no settings for the servers, every item asked for is null.
+func serverRequestResult(msg lspMessage) any {
+	if msg.Method == "workspace/configuration" {
+		var params struct {
+			Items []json.RawMessage `json:"items"`
+		}
+		json.Unmarshal(msg.Params, &params)
+		return make([]any, len(params.Items))
+	}
+	return nil
+}
+
+// readLSPMessage reads a message with its headers.
+func readLSPMessage(r *bufio.Reader) (lspMessage, error) {
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		return lspMessage{}, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return lspMessage{}, fmt.Errorf("invalid Content-Length: %w", err)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return lspMessage{}, err
+	}
+
+	var msg lspMessage
+	err = json.Unmarshal(body, &msg)
+	return msg, err
+}
+
+// shutdown asks the server to exit and waits for it. A server that doesn't
+// exit in time is killed.
+func (c *lspClient) shutdown() error {
+	ctx, cancel := context.WithTimeout(context.Background(), lspShutdownTimeout)
+	defer cancel()
+
+	if err := c.call(ctx, "shutdown", nil, nil); err == nil {
+		c.notification("exit", nil)
+	}
+	c.stdin.Close()
+
+	exited := make(chan error, 1)
+	go func() { exited <- c.cmd.Wait() }()
+	select {
+	case err := <-exited:
+		return err
+	case <-ctx.Done():
+		c.cmd.Process.Kill()
+		return <-exited
+	}
+}
+
+// lspServerMsg is a notification of a language server, or a request the
+// editor answers with lspManager.reply.
+type lspServerMsg struct {
+	server string
+	// key is the server's key in the manager
+	key    string
+	id     *json.RawMessage
+	method string
+	params json.RawMessage
+}
+
+// lspErrorMsg reports a language server that failed.
+type lspErrorMsg struct {
+	server string
+	err    error
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type lspCodeActionParams struct {
+	TextDocument lspTextDocumentIdentifier `json:"textDocument"`
+	Range        lspRange                  `json:"range"`
+	Context      lspCodeActionContext      `json:"context"`
+}
+
+type lspCodeActionContext struct {
+	Diagnostics []lspDiagnostic `json:"diagnostics"`
+}
+
+type lspCommand struct {
+	Title     string            `json:"title"`
+	Command   string            `json:"command"`
+	Arguments []json.RawMessage `json:"arguments,omitempty"`
+}
+
+// lspCodeAction is an action offered by the server. Its edit is made before
+// its command is executed. Actions without either are resolved first.
+type lspCodeAction struct {
+	Title    string            `json:"title"`
+	Kind     string            `json:"kind,omitempty"`
+	Edit     *lspWorkspaceEdit `json:"edit,omitempty"`
+	Command  *lspCommand       `json:"command,omitempty"`
+	Disabled *struct {
+		Reason string `json:"reason"`
+	} `json:"disabled,omitempty"`
+	// raw is the action as sent by the server, to resolve it
+	raw json.RawMessage
+}
+
+// parseCodeActions reads the answer to a code action request, a list of
+// commands and code actions. Disabled actions are left out.
+func parseCodeActions(raw json.RawMessage) ([]lspCodeAction, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, err
+	}
+
+	var actions []lspCodeAction
+	for _, item := range items {
+		var command struct {
+			Command json.RawMessage `json:"command"`
+		}
+		if err := json.Unmarshal(item, &command); err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(string(command.Command), `"`) {
+			// A command instead of a code action
+			var c lspCommand
+			if err := json.Unmarshal(item, &c); err != nil {
+				return nil, err
+			}
+			actions = append(actions, lspCodeAction{Title: c.Title, Command: &c, raw: item})
+			continue
+		}
+
+		var action lspCodeAction
+		if err := json.Unmarshal(item, &action); err != nil {
+			return nil, err
+		}
+		if action.Disabled == nil {
+			action.raw = item
+			actions = append(actions, action)
+		}
+	}
+	return actions, nil
+}
+
+// lspDiagnostic converts the diagnostic back to the one of the protocol.
+func (b buffer) lspDiagnostic(d diagnostic) lspDiagnostic {
+	return lspDiagnostic{
+		Range:    lspRange{Start: b.lspPosition(d.start), End: b.lspPosition(d.end)},
+		Severity: int(d.severity),
+		Message:  d.message,
+		Source:   d.source,
+	}
+}
+
+// requestCodeActions asks the server what can be done at the cursor, with
+// the diagnostics of its line, and shows the actions in a list.
+func (m model) requestCodeActions() model {
+	b := m.CurrentBuffer()
+	filename := b.filename
+	cur := b.lspPosition(position{line: b.cursorY, col: b.cursorX})
+	params := lspCodeActionParams{
+		TextDocument: lspTextDocumentIdentifier{URI: fileURI(filename)},
+		Range:        lspRange{Start: cur, End: cur},
+		Context:      lspCodeActionContext{Diagnostics: []lspDiagnostic{}},
+	}
+	for _, d := range b.diagnostics {
+		if d.start.line <= b.cursorY && b.cursorY <= d.end.line {
+			params.Context.Diagnostics = append(params.Context.Diagnostics, b.lspDiagnostic(d))
+		}
+	}
+
+	handle := func(m model, result json.RawMessage, err error) (model, tea.Cmd) {
+		if m.mode != ModeNormal {
+			return m, nil
+		}
+		if err != nil {
+			return m.SetErrorMessage(err.Error()), nil
+		}
+		actions, err := parseCodeActions(result)
+		if err != nil {
+			return m.SetErrorMessage("Invalid answer of the language server: " + err.Error()), nil
+		}
+		if len(actions) == 0 {
+			return m.SetErrorMessage("No code actions"), nil
+		}
+
+		items := make([]listItem, 0, len(actions))
+		for _, a := range actions {
+			label := a.Title
+			if a.Kind != "" {
+				label = fmt.Sprintf("%s (%s)", a.Title, a.Kind)
+			}
+			items = append(items, listItem{label: label})
+		}
+		title := fmt.Sprintf("Code actions: %d", len(actions))
+		return m.OpenList(title, items, 0, func(m model, i int) (model, tea.Cmd) {
+			return m.runCodeAction(filename, actions[i]), nil
+		}), nil
+	}
+
+	if err := m.lsp.request(filename, "textDocument/codeAction", params, handle); err != nil {
+		return m.SetErrorMessage(err.Error())
+	}
+	return m
+}
+
+// runCodeAction makes the edit of the action and executes its command on
+// the server of the file.
+func (m model) runCodeAction(filename string, action lspCodeAction) model {
+	if action.Edit == nil && action.Command == nil {
+		return m.resolveCodeAction(filename, action)
+	}
+
+	if action.Edit != nil {
+		var names []string
+		var err error
+		m, names, err = m.applyWorkspaceEdit(*action.Edit)
+		if err != nil {
+			return m.SetErrorMessage("Failed to apply the edit: " + err.Error())
+		}
+		m = m.SetInfoMessage(editSummary(names))
+	}
+	if action.Command == nil {
+		return m
+	}
+
+	// The edits of the command come as workspace/applyEdit requests
+	handle := func(m model, result json.RawMessage, err error) (model, tea.Cmd) {
+		if err != nil {
+			return m.SetErrorMessage(err.Error()), nil
+		}
+		return m, nil
+	}
+	params := lspCommand{Command: action.Command.Command, Arguments: action.Command.Arguments}
+	if err := m.lsp.request(filename, "workspace/executeCommand", params, handle); err != nil {
+		return m.SetErrorMessage(err.Error())
+	}
+	return m
+}
+
+// resolveCodeAction asks the server for the edit of the action before
+// running it.
+func (m model) resolveCodeAction(filename string, action lspCodeAction) model {
+	handle := func(m model, result json.RawMessage, err error) (model, tea.Cmd) {
+		if err != nil {
+			return m.SetErrorMessage(err.Error()), nil
+		}
+		var resolved lspCodeAction
+		if err := json.Unmarshal(result, &resolved); err != nil {
+			return m.SetErrorMessage("Invalid answer of the language server: " + err.Error()), nil
+		}
+		if resolved.Edit == nil && resolved.Command == nil {
+			return m.SetErrorMessage("Nothing to do for " + action.Title), nil
+		}
+		return m.runCodeAction(filename, resolved), nil
+	}
+
+	if err := m.lsp.request(filename, "codeAction/resolve", action.raw, handle); err != nil {
+		return m.SetErrorMessage(err.Error())
+	}
+	return m
+}
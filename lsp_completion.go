@@ -0,0 +1,524 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+const (
+	// completionMaxItems is how many items the menu shows at once
+	completionMaxItems = 10
+	// completionMaxLabel is the width of the longest label shown
+	completionMaxLabel = 40
+	// completionMinDocWidth is the least room the documentation needs to be
+	// shown next to the menu
+	completionMinDocWidth = 20
+)
+
+// completionKinds are the labels of the kinds of the items, indexed by the
+// kind as numbered by the protocol.
+var completionKinds = []string{
+	"", "text", "method", "func", "ctor", "field", "var", "class", "iface", "module", "prop", "unit", "value",
+	"enum", "keyword", "snippet", "color", "file", "ref", "folder", "member", "const", "struct", "event", "op", "type",
+}
+
+// The trigger kinds of a completion request
+const (
+	completionInvoked              = 1
+	completionTriggerCharacter     = 2
+	completionTriggerForIncomplete = 3
+)
+
+// insertTextFormatSnippet marks the insert text of an item as a snippet.
+const insertTextFormatSnippet = 2
+
+type lspCompletionParams struct {
+	lspTextDocumentPositionParams
+	Context lspCompletionContext `json:"context"`
+}
+
+type lspCompletionContext struct {
+	TriggerKind      int    `json:"triggerKind"`
+	TriggerCharacter string `json:"triggerCharacter,omitempty"`
+}
+
+type lspCompletionList struct {
+	IsIncomplete bool                `json:"isIncomplete"`
+	Items        []lspCompletionItem `json:"items"`
+}
+
+type lspCompletionItem struct {
+	Label               string          `json:"label"`
+	Kind                int             `json:"kind,omitempty"`
+	Detail              string          `json:"detail,omitempty"`
+	Documentation       json.RawMessage `json:"documentation,omitempty"`
+	SortText            string          `json:"sortText,omitempty"`
+	FilterText          string          `json:"filterText,omitempty"`
+	InsertText          string          `json:"insertText,omitempty"`
+	InsertTextFormat    int             `json:"insertTextFormat,omitempty"`
+	TextEdit            json.RawMessage `json:"textEdit,omitempty"`
+	AdditionalTextEdits []lspTextEdit   `json:"additionalTextEdits,omitempty"`
+}
+
+// lspCompletionEdit is a text edit or an insert and replace edit, the
+// insert range is used for the latter.
+type lspCompletionEdit struct {
+	Range   *lspRange `json:"range"`
+	Insert  *lspRange `json:"insert"`
+	NewText string    `json:"newText"`
+}
+
+// completionMenu is the completion of the word typed in insert mode.
+type completionMenu struct {
+	// start is where the completed word starts
+	start position
+	// request is the last request for the items, answers to the older ones
+	// are dropped
+	request int
+	items   []lspCompletionItem
+	// incomplete items are asked for again as the word is typed
+	incomplete bool
+	matches    []completionMatch
+	selected   int
+}
+
+// completionMatch is an item matching the typed word.
+type completionMatch struct {
+	item int
+	fuzzyMatch
+}
+
+func (item lspCompletionItem) filterText() string {
+	if item.FilterText != "" {
+		return item.FilterText
+	}
+	return item.Label
+}
+
+func (item lspCompletionItem) kind() string {
+	if item.Kind > 0 && item.Kind < len(completionKinds) {
+		return completionKinds[item.Kind]
+	}
+	return ""
+}
+
+// parseCompletion reads the answer to a completion request, which is a list
+// of items or a completion list.
+func parseCompletion(raw json.RawMessage) ([]lspCompletionItem, bool, error) {
+	var items []lspCompletionItem
+	if err := json.Unmarshal(raw, &items); err == nil {
+		return items, false, nil
+	}
+	var list lspCompletionList
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, false, err
+	}
+	return list.Items, list.IsIncomplete, nil
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// completionWordStart is where the word ending at x starts.
+func completionWordStart(line string, x int) int {
+	x = min(x, len(line))
+	for x > 0 {
+		r, size := utf8.DecodeLastRuneInString(line[:x])
+		if !isWordRune(r) {
+			break
+		}
+		x -= size
+	}
+	return x
+}
+
+// startCompletion asks for the items completing the word before the
+// cursor. After a trigger character the completion starts at the cursor.
+func (m model) startCompletion(trigger string) model {
+	b := m.CurrentBuffer()
+	start := position{line: b.cursorY, col: completionWordStart(b.Line(b.cursorY), b.cursorX)}
+	context := lspCompletionContext{TriggerKind: completionInvoked}
+	if trigger != "" {
+		start.col = b.cursorX
+		context = lspCompletionContext{TriggerKind: completionTriggerCharacter, TriggerCharacter: trigger}
+	}
+	return m.requestCompletion(completionMenu{start: start}, context)
+}
+
+// requestCompletion asks the server for the items of the menu. The items
+// it already has are shown until the answer comes.
+func (m model) requestCompletion(menu completionMenu, context lspCompletionContext) model {
+	m.completionRequests++
+	menu.request = m.completionRequests
+	id := menu.request
+
+	handle := func(m model, result json.RawMessage, err error) (model, tea.Cmd) {
+		if m.mode != ModeInsert || m.insertCompletion == nil || m.insertCompletion.request != id {
+			return m, nil
+		}
+		if err != nil {
+			return m.SetErrorMessage(err.Error()), nil
+		}
+
+		c := *m.insertCompletion
+		items, incomplete, err := parseCompletion(result)
+		if err != nil {
+			return m.SetErrorMessage("Invalid answer of the language server: " + err.Error()), nil
+		}
+		c.items, c.incomplete = items, incomplete
+		m.insertCompletion = &c
+		return m.filterCompletion(), nil
+	}
+
+	b := m.CurrentBuffer()
+	params := lspCompletionParams{lspTextDocumentPositionParams: b.cursorPositionParams(), Context: context}
+	if err := m.lsp.request(b.filename, "textDocument/completion", params, handle); err != nil {
+		// Without a server there's simply nothing to complete
+		m.insertCompletion = nil
+		return m
+	}
+	m.insertCompletion = &menu
+	return m
+}
+
+// completionQuery is the part of the completed word typed so far.
+func (m model) completionQuery() string {
+	b := m.CurrentBuffer()
+	line := b.Line(m.insertCompletion.start.line)
+	return line[min(m.insertCompletion.start.col, len(line)):min(b.cursorX, len(line))]
+}
+
+// filterCompletion matches the items with the typed word. Without a word
+// they keep the order given by the server.
+func (m model) filterCompletion() model {
+	c := *m.insertCompletion
+	query := m.completionQuery()
+
+	c.matches = nil
+	for i, item := range c.items {
+		match, ok := fuzzyScore(query, item.filterText())
+		if !ok {
+			continue
+		}
+		if item.filterText() != item.Label {
+			// The matched characters are only highlighted in the label
+			match.positions = nil
+		}
+		match.text = item.Label
+		c.matches = append(c.matches, completionMatch{item: i, fuzzyMatch: match})
+	}
+	slices.SortStableFunc(c.matches, func(a, b completionMatch) int {
+		if a.score != b.score {
+			return b.score - a.score
+		}
+		return cmp.Compare(c.items[a.item].SortText, c.items[b.item].SortText)
+	})
+	c.selected = 0
+	m.insertCompletion = &c
+	return m
+}
+
+// updateCompletionKeys handles the keys of the menu: ctrl+n and ctrl+p
+// select the items, or open the menu when it's closed, tab and enter accept
+// the selected item and ctrl+e closes the menu. It tells if it handled the
+// key.
+func (m model) updateCompletionKeys(msg tea.KeyMsg) (model, bool) {
+	c := m.insertCompletion
+	visible := c != nil && len(c.matches) > 0
+
+	switch msg.String() {
+	case "ctrl+n", "ctrl+p":
+		if !visible {
+			return m.startCompletion(""), true
+		}
+		menu := *c
+		n := 1
+		if msg.String() == "ctrl+p" {
+			n = -1
+		}
+		menu.selected = (menu.selected + n + len(menu.matches)) % len(menu.matches)
+		m.insertCompletion = &menu
+		return m, true
+	case "tab", "enter":
+		if visible {
+			return m.acceptCompletion(), true
+		}
+	case "ctrl+e":
+		if c != nil {
+			m.insertCompletion = nil
+			return m, true
+		}
+	}
+	return m, false
+}
+
+// updateCompletion follows the typing in insert mode. Typing a word or a
+// trigger character asks for the items, typing more of the word filters
+// them. Other keys close the menu.
+func (m model) updateCompletion(msg tea.KeyMsg) model {
+	if m.mode != ModeInsert {
+		m.insertCompletion = nil
+		return m
+	}
+
+	switch msg.String() {
+	case "ctrl+n", "ctrl+p", "ctrl+e", "tab":
+		// Handled by updateCompletionKeys
+		return m
+	}
+
+	typed := msg.Type == tea.KeyRunes && len(msg.Runes) == 1
+	if typed && slices.Contains(m.lsp.completionTriggers(m.CurrentBuffer().filename), string(msg.Runes)) {
+		return m.startCompletion(string(msg.Runes))
+	}
+	if !typed && msg.Type != tea.KeyBackspace {
+		m.insertCompletion = nil
+		return m
+	}
+
+	if c := m.insertCompletion; c != nil {
+		b := m.CurrentBuffer()
+		if b.cursorY == c.start.line && b.cursorX >= c.start.col && strings.IndexFunc(m.completionQuery(), func(r rune) bool { return !isWordRune(r) }) < 0 {
+			if c.incomplete {
+				m = m.requestCompletion(*c, lspCompletionContext{TriggerKind: completionTriggerForIncomplete})
+			}
+			return m.filterCompletion()
+		}
+		m.insertCompletion = nil
+	}
+
+	if typed && isWordRune(msg.Runes[0]) {
+		return m.startCompletion("")
+	}
+	return m
+}
+
+// acceptCompletion replaces the typed word with the selected item and makes
+// its additional edits, like adding an import. The cursor goes to the end
+// of the inserted text, or the first tab stop of a snippet.
+func (m model) acceptCompletion() model {
+	c := *m.insertCompletion
+	item := c.items[c.matches[c.selected].item]
+	query := m.completionQuery()
+	m.insertCompletion = nil
+
+	b := m.CurrentBuffer()
+	cur := position{line: b.cursorY, col: b.cursorX}
+	main := textEdit{start: c.start, end: cur, text: item.InsertText}
+	if main.text == "" {
+		main.text = item.Label
+	}
+
+	var edit lspCompletionEdit
+	if len(item.TextEdit) > 0 && json.Unmarshal(item.TextEdit, &edit) == nil {
+		r := edit.Range
+		if r == nil {
+			r = edit.Insert
+		}
+		if r != nil {
+			main.start = b.bytePosition(r.Start)
+			if end := b.bytePosition(r.End); cur.before(end) {
+				main.end = end
+			}
+			main.text = edit.NewText
+		}
+	}
+
+	cursor := len(main.text)
+	if item.InsertTextFormat == insertTextFormatSnippet {
+		main.text, cursor = expandSnippet(main.text)
+	}
+
+	var others []textEdit
+	for _, e := range item.AdditionalTextEdits {
+		others = append(others, b.textEditFromLSP(e))
+	}
+	b = b.applyTextEdits(append(slices.Clone(others), main))
+
+	p := positionAfterEdits(main.start, others)
+	lines := strings.Split(main.text[:cursor], "\n")
+	if len(lines) == 1 {
+		p.col += len(lines[0])
+	} else {
+		p = position{line: p.line + len(lines) - 1, col: len(lines[len(lines)-1])}
+	}
+
+	m.buffers[m.currBuffer] = b.moveCursorTo(p.col, p.line).SetStateModified()
+	m.lastInsert = strings.TrimSuffix(m.lastInsert, query) + main.text
+	return m
+}
+
+// expandSnippet turns the snippet into plain text: placeholders become their
+// default text, choices their first option and variables are left out. It
+// returns the offset of the first tab stop, or the final one, or the end of
+// the text.
+func expandSnippet(snippet string) (string, int) {
+	p := snippetParser{s: snippet, stops: make(map[int]int)}
+	p.parse(false)
+	text := p.out.String()
+
+	first := -1
+	for n := range p.stops {
+		if n > 0 && (first < 0 || n < first) {
+			first = n
+		}
+	}
+	if first > 0 {
+		return text, p.stops[first]
+	}
+	if offset, ok := p.stops[0]; ok {
+		return text, offset
+	}
+	return text, len(text)
+}
+
+type snippetParser struct {
+	s   string
+	i   int
+	out strings.Builder
+	// stops are the offsets of the tab stops by their number
+	stops map[int]int
+}
+
+// parse copies the text until the end of the snippet, or the closing brace
+// of the placeholder.
+func (p *snippetParser) parse(nested bool) {
+	for p.i < len(p.s) {
+		c := p.s[p.i]
+		switch {
+		case c == '\\' && p.i+1 < len(p.s) && strings.IndexByte(`$}\,|`, p.s[p.i+1]) >= 0:
+			p.out.WriteByte(p.s[p.i+1])
+			p.i += 2
+		case c == '}' && nested:
+			p.i++
+			return
+		case c == '$':
+			p.i++
+			p.element()
+		default:
+			p.out.WriteByte(c)
+			p.i++
+		}
+	}
+}
+
+// element reads what follows a $: a tab stop, a placeholder, a choice or a
+// variable.
+func (p *snippetParser) element() {
+	braced := p.i < len(p.s) && p.s[p.i] == '{'
+	if braced {
+		p.i++
+	}
+
+	name := p.name()
+	if name == "" {
+		p.out.WriteByte('$')
+		if braced {
+			p.out.WriteByte('{')
+		}
+		return
+	}
+	if n, err := strconv.Atoi(name); err == nil {
+		if _, ok := p.stops[n]; !ok {
+			p.stops[n] = p.out.Len()
+		}
+	}
+	if !braced {
+		return
+	}
+
+	switch {
+	case p.i < len(p.s) && p.s[p.i] == ':':
+		p.i++
+		p.parse(true)
+	case p.i < len(p.s) && p.s[p.i] == '|':
+		choices, _, _ := strings.Cut(p.s[p.i+1:], "|")
+		first, _, _ := strings.Cut(choices, ",")
+		p.out.WriteString(first)
+		p.i += 1 + len(choices) + 1
+		if p.i < len(p.s) && p.s[p.i] == '}' {
+			p.i++
+		}
+	default:
+		// The rest of a variable, like its transformation, is skipped
+		if end := strings.IndexByte(p.s[p.i:], '}'); end >= 0 {
+			p.i += end + 1
+		} else {
+			p.i = len(p.s)
+		}
+	}
+}
+
+// name reads a tab stop number or a variable name.
+func (p *snippetParser) name() string {
+	start := p.i
+	for p.i < len(p.s) && (p.s[p.i] == '_' || unicode.IsLetter(rune(p.s[p.i])) || unicode.IsDigit(rune(p.s[p.i]))) {
+		p.i++
+	}
+	return p.s[start:p.i]
+}
+
+// box renders the menu with the selected item highlighted.
+func (c completionMenu) box(style editorStyle) []string {
+	first := max(0, c.selected-completionMaxItems+1)
+	last := min(first+completionMaxItems, len(c.matches))
+
+	labelWidth, kindWidth := 0, 0
+	for _, match := range c.matches[first:last] {
+		item := c.items[match.item]
+		labelWidth = max(labelWidth, min(ansi.StringWidth(item.Label), completionMaxLabel))
+		kindWidth = max(kindWidth, len(item.kind()))
+	}
+
+	var box []string
+	for i := first; i < last; i++ {
+		base := style.popup
+		if i == c.selected {
+			base = style.selection
+		}
+		match := c.matches[i]
+		label, used := fuzzyMatchView(match.fuzzyMatch, base, style.fuzzyMatch.Inherit(base), labelWidth)
+		kind := c.items[match.item].kind()
+		box = append(box, base.Render(" ")+label+base.Render(strings.Repeat(" ", labelWidth-used+1)+kind+strings.Repeat(" ", kindWidth-len(kind))+" "))
+	}
+	return box
+}
+
+// documentation is the detail and the documentation of the selected item.
+func (c completionMenu) documentation() *popup {
+	item := c.items[c.matches[c.selected].item]
+	text := item.Detail
+	if doc := markupText(item.Documentation); doc != "" {
+		text += "\n\n" + doc
+	}
+	return newPopup(text, false)
+}
+
+// completionView draws the menu below the word being completed with the
+// documentation next to it.
+func (m model) completionView(lines []string) []string {
+	c := m.insertCompletion
+	if m.mode != ModeInsert || c == nil || len(c.matches) == 0 {
+		return lines
+	}
+
+	x, y := m.cursorScreenPosition()
+	x -= ansi.StringWidth(expandTabs(m.completionQuery()))
+	menu := c.box(m.style)
+	lines = overlay(lines, menu, x, y, m.viewport.Width)
+
+	docX := x + ansi.StringWidth(menu[0])
+	if doc := c.documentation(); doc != nil && m.viewport.Width-docX >= completionMinDocWidth {
+		lines = overlay(lines, doc.box(m.style, m.viewport.Width-docX), docX, y, m.viewport.Width)
+	}
+	return lines
+}
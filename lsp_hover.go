@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type lspMarkupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+type lspHover struct {
+	Contents json.RawMessage `json:"contents"`
+}
+
+type lspSignatureHelp struct {
+	Signatures      []lspSignature `json:"signatures"`
+	ActiveSignature int            `json:"activeSignature"`
+}
+
+type lspSignature struct {
+	Label         string          `json:"label"`
+	Documentation json.RawMessage `json:"documentation"`
+}
+
+// markupText is the text of the documentation sent by a server. It's a
+// string, markup content, a marked string or a list of them. The fences of
+// code blocks are left out.
+func markupText(raw json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
+	}
+
+	var list []json.RawMessage
+	if err := json.Unmarshal(raw, &list); err == nil {
+		var parts []string
+		for _, item := range list {
+			if text := markupText(item); text != "" {
+				parts = append(parts, text)
+			}
+		}
+		return strings.Join(parts, "\n\n")
+	}
+
+	var content lspMarkupContent
+	if err := json.Unmarshal(raw, &content); err != nil {
+		return ""
+	}
+
+	var lines []string
+	for _, line := range strings.Split(content.Value, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// requestHover asks for the documentation of the symbol under the cursor.
+// It's shown unless the cursor moved in the meantime.
+func (m model) requestHover() model {
+	b := m.CurrentBuffer()
+	params := b.cursorPositionParams()
+	handle := func(m model, result json.RawMessage, err error) (model, tea.Cmd) {
+		cur := m.CurrentBuffer()
+		if m.mode != ModeNormal || fileURI(cur.filename) != params.TextDocument.URI || cur.cursorY != b.cursorY || cur.cursorX != b.cursorX {
+			return m, nil
+		}
+		if err != nil {
+			return m.SetErrorMessage(err.Error()), nil
+		}
+
+		var hover lspHover
+		if string(result) != "null" {
+			json.Unmarshal(result, &hover)
+		}
+		m.popup = newPopup(markupText(hover.Contents), false)
+		if m.popup == nil {
+			return m.SetInfoMessage("No documentation"), nil
+		}
+		return m, nil
+	}
+
+	if err := m.lsp.request(b.filename, "textDocument/hover", params, handle); err != nil {
+		return m.SetErrorMessage(err.Error())
+	}
+	return m
+}
+
+// signatureTriggers are the characters typed in insert mode which update
+// the signature help.
+const signatureTriggers = "(,)"
+
+// requestSignatureHelp asks for the signature of the function called at the
+// cursor. It's shown while typing its arguments, the popup is closed when
+// the server has no signature for the position.
+func (m model) requestSignatureHelp() model {
+	b := m.CurrentBuffer()
+	handle := func(m model, result json.RawMessage, err error) (model, tea.Cmd) {
+		if m.mode != ModeInsert || m.CurrentBuffer().filename != b.filename {
+			return m, nil
+		}
+
+		var help lspSignatureHelp
+		if err != nil || string(result) == "null" || json.Unmarshal(result, &help) != nil || len(help.Signatures) == 0 {
+			m.popup = nil
+			return m, nil
+		}
+
+		s := help.Signatures[max(0, min(help.ActiveSignature, len(help.Signatures)-1))]
+		text := s.Label
+		if doc := markupText(s.Documentation); doc != "" {
+			text += "\n\n" + doc
+		}
+		m.popup = newPopup(text, true)
+		return m, nil
+	}
+
+	// Without a server there's simply no signature
+	m.lsp.request(b.filename, "textDocument/signatureHelp", b.cursorPositionParams(), handle)
+	return m
+}
+
+// updatePopup closes the popup after a key, except the signature help which
+// is updated by typing its trigger characters in insert mode.
+func (m model) updatePopup(msg tea.KeyMsg) model {
+	if m.mode != ModeInsert || (m.popup != nil && !m.popup.signature) {
+		m.popup = nil
+	}
+	if m.mode == ModeInsert && msg.Type == tea.KeyRunes && len(msg.Runes) == 1 && strings.ContainsRune(signatureTriggers, msg.Runes[0]) {
+		return m.requestSignatureHelp()
+	}
+	return m
+}
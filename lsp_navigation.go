@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// lspPosition converts the position in the buffer to the one of the
+// protocol.
+func (b buffer) lspPosition(p position) lspPosition {
+	return lspPosition{Line: p.line, Character: byteOffsetToUTF16(b.Line(p.line), p.col)}
+}
+
+// cursorPositionParams are the params of the requests about the symbol
+// under the cursor.
+func (b buffer) cursorPositionParams() lspTextDocumentPositionParams {
+	return lspTextDocumentPositionParams{
+		TextDocument: lspTextDocumentIdentifier{URI: fileURI(b.filename)},
+		Position:     b.lspPosition(position{line: b.cursorY, col: b.cursorX}),
+	}
+}
+
+// parseLocations reads the answer to a definition or references request,
+// which is a location, a list of them or a list of location links.
+func parseLocations(raw json.RawMessage) ([]lspLocation, error) {
+	trimmed := strings.TrimSpace(string(raw))
+	if trimmed == "" || trimmed == "null" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(trimmed, "[") {
+		raw = json.RawMessage("[" + trimmed + "]")
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, err
+	}
+
+	var locations []lspLocation
+	for _, item := range items {
+		var link lspLocationLink
+		if err := json.Unmarshal(item, &link); err == nil && link.TargetURI != "" {
+			locations = append(locations, lspLocation{URI: link.TargetURI, Range: link.TargetSelectionRange})
+			continue
+		}
+		var loc lspLocation
+		if err := json.Unmarshal(item, &loc); err != nil {
+			return nil, err
+		}
+		locations = append(locations, loc)
+	}
+	return locations, nil
+}
+
+// requestLocations asks the server where the symbol under the cursor is
+// defined, used and so on. One location is opened right away, more of them
+// are shown in a list. An answer coming after leaving normal mode is
+// dropped.
+func (m model) requestLocations(method, what string, params any) model {
+	handle := func(m model, result json.RawMessage, err error) (model, tea.Cmd) {
+		if m.mode != ModeNormal {
+			return m, nil
+		}
+		if err != nil {
+			return m.SetErrorMessage(err.Error()), nil
+		}
+		locations, err := parseLocations(result)
+		if err != nil {
+			return m.SetErrorMessage("Invalid answer of the language server: " + err.Error()), nil
+		}
+		switch len(locations) {
+		case 0:
+			return m.SetErrorMessage("No " + what + " found"), nil
+		case 1:
+			return m.openLocation(locations[0])
+		}
+		return m.locationList(what, locations), nil
+	}
+
+	if err := m.lsp.request(m.CurrentBuffer().filename, method, params, handle); err != nil {
+		return m.SetErrorMessage(err.Error())
+	}
+	return m
+}
+
+// locationPath is the file name of the URI, relative to the working
+// directory when it's inside of it.
+func locationPath(uri string) string {
+	name := uriToPath(uri)
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, name); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+	return name
+}
+
+// openLocation opens the file with the cursor at the start of the range.
+func (m model) openLocation(loc lspLocation) (model, tea.Cmd) {
+	m, err := m.openFile(locationPath(loc.URI))
+	if err != nil {
+		return m.SetErrorMessage("Failed to open file: " + err.Error()), nil
+	}
+
+	b := m.buffers[m.currBuffer]
+	p := b.bytePosition(loc.Range.Start)
+	m.buffers[m.currBuffer] = b.moveCursorTo(p.col, p.line)
+	return m, nil
+}
+
+// locationList shows the locations with the lines they point at. The lines
+// come from the buffers, or the files when they aren't open.
+func (m model) locationList(what string, locations []lspLocation) model {
+	files := make(map[string][]string)
+	fileLines := func(name string) []string {
+		if lines, ok := files[name]; ok {
+			return lines
+		}
+		var lines []string
+		if i := m.findBufferByFile(name); i >= 0 {
+			lines = m.buffers[i].Lines()
+		} else if content, err := os.ReadFile(name); err == nil {
+			lines = strings.Split(string(content), "\n")
+		}
+		files[name] = lines
+		return lines
+	}
+
+	items := make([]listItem, 0, len(locations))
+	for _, loc := range locations {
+		name := locationPath(loc.URI)
+		lines := fileLines(name)
+		y := loc.Range.Start.Line
+		var text string
+		if y < len(lines) {
+			text = lines[y]
+		}
+		first := min(max(y-grepContextLines, 0), len(lines))
+		items = append(items, listItem{
+			label:   fmt.Sprintf("%s:%d:%d: %s", name, y+1, utf16ToByteOffset(text, loc.Range.Start.Character)+1, strings.TrimSpace(text)),
+			preview: lines[first:min(first+previewLines, len(lines))],
+		})
+	}
+
+	title := fmt.Sprintf("%s%s: %d", strings.ToUpper(what[:1]), what[1:], len(locations))
+	return m.OpenList(title, items, 0, func(m model, i int) (model, tea.Cmd) {
+		return m.openLocation(locations[i])
+	})
+}
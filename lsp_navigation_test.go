@@ -0,0 +1,134 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// navigationTestModel has the cursor on the call of helper, which is
+// declared in other.go.
+func navigationTestModel(t *testing.T) (model, string) {
+	m, dir := lspTestModel(t, os.Args[0], map[string]string{
+		"main.go":  "package main\n\nfunc main() {\n\thelper(1, \"x\")\n}\n",
+		"other.go": "package main\n\n// helper does nothing\nfunc helper(a int, b string) {}\n",
+	})
+	// The server is ready once it asked for its settings
+	m = waitUntil(t, m, func(m model) bool { return m.currentMessage != nil })
+	m = pressKeys(m, runeKeys("jjjl")...)
+	return m, dir
+}
+
+func TestGoToDefinition(t *testing.T) {
+	for _, key := range []string{"gd", "gy"} {
+		t.Run(key, func(t *testing.T) {
+			m, dir := navigationTestModel(t)
+			defer m.lsp.Shutdown()
+
+			m = pressKeys(m, runeKeys(key)...)
+			m = waitUntil(t, m, func(m model) bool { return m.currBuffer != 0 })
+			if m.CurrentBuffer().filename != filepath.Join(dir, "other.go") || cursorOf(m) != (position{line: 3, col: 5}) {
+				t.Errorf("Expected the definition in other.go, got %s %v", m.CurrentBuffer().filename, cursorOf(m))
+			}
+		})
+	}
+}
+
+func TestReferences(t *testing.T) {
+	m, dir := navigationTestModel(t)
+	defer m.lsp.Shutdown()
+
+	m = pressKeys(m, runeKeys("gr")...)
+	m = waitUntil(t, m, func(m model) bool { return m.mode == ModeList })
+	if len(m.list.items) != 3 || m.list.title != "References: 3" {
+		t.Fatalf("Expected 3 references, got %q", m.list.title)
+	}
+	want := filepath.Join(dir, "other.go") + ":4:6: func helper(a int, b string) {}"
+	if m.list.items[2].label != want {
+		t.Errorf("Expected the label %q, got %q", want, m.list.items[2].label)
+	}
+
+	m = pressKeys(m, runeKeys("G")...)
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.CurrentBuffer().filename != filepath.Join(dir, "other.go") || cursorOf(m) != (position{line: 3, col: 5}) {
+		t.Errorf("Expected the reference in other.go, got %s %v", m.CurrentBuffer().filename, cursorOf(m))
+	}
+}
+
+func TestNoImplementation(t *testing.T) {
+	m, _ := navigationTestModel(t)
+	defer m.lsp.Shutdown()
+
+	m = pressKeys(m, runeKeys("gi")...)
+	m = waitUntil(t, m, func(m model) bool { return m.currentMessage != nil })
+	if m.currentMessage.text != "No implementation found" {
+		t.Errorf("Expected no implementation, got %q", m.currentMessage.text)
+	}
+}
+
+func TestNoLanguageServer(t *testing.T) {
+	m := initialModel()
+	m = pressKeys(m, runeKeys("gd")...)
+	if m.currentMessage == nil || m.currentMessage.text != "No language server for this file" {
+		t.Errorf("Expected an error without a server, got %+v", m.currentMessage)
+	}
+}
+
+func TestHover(t *testing.T) {
+	m, _ := navigationTestModel(t)
+	defer m.lsp.Shutdown()
+
+	m = pressKeys(m, runeKeys("K")...)
+	m = waitUntil(t, m, func(m model) bool { return m.popup != nil })
+	if strings.Join(m.popup.lines, "\n") != "func helper()\nDocs of helper" {
+		t.Errorf("Expected the documentation without fences, got %q", m.popup.lines)
+	}
+
+	lines := strings.Split(m.View(), "\n")
+	if !strings.Contains(lines[4], "func helper()") || !strings.Contains(lines[5], "Docs of helper") {
+		t.Errorf("Expected the popup below the cursor, got %q", lines[3:6])
+	}
+
+	m = pressKeys(m, runeKeys("l")...)
+	if m.popup != nil || strings.Contains(m.View(), "Docs of helper") {
+		t.Errorf("Expected the popup to be closed by the next key")
+	}
+}
+
+func TestSignatureHelp(t *testing.T) {
+	m, _ := navigationTestModel(t)
+	defer m.lsp.Shutdown()
+
+	m = pressKeys(m, runeKeys("ohelper(")...)
+	m = waitUntil(t, m, func(m model) bool { return m.popup != nil })
+	if m.popup.lines[0] != "helper(a int, b string)" || !strings.Contains(m.View(), "helper(a int, b string)") {
+		t.Errorf("Expected the signature, got %q", m.popup.lines)
+	}
+
+	m = pressKeys(m, runeKeys("1, ")...)
+	if m.popup == nil {
+		t.Fatalf("Expected the signature to stay while typing the arguments")
+	}
+
+	m = pressKeys(m, runeKeys("\"y\")")...)
+	m = waitUntil(t, m, func(m model) bool { return m.popup == nil })
+}
+
+func TestOverlay(t *testing.T) {
+	lines := []string{"0123456789", "abcdefghij", "ABCDEFGHIJ", ""}
+	got := overlay(lines, []string{"xx", "yy"}, 8, 0, 10)
+	want := []string{"0123456789", "abcdefghxx", "ABCDEFGHyy", ""}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	// Without room below the box goes above the cursor
+	got = overlay(lines, []string{"xx", "yy"}, 2, 3, 10)
+	want = []string{"0123456789", "abxxefghij", "AByyEFGHIJ", ""}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
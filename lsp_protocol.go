@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+)
+
+// lspMessage is a JSON-RPC message. Requests have an id and a method,
+// notifications only a method and responses only an id.
+type lspMessage struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *lspError        `json:"error,omitempty"`
+}
+
+type lspError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *lspError) Error() string {
+	return e.Message
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspLocation struct {
+	URI   string   `json:"uri"`
+	Range lspRange `json:"range"`
+}
+
+// lspLocationLink is an alternative to lspLocation a server may answer a
+// definition request with.
+type lspLocationLink struct {
+	TargetURI            string   `json:"targetUri"`
+	TargetSelectionRange lspRange `json:"targetSelectionRange"`
+}
+
+type lspTextDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type lspVersionedTextDocumentIdentifier struct {
+	URI     string `json:"uri"`
+	Version int    `json:"version"`
+}
+
+type lspTextDocumentItem struct {
+	URI        string `json:"uri"`
+	LanguageID string `json:"languageId"`
+	Version    int    `json:"version"`
+	Text       string `json:"text"`
+}
+
+type lspDidOpenParams struct {
+	TextDocument lspTextDocumentItem `json:"textDocument"`
+}
+
+// lspContentChange without a range replaces the whole document.
+type lspContentChange struct {
+	Text string `json:"text"`
+}
+
+type lspDidChangeParams struct {
+	TextDocument   lspVersionedTextDocumentIdentifier `json:"textDocument"`
+	ContentChanges []lspContentChange                 `json:"contentChanges"`
+}
+
+type lspDidSaveParams struct {
+	TextDocument lspTextDocumentIdentifier `json:"textDocument"`
+}
+
+type lspDidCloseParams struct {
+	TextDocument lspTextDocumentIdentifier `json:"textDocument"`
+}
+
+type lspTextDocumentPositionParams struct {
+	TextDocument lspTextDocumentIdentifier `json:"textDocument"`
+	Position     lspPosition               `json:"position"`
+}
+
+type lspReferenceParams struct {
+	lspTextDocumentPositionParams
+	Context lspReferenceContext `json:"context"`
+}
+
+type lspReferenceContext struct {
+	IncludeDeclaration bool `json:"includeDeclaration"`
+}
+
+type lspWorkspaceFolder struct {
+	URI  string `json:"uri"`
+	Name string `json:"name"`
+}
+
+type lspInitializeParams struct {
+	ProcessID        int                  `json:"processId"`
+	RootURI          string               `json:"rootUri"`
+	WorkspaceFolders []lspWorkspaceFolder `json:"workspaceFolders"`
+	Capabilities     map[string]any       `json:"capabilities"`
+	ClientInfo       map[string]string    `json:"clientInfo"`
+}
+
+type lspInitializeResult struct {
+	Capabilities lspServerCapabilities `json:"capabilities"`
+}
+
+// lspServerCapabilities are the features of the server the editor needs to
+// know about before asking for them.
+type lspServerCapabilities struct {
+	CompletionProvider *lspCompletionOptions `json:"completionProvider"`
+}
+
+type lspCompletionOptions struct {
+	TriggerCharacters []string `json:"triggerCharacters"`
+}
+
+type lspShowMessageParams struct {
+	Type    int    `json:"type"`
+	Message string `json:"message"`
+}
+
+// The types of window/showMessage
+const (
+	lspMessageTypeError = 1
+	lspMessageTypeLog   = 4
+)
+
+// fileURI turns a file name into a file:// URI.
+func fileURI(name string) string {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		abs = name
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String()
+}
+
+// uriToPath turns a file:// URI into a file name. Other URIs are returned
+// as they are.
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+// lspLanguageID is the language of the file as named by the protocol.
+func lspLanguageID(name string) string {
+	ext := strings.TrimPrefix(filepath.Ext(name), ".")
+	switch ext {
+	case "js":
+		return "javascript"
+	case "ts":
+		return "typescript"
+	}
+	if lang := languageName(name); lang != "" {
+		return lang
+	}
+	return ext
+}
+
+// utf16ToByteOffset converts a column counted in UTF-16 code units, the way
+// the protocol counts them, to a byte offset in the line.
+func utf16ToByteOffset(line string, character int) int {
+	units := 0
+	for i, r := range line {
+		if units >= character {
+			return i
+		}
+		units += utf16.RuneLen(r)
+	}
+	return len(line)
+}
+
+// byteOffsetToUTF16 converts a byte offset in the line to a column counted
+// in UTF-16 code units.
+func byteOffsetToUTF16(line string, offset int) int {
+	units := 0
+	for i, r := range line {
+		if i >= offset {
+			break
+		}
+		units += utf16.RuneLen(r)
+	}
+	return units
+}
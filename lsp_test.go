@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestMain(m *testing.M) {
+	// The test binary is the fake language server of the tests
+	if os.Getenv("GOKU_FAKE_LSP") != "" {
+		runFakeLSPServer(os.Stdin, os.Stdout)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// lspTestModel opens main.go of a Go project served by the fake server.
+// The files are added to the project.
+func lspTestModel(t *testing.T, server string, files map[string]string) (model, string) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":  "module fake\n",
+		"main.go": "package main\n\nfunc main() {}\n",
+	})
+	writeFiles(t, dir, files)
+	t.Setenv("GOKU_FAKE_LSP", "1")
+
+	m := initialModel(WithLSP(newLSPManager()), WithFile(filepath.Join(dir, "main.go")))
+	m.Languages["go"] = languageSupport{Name: "Go", LSPServer: toolInfo{Name: server, IsInstalled: true}}
+	res, _ := m.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	return res.(model), dir
+}
+
+// nextLSPMsg waits for the next message of the servers and handles it.
+func nextLSPMsg(t *testing.T, m model) (model, tea.Msg) {
+	t.Helper()
+	msgs := make(chan tea.Msg, 1)
+	go func() { msgs <- m.lsp.listen()() }()
+
+	select {
+	case msg := <-msgs:
+		res, _ := m.Update(msg)
+		return res.(model), msg
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Timed out waiting for the language server")
+		return m, nil
+	}
+}
+
+// waitUntil handles the messages of the servers until the condition is met.
+func waitUntil(t *testing.T, m model, cond func(m model) bool) model {
+	t.Helper()
+	for !cond(m) {
+		m, _ = nextLSPMsg(t, m)
+	}
+	return m
+}
+
+// waitForLSP waits for the notification of the server.
+func waitForLSP(t *testing.T, m model, method string, params any) model {
+	t.Helper()
+	for {
+		var msg tea.Msg
+		m, msg = nextLSPMsg(t, m)
+		switch msg := msg.(type) {
+		case lspErrorMsg:
+			t.Fatalf("Unexpected error of the server: %v", msg.err)
+		case lspServerMsg:
+			if msg.method == method {
+				if err := json.Unmarshal(msg.params, params); err != nil {
+					t.Fatalf("Invalid params of %s: %v", method, err)
+				}
+				return m
+			}
+		}
+	}
+}
+
+func TestLSPDocumentSync(t *testing.T) {
+	m, dir := lspTestModel(t, os.Args[0], nil)
+	uri := fileURI(filepath.Join(dir, "main.go"))
+
+	var opened lspDidOpenParams
+	m = waitForLSP(t, m, "fake/textDocument/didOpen", &opened)
+	want := lspTextDocumentItem{URI: uri, LanguageID: "go", Version: 1, Text: "package main\n\nfunc main() {}\n"}
+	if opened.TextDocument != want {
+		t.Errorf("Expected didOpen of %+v, got %+v", want, opened.TextDocument)
+	}
+
+	m = pressKeys(m, runeKeys("dd")...)
+	var changed lspDidChangeParams
+	m = waitForLSP(t, m, "fake/textDocument/didChange", &changed)
+	if changed.TextDocument.Version != 2 || len(changed.ContentChanges) != 1 || changed.ContentChanges[0].Text != "\nfunc main() {}\n" {
+		t.Errorf("Expected the new text in version 2, got %+v", changed)
+	}
+
+	m = pressKeys(m, runeKeys(":w")...)
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEnter})
+	var saved lspDidSaveParams
+	m = waitForLSP(t, m, "fake/textDocument/didSave", &saved)
+	if saved.TextDocument.URI != uri {
+		t.Errorf("Expected didSave of %s, got %s", uri, saved.TextDocument.URI)
+	}
+
+	m = pressKeys(m, runeKeys(":e "+filepath.Join(dir, "notes.txt"))...)
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEnter})
+	m = pressKeys(m, runeKeys(":bd 1")...)
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEnter})
+	var closed lspDidCloseParams
+	m = waitForLSP(t, m, "fake/textDocument/didClose", &closed)
+	if closed.TextDocument.URI != uri {
+		t.Errorf("Expected didClose of %s, got %s", uri, closed.TextDocument.URI)
+	}
+
+	l := m.lsp
+	l.Shutdown()
+	if len(l.clients) != 1 {
+		t.Fatalf("Expected a server for the project, got %d", len(l.clients))
+	}
+	for _, c := range l.clients {
+		if !c.cmd.ProcessState.Exited() || c.cmd.ProcessState.ExitCode() != 0 {
+			t.Errorf("Expected the server to exit, got %v", c.cmd.ProcessState)
+		}
+	}
+}
+
+func TestLSPServerRequests(t *testing.T) {
+	m, _ := lspTestModel(t, os.Args[0], nil)
+	defer m.lsp.Shutdown()
+
+	// The server asks for its settings, the answer has one for each item
+	m = waitUntil(t, m, func(m model) bool { return m.currentMessage != nil })
+	if m.currentMessage.text != "configured 2" || m.currentMessage.msgType != MessageInfo {
+		t.Errorf("Expected the message of the server, got %+v", m.currentMessage)
+	}
+}
+
+func TestLSPServerNotStarted(t *testing.T) {
+	m, _ := lspTestModel(t, "goku-missing-language-server", nil)
+	defer m.lsp.Shutdown()
+
+	m, msg := nextLSPMsg(t, m)
+	if _, ok := msg.(lspErrorMsg); !ok {
+		t.Fatalf("Expected an error, got %#v", msg)
+	}
+	if m.currentMessage == nil || m.currentMessage.msgType != MessageError || !strings.Contains(m.currentMessage.text, "goku-missing-language-server") {
+		t.Errorf("Expected the failure to be shown, got %+v", m.currentMessage)
+	}
+}
+
+func TestLSPRoot(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":          "module fake\n",
+		"cmd/app/main.go": "package main\n",
+	})
+	other := t.TempDir()
+
+	if root := lspRoot(filepath.Join(dir, "cmd", "app", "main.go")); root != dir {
+		t.Errorf("Expected the root of the module %s, got %s", dir, root)
+	}
+	if root := lspRoot(filepath.Join(other, "main.go")); root != other {
+		t.Errorf("Expected the directory of the file %s, got %s", other, root)
+	}
+}
@@ -20,6 +20,9 @@ func main() {
 	}
 	
 	var opts []modelOption
+	if filename, err := historyFile(); err == nil {
+		opts = append(opts, WithHistoryFile(filename))
+	}
 	
 	// Check if filenames were provided as command line arguments
 	if len(os.Args) > 1 {
@@ -33,8 +36,13 @@ func main() {
 		}
 	}
 	
+	lsp := newLSPManager()
+	opts = append(opts, WithLSP(lsp))
+
 	p := tea.NewProgram(initialModel(opts...), tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
+	_, err := p.Run()
+	lsp.Shutdown()
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
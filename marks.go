@@ -0,0 +1,47 @@
+package main
+
+// marks maps mark names to positions in a buffer. a-z are set with m, < and >
+// hold the last visual selection.
+type marks map[rune]position
+
+// Mark returns the position of the mark.
+func (b buffer) Mark(name rune) (position, bool) {
+	p, ok := b.marks[name]
+	if !ok {
+		return position{}, false
+	}
+	// Lines could have been deleted since the mark was set
+	p.line = min(p.line, b.NoOfLines()-1)
+	return p, true
+}
+
+// SetMark stores the position under the name. The map is copied because
+// other copies of the buffer share it.
+func (b buffer) SetMark(name rune, p position) buffer {
+	m := make(marks, len(b.marks)+1)
+	for k, v := range b.marks {
+		m[k] = v
+	}
+	m[name] = p
+	b.marks = m
+	return b
+}
+
+// setVisualMarks stores the bounds of the selection in the < and > marks.
+func (m model) setVisualMarks() model {
+	r := m.Selection()
+	b := m.buffers[m.currBuffer]
+	start, end := r.start, r.end
+	switch {
+	case r.kind == rangeBlockwise:
+		// The marks hold byte offsets, the block covers screen columns
+		start = b.rangeStart(r)
+		end.col = columnOffset(b.Line(end.line), max(0, end.col-1))
+	case r.kind != rangeLinewise && end.col > 0:
+		// The end of charwise selections is exclusive
+		end.col--
+	}
+
+	m.buffers[m.currBuffer] = b.SetMark('<', start).SetMark('>', end)
+	return m
+}
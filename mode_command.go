@@ -4,6 +4,8 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"slices"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 type command interface {
@@ -11,32 +13,251 @@ type command interface {
 	Update(m model, msg tea.Msg, args []string) (model, tea.Cmd)
 }
 
+// rangeCommand is a command working on a range of lines. Without a range
+// it gets the current line and r.given is false.
+type rangeCommand interface {
+	command
+	UpdateRange(m model, msg tea.Msg, r lineRange, args []string) (model, tea.Cmd)
+}
+
+// commandPrompt returns the character shown in front of the command line.
+func (m model) commandPrompt() string {
+	switch {
+	case !m.search.prompt:
+		return ":"
+	case m.search.promptBackward:
+		return "?"
+	}
+	return "/"
+}
+
 func (m model) updateCommand(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if m.search.prompt {
+		return m.updateSearchPrompt(msg)
+	}
+
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		switch msg.Type {
-		case tea.KeyEsc:
-			m.mode = ModeNormal
-			m.commandBuffer = ""
+		case tea.KeyEsc, tea.KeyCtrlC:
+			return m.closeCommandLine(), nil
 		case tea.KeyEnter:
-			args := strings.Split(m.commandBuffer, " ")
-			cmd := strings.TrimSpace(args[0])
-			for _, c := range m.commands {
-				if slices.Contains(c.Aliases(), cmd) {
-					return c.Update(m, msg, args[1:])
-				}
+			m.history = m.history.add(m.commandBuffer)
+			m.completion = nil
+			return m.executeCommandLine(msg)
+		case tea.KeyUp, tea.KeyDown:
+			var line string
+			m.history, line = m.history.browse(m.commandBuffer, msg.Type == tea.KeyUp)
+			m.completion = nil
+			return m.setCommandLine(line), nil
+		case tea.KeyTab, tea.KeyShiftTab:
+			return m.completeCommandLine(msg.Type == tea.KeyShiftTab), nil
+		case tea.KeyBackspace, tea.KeyCtrlH:
+			if m.commandBuffer == "" {
+				return m.closeCommandLine(), nil
+			}
+		}
+
+		m.history.browsing = false
+		m.completion = nil
+		m = m.editCommandLine(msg)
+	}
+	return m, nil
+}
+
+// setCommandLine replaces the text in the command line and puts the cursor
+// at its end.
+func (m model) setCommandLine(line string) model {
+	m.commandBuffer = line
+	m.commandCursor = len(line)
+	return m
+}
+
+// closeCommandLine leaves the command line without running the command.
+func (m model) closeCommandLine() model {
+	m.mode = ModeNormal
+	m.history.browsing = false
+	m.completion = nil
+	return m.setCommandLine("")
+}
+
+// executeCommandLine runs the command typed in the command line.
+func (m model) executeCommandLine(msg tea.Msg) (tea.Model, tea.Cmd) {
+	return m.runCommandLine(m.commandBuffer, msg)
+}
+
+// runCommandLine parses the range and the name of the command in the line
+// and runs it.
+func (m model) runCommandLine(line string, msg tea.Msg) (model, tea.Cmd) {
+	r, rest, hasRange, err := m.parseRange(line)
+	if err != nil {
+		m.commandBuffer = ""
+		m.mode = ModeNormal
+		return m.SetErrorMessage(err.Error()), nil
+	}
+
+	name, args := splitCommand(rest)
+	if name == "" && hasRange {
+		// A range alone jumps to its last line
+		m.commandBuffer = ""
+		m.mode = ModeNormal
+		b := m.buffers[m.currBuffer]
+		m.buffers[m.currBuffer] = b.moveCursorTo(firstNonBlank(b.Line(r.end)), r.end)
+		return m, nil
+	}
+
+	for _, c := range m.commands {
+		if !slices.Contains(c.Aliases(), name) {
+			continue
+		}
+		if rc, ok := c.(rangeCommand); ok {
+			if !hasRange {
+				r = m.CurrentBuffer().currentLineRange()
 			}
+			return rc.UpdateRange(m, msg, r, args)
+		}
+		if hasRange {
 			m.commandBuffer = ""
 			m.mode = ModeNormal
-		case tea.KeyBackspace:
-			if len(m.commandBuffer) > 0 {
-				m.commandBuffer = m.commandBuffer[:len(m.commandBuffer)-1]
-			}
-		default:
-			if msg.String() != "" && len(msg.String()) == 1 {
-				m.commandBuffer += msg.String()
-			}
+			return m.SetErrorMessage("No range allowed"), nil
 		}
+		return c.Update(m, msg, args)
 	}
+
+	m.commandBuffer = ""
+	m.mode = ModeNormal
 	return m, nil
 }
+
+// splitCommand splits the command line into the command name and its
+// arguments. The name is made of letters and an optional !, arguments
+// following it directly, like in s/a/b/, are passed as one argument.
+func splitCommand(line string) (string, []string) {
+	line = strings.TrimLeft(line, " ")
+	i := 0
+	for i < len(line) && unicode.IsLetter(rune(line[i])) {
+		i++
+	}
+	if i < len(line) && line[i] == '!' {
+		i++
+	}
+	name, rest := line[:i], line[i:]
+
+	switch {
+	case rest == "":
+		return name, []string{}
+	case strings.HasPrefix(rest, " "):
+		return name, strings.Split(rest[1:], " ")
+	}
+	return name, []string{rest}
+}
+
+// updateSearchPrompt handles the command line while a search pattern is
+// typed. The cursor follows the matches as the pattern changes.
+func (m model) updateSearchPrompt(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	switch keyMsg.Type {
+	case tea.KeyEsc, tea.KeyCtrlC:
+		return m.cancelSearch(), nil
+	case tea.KeyEnter:
+		return m.finishSearch()
+	case tea.KeyBackspace, tea.KeyCtrlH:
+		if m.commandBuffer == "" {
+			return m.cancelSearch(), nil
+		}
+	}
+
+	return m.editCommandLine(keyMsg).previewSearch(), nil
+}
+
+// editCommandLine applies keys editing the text in the command line.
+func (m model) editCommandLine(msg tea.KeyMsg) model {
+	line := m.commandBuffer
+	cur := min(m.commandCursor, len(line))
+
+	switch msg.Type {
+	case tea.KeyLeft:
+		_, size := utf8.DecodeLastRuneInString(line[:cur])
+		cur -= size
+	case tea.KeyRight:
+		_, size := utf8.DecodeRuneInString(line[cur:])
+		cur += size
+	case tea.KeyHome, tea.KeyCtrlA:
+		cur = 0
+	case tea.KeyEnd, tea.KeyCtrlE:
+		cur = len(line)
+	case tea.KeyBackspace, tea.KeyCtrlH:
+		_, size := utf8.DecodeLastRuneInString(line[:cur])
+		line = line[:cur-size] + line[cur:]
+		cur -= size
+	case tea.KeyDelete:
+		_, size := utf8.DecodeRuneInString(line[cur:])
+		line = line[:cur] + line[cur+size:]
+	case tea.KeyCtrlW:
+		start := wordStartBefore(line, cur)
+		line = line[:start] + line[cur:]
+		cur = start
+	case tea.KeyCtrlU:
+		line = line[cur:]
+		cur = 0
+	case tea.KeySpace:
+		line = line[:cur] + " " + line[cur:]
+		cur++
+	case tea.KeyRunes:
+		if msg.Alt {
+			break
+		}
+		text := string(msg.Runes)
+		line = line[:cur] + text + line[cur:]
+		cur += len(text)
+	}
+
+	m.commandBuffer = line
+	m.commandCursor = cur
+	return m
+}
+
+// wordStartBefore returns where the word before the cursor starts, skipping
+// spaces in front of the cursor. Ctrl-w deletes from there.
+func wordStartBefore(line string, cur int) int {
+	isWord := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' }
+
+	for cur > 0 {
+		r, size := utf8.DecodeLastRuneInString(line[:cur])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		cur -= size
+	}
+	if cur == 0 {
+		return 0
+	}
+
+	r, _ := utf8.DecodeLastRuneInString(line[:cur])
+	word := isWord(r)
+	for cur > 0 {
+		r, size := utf8.DecodeLastRuneInString(line[:cur])
+		if unicode.IsSpace(r) || isWord(r) != word {
+			break
+		}
+		cur -= size
+	}
+	return cur
+}
+
+// commandLineView renders the command line with the cursor.
+func (m model) commandLineView() string {
+	line := m.commandBuffer
+	cur := min(m.commandCursor, len(line))
+
+	char, size := " ", 0
+	if cur < len(line) {
+		_, size = utf8.DecodeRuneInString(line[cur:])
+		char = line[cur : cur+size]
+	}
+	return m.commandPrompt() + line[:cur] + m.style.cursor.Render(char) + line[cur+size:]
+}
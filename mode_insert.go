@@ -8,10 +8,16 @@ func (m model) updateInsert(msg tea.Msg) (tea.Model, tea.Cmd) {
 	buff := m.CurrentBuffer()
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m, ok := m.updateCompletionKeys(msg); ok {
+			return m, nil
+		}
 		switch msg.String() {
 		case "esc", "alt+esc":
 			m.mode = ModeNormal
 			m.commandBuffer = ""
+			buff = m.repeatBlockInsert(buff).commitUndoStep()
+			m.blockInsert = nil
+			m = m.setLastInsert(m.lastInsert)
 		case "enter":
 			// Split the current line at cursor position
 			currentLine := buff.Line(buff.CursorY())
@@ -34,6 +40,7 @@ func (m model) updateInsert(msg tea.Msg) (tea.Model, tea.Cmd) {
 			// Move cursor to beginning of new line
 			buff = buff.IncreaseCursorY(1)
 			buff = buff.SetCursorX(0)
+			m.lastInsert += "\n"
 		case "left":
 			buff = buff.IncreaseCursorX(-1)
 		case "right":
@@ -57,6 +64,7 @@ func (m model) updateInsert(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 				
 				if cursorX > 0 {
+					m.lastInsert = dropLastRune(m.lastInsert)
 					line = line[:cursorX-1] + line[cursorX:]
 					buff = buff.IncreaseCursorX(-1)
 					buff = buff.ReplaceLine(buff.CursorY(), line)
@@ -76,6 +84,7 @@ func (m model) updateInsert(msg tea.Msg) (tea.Model, tea.Cmd) {
 				
 				// Delete the current line (now that content has been moved)
 				buff = buff.DeleteLine(buff.CursorY() + 1)
+				m.lastInsert = dropLastRune(m.lastInsert)
 			}
 		default:
 			s := msg.String()
@@ -89,6 +98,7 @@ func (m model) updateInsert(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 			buff = buff.SetStateModified()
+			m.lastInsert += s
 			cursorX := buff.CursorX()
 			line := buff.Line(buff.CursorY())
 			
@@ -112,3 +122,11 @@ func (m model) updateInsert(msg tea.Msg) (tea.Model, tea.Cmd) {
 	m.buffers[m.currBuffer] = buff
 	return m, nil
 }
+
+func dropLastRune(s string) string {
+	r := []rune(s)
+	if len(r) == 0 {
+		return s
+	}
+	return string(r[:len(r)-1])
+}
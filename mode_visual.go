@@ -0,0 +1,142 @@
+package main
+
+import (
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func isVisualMode(mode editorMode) bool {
+	return mode == ModeVisual || mode == ModeVisualLine || mode == ModeVisualBlock
+}
+
+// EnterVisualMode starts a selection at the cursor.
+func (m model) EnterVisualMode(mode editorMode) model {
+	b := m.CurrentBuffer()
+	m.visualAnchor = position{line: b.cursorY, col: b.cursorX}
+	m.mode = mode
+	return m
+}
+
+// SelectRange changes the visual selection to the range. Linewise ranges
+// switch from charwise to linewise visual mode.
+func (m model) SelectRange(r textRange) model {
+	b := m.CurrentBuffer()
+	if r.kind == rangeLinewise {
+		if m.mode == ModeVisual {
+			m.mode = ModeVisualLine
+		}
+		m.visualAnchor = position{line: r.start.line}
+		m.buffers[m.currBuffer] = b.moveCursorTo(0, r.end.line)
+		return m
+	}
+
+	// The cursor is on the last selected character
+	end := r.end
+	if end.col > 0 && end != r.start {
+		end.col--
+	}
+	m.visualAnchor = r.start
+	m.buffers[m.currBuffer] = b.moveCursorTo(end.col, end.line)
+	return m
+}
+
+// Selection returns the range selected in visual mode.
+func (m model) Selection() textRange {
+	b := m.CurrentBuffer()
+	start := m.visualAnchor
+	end := position{line: b.cursorY, col: b.cursorX}
+	if end.before(start) {
+		start, end = end, start
+	}
+
+	switch m.mode {
+	case ModeVisualLine:
+		return textRange{start: start, end: end, kind: rangeLinewise}
+	case ModeVisualBlock:
+		// The block covers the screen columns of the characters at both
+		// corners
+		anchorLeft, anchorRight := b.screenColumns(m.visualAnchor)
+		cursorLeft, cursorRight := b.screenColumns(position{line: b.cursorY, col: b.cursorX})
+		return textRange{
+			start: position{line: start.line, col: min(anchorLeft, cursorLeft)},
+			end:   position{line: end.line, col: max(anchorRight, cursorRight)},
+			kind:  rangeBlockwise,
+		}
+	}
+
+	// The character under the cursor is part of the selection
+	end.col++
+	return textRange{start: start, end: end, kind: rangeCharwise}
+}
+
+func (m model) updateVisual(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	modes := map[string]editorMode{
+		"v":      ModeVisual,
+		"V":      ModeVisualLine,
+		"ctrl+v": ModeVisualBlock,
+	}
+
+	// The marks always hold the current selection, so they are right
+	// whichever key ends visual mode
+	m = m.setVisualMarks()
+
+	key := keyMsg.String()
+	switch key {
+	case ":":
+		// Run the command on the selected lines
+		m.mode = ModeCommand
+		return m.setCommandLine("'<,'>"), nil
+	case "esc", "ctrl+c":
+		m.mode = ModeNormal
+		m.normalmode.buffer = ""
+		return m, nil
+	case "v", "V", "ctrl+v":
+		if m.mode == modes[key] {
+			m.mode = ModeNormal
+		} else {
+			m.mode = modes[key]
+		}
+		return m, nil
+	case "o":
+		// Move the cursor to the other end of the selection
+		b := m.CurrentBuffer()
+		anchor := m.visualAnchor
+		m.visualAnchor = position{line: b.cursorY, col: b.cursorX}
+		m.buffers[m.currBuffer] = b.moveCursorTo(anchor.col, anchor.line)
+		return m, nil
+	case "d", "x", "y", "c", ">", "<", "~":
+		op := key
+		if op == "x" {
+			op = "d"
+		}
+		r := m.Selection()
+		reg := m.normalmode.register
+		m.normalmode.register = 0
+		m.mode = ModeNormal
+		return m.applyOperator(op, r, reg)
+	}
+
+	nm, mod, cmd := m.normalmode.HandleMotion(keyMsg, m)
+	m = mod.(model)
+	m.normalmode = nm
+
+	return m, cmd
+}
+
+// screenColumns returns the screen columns taken by the character at the
+// position. The end of the line takes one column.
+func (b buffer) screenColumns(p position) (int, int) {
+	line := b.Line(p.line)
+	start := displayColumn(line, p.col)
+	if p.col >= len(line) {
+		return start, start + 1
+	}
+	r, _ := utf8.DecodeRuneInString(line[p.col:])
+	return start, start + max(1, runeColumns(r, start))
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func visualTestModel(content string) model {
+	m := initialModel()
+	m.viewport = tea.WindowSizeMsg{Width: 80, Height: 24}
+	m.buffers[0] = newBuffer(m.style, bufferWithContent("", content))
+	m.buffers[0].viewport = m.viewport
+	return m
+}
+
+func TestVisualDeleteCharwise(t *testing.T) {
+	m := visualTestModel("hello world\nsecond line")
+
+	m = pressKeys(m, runeKeys("lvjd")...)
+	if m.mode != ModeNormal {
+		t.Errorf("Expected normal mode after d, got %s", m.mode)
+	}
+	if got := m.CurrentBuffer().Lines(); !slices.Equal(got, []string{"hcond line"}) {
+		t.Errorf("Expected [hcond line], got %v", got)
+	}
+
+	r, _ := m.Register('1')
+	if r.linewise || !slices.Equal(r.lines, []string{"ello world", "se"}) {
+		t.Errorf("Expected charwise register with the selection, got %v", r)
+	}
+}
+
+func TestVisualLineYankAndIndent(t *testing.T) {
+	m := visualTestModel("a\nb\nc")
+
+	m = pressKeys(m, runeKeys("Vjy")...)
+	r, _ := m.Register('0')
+	if !r.linewise || !slices.Equal(r.lines, []string{"a", "b"}) {
+		t.Errorf("Expected linewise register [a b], got %v", r)
+	}
+
+	m = pressKeys(m, runeKeys("Vj>")...)
+	if got := m.CurrentBuffer().Lines(); !slices.Equal(got, []string{"\ta", "\tb", "c"}) {
+		t.Errorf("Expected first two lines indented, got %q", got)
+	}
+
+	m = pressKeys(m, runeKeys("V<")...)
+	if got := m.CurrentBuffer().Line(0); got != "a" {
+		t.Errorf("Expected first line unindented, got %q", got)
+	}
+}
+
+func TestVisualBlockToggleCase(t *testing.T) {
+	m := visualTestModel("abcd\nefgh\nijkl")
+
+	m = pressKeys(m, runeKeys("l")...)
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyCtrlV})
+	if m.mode != ModeVisualBlock {
+		t.Fatalf("Expected visual block mode, got %s", m.mode)
+	}
+	m = pressKeys(m, runeKeys("jl~")...)
+
+	if got := m.CurrentBuffer().Lines(); !slices.Equal(got, []string{"aBCd", "eFGh", "ijkl"}) {
+		t.Errorf("Expected [aBCd eFGh ijkl], got %v", got)
+	}
+}
+
+func TestVisualChangeIsOneUndoStep(t *testing.T) {
+	m := visualTestModel("one two")
+
+	m = pressKeys(m, runeKeys("vllcxyz")...)
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEsc})
+	if got := m.CurrentBuffer().Line(0); got != "xyz two" {
+		t.Fatalf("Expected 'xyz two', got %q", got)
+	}
+
+	m = pressKeys(m, runeKeys("u")...)
+	if got := m.CurrentBuffer().Line(0); got != "one two" {
+		t.Errorf("Expected change to be undone, got %q", got)
+	}
+}
+
+func TestVisualModeView(t *testing.T) {
+	m := visualTestModel("hello")
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("V")})
+
+	if !strings.Contains(m.View(), "VISUAL LINE") {
+		t.Error("Expected mode label in the status bar")
+	}
+	if !strings.Contains(m.CurrentBuffer().View(), "hello") {
+		t.Error("Expected buffer content to be rendered")
+	}
+}
+
+func TestVisualBlockChange(t *testing.T) {
+	m := visualTestModel("abcd\nefgh\nij\nklmn")
+
+	m = pressKeys(m, runeKeys("l")...)
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyCtrlV})
+	m = pressKeys(m, runeKeys("jjjlcXY")...)
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEsc})
+
+	// The short line reaching into the block is changed too
+	want := []string{"aXYd", "eXYh", "iXY", "kXYn"}
+	if got := m.CurrentBuffer().Lines(); !slices.Equal(got, want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	m = pressKeys(m, runeKeys("u")...)
+	if got := m.CurrentBuffer().Lines(); !slices.Equal(got, []string{"abcd", "efgh", "ij", "klmn"}) {
+		t.Errorf("Expected the change to be undone at once, got %q", got)
+	}
+}
+
+func TestVisualBlockMultiByte(t *testing.T) {
+	m := visualTestModel("héllo\nabcde")
+
+	// The block covers the same screen columns on both lines
+	m = pressKeys(m, runeKeys("l")...)
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyCtrlV})
+	m = pressKeys(m, runeKeys("jld")...)
+	if got := m.CurrentBuffer().Lines(); !slices.Equal(got, []string{"hlo", "ade"}) {
+		t.Errorf("Expected [hlo ade], got %q", got)
+	}
+}
@@ -14,6 +14,12 @@ type editorMode string
 const ModeNormal editorMode = "normal"
 const ModeInsert editorMode = "insert"
 const ModeCommand editorMode = "command"
+const ModeList editorMode = "list"
+const ModeVisual editorMode = "visual"
+const ModeVisualLine editorMode = "visual line"
+const ModeVisualBlock editorMode = "visual block"
+const ModeConfirm editorMode = "confirm"
+const ModePicker editorMode = "picker"
 
 type messageType string
 
@@ -27,6 +33,8 @@ type message struct {
 
 type toolInfo struct {
 	Name       string
+	// Args are given to the tool when it's run
+	Args       []string
 	IsInstalled bool
 }
 
@@ -41,12 +49,61 @@ type model struct {
 	mode           editorMode
 	normalmode     *normalmode
 	commandBuffer  string // Buffer for command mode input
+	// commandCursor is the byte offset of the cursor in commandBuffer
+	commandCursor int
+	// history holds the executed command lines
+	history commandHistory
+	// completion is the tab completion in progress in the command line
+	completion *completionState
 	commands       []command
 	viewport       tea.WindowSizeMsg
 	currentMessage *message
+	list           *listPopup
+	// picker is the fuzzy file finder shown in picker mode
+	picker *filePicker
+	// grep is the last :grep, its results are shown in the list
+	grep *grepSearch
+	// lsp talks to the language servers, there are none without it
+	lsp *lspManager
+	// popup is the documentation shown over the buffer
+	popup *popup
+	// insertCompletion is the completion menu shown in insert mode
+	insertCompletion *completionMenu
+	// completionRequests counts the completion requests to drop stale answers
+	completionRequests int
+	registers      registers
+	clipboard      clipboard
+	// clipboardCopies are the texts the last update stored in the clipboard
+	// registers, they're copied after it
+	clipboardCopies []clipboardCopy
+	// clipboardSequence is the OSC 52 sequence sent with the frames after a
+	// copy, so it reaches the terminal through the renderer
+	clipboardSequence string
+	// lastInsert collects the text typed in the current insert session
+	lastInsert string
+	// blockInsert is the block changed with c until insert mode is left
+	blockInsert *blockInsert
+	// visualAnchor is the end of the visual selection opposite to the cursor
+	visualAnchor position
+	search       searchState
+	// searchCache is shared by the copies of the model, View fills it
+	searchCache *searchCache
+	// substitute is the :s command waiting for confirmation of a match
+	substitute *substituteState
+	// expandHistory holds the selections replaced by expanding to a syntax
+	// node
+	expandHistory []expandStep
+	// inGlobal is set while :g runs its command on the matching lines
+	inGlobal bool
 
 	buffers    []buffer
 	currBuffer int
+	// windows are the splits of the screen, each showing one of the buffers
+	windows windowSet
+	// tabs hold the windows of every tab page. The current one is shown
+	// from windows, its entry is updated when switching to another one.
+	tabs    []windowSet
+	currTab int
 
 	style editorStyle
 
@@ -115,12 +172,44 @@ func initialModel(opts ...modelOption) model {
 			&commandBufferPrev{},
 			&commandBufferLast{},
 			&commandBufferFirst{},
+			&commandBufferSwitch{},
+			&commandBufferList{},
+			&commandBufferDelete{},
+			&commandBufferDelete{force: true},
+			&commandReload{},
+			&commandUndo{},
+			&commandRedo{},
+			&commandEarlier{},
+			&commandLater{},
+			&commandUndoTree{},
+			&commandRegisters{},
+			&commandNoHighlight{},
+			&commandSubstitute{},
+			&commandDelete{},
+			&commandYank{},
+			&commandMove{},
+			&commandCopy{},
+			&commandNormal{},
+			&commandGlobal{},
+			&commandGlobal{invert: true},
+			&commandSplit{},
+			&commandSplit{vertical: true},
+			&commandClose{},
+			&commandOnly{},
+			&commandTabNew{},
+			&commandTabNext{},
+			&commandTabPrev{},
+			&commandTabClose{},
+			&commandGrep{},
+			&commandDiagnostics{},
+			&commandRename{},
 		},
 		style: s,
 
 		buffers: []buffer{
 			newBuffer(s),
 		},
+		searchCache: &searchCache{},
 
 		Languages: make(map[string]languageSupport),
 	}
@@ -134,13 +223,13 @@ func initialModel(opts ...modelOption) model {
 	}
 	m.Languages["py"] = languageSupport{
 		Name: "Python",
-		LSPServer: toolInfo{Name: "pyright", IsInstalled: false},
+		LSPServer: toolInfo{Name: "pyright-langserver", Args: []string{"--stdio"}, IsInstalled: false},
 		Formatter: toolInfo{Name: "black", IsInstalled: false},
 		Highlighting: toolInfo{Name: "builtin-python", IsInstalled: true},
 	}
 	m.Languages["js"] = languageSupport{
 		Name: "JavaScript",
-		LSPServer: toolInfo{Name: "typescript-language-server", IsInstalled: false},
+		LSPServer: toolInfo{Name: "typescript-language-server", Args: []string{"--stdio"}, IsInstalled: false},
 		Formatter: toolInfo{Name: "prettier", IsInstalled: false},
 		Highlighting: toolInfo{Name: "builtin-javascript", IsInstalled: true},
 	}
@@ -169,13 +258,21 @@ func initialModel(opts ...modelOption) model {
 }
 
 func (m model) Init() tea.Cmd {
-	return nil
+	return m.lsp.listen()
 }
 
 func (m model) CurrentBuffer() buffer {
 	return m.buffers[m.currBuffer]
 }
 
+// Clipboard returns the system clipboard unless another one was set.
+func (m model) Clipboard() clipboard {
+	if m.clipboard == nil {
+		return newSystemClipboard()
+	}
+	return m.clipboard
+}
+
 func (m model) EnterCommandMode() model {
 	m.mode = ModeCommand
 	return m
@@ -183,6 +280,8 @@ func (m model) EnterCommandMode() model {
 
 func (m model) EnterInsertMode() model {
 	m.mode = ModeInsert
+	m.lastInsert = ""
+	m.blockInsert = nil
 	return m
 }
 
@@ -207,12 +306,34 @@ func (m model) ClearMessage() model {
 }
 
 func (m model) addBuffer(b buffer) model {
-	b.viewport = m.viewport
+	b.viewport = m.windowSize()
 	m.buffers = append(m.buffers, b)
 	return m
 }
 
+// Update handles the message and tells the language servers about the
+// changes it made to the buffers. After a key the diagnostic under the
+// cursor is shown.
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	res, cmd := m.update(msg)
+	m, ok := res.(model)
+	if !ok {
+		return res, cmd
+	}
+
+	m.lsp.sync(m)
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		m = m.updatePopup(msg).updateCompletion(msg).showCursorDiagnostic()
+	}
+	if len(m.clipboardCopies) > 0 {
+		var copyCmd tea.Cmd
+		m, copyCmd = m.copyToClipboard()
+		cmd = tea.Batch(cmd, copyCmd)
+	}
+	return m, cmd
+}
+
+func (m model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	// Clear the message on any key event (except window resize)
 	if m.currentMessage != nil {
 		if _, ok := msg.(tea.KeyMsg); ok {
@@ -223,8 +344,15 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.viewport = msg
-		m.buffers[m.currBuffer].viewport = msg
-		return m, nil
+		return m.resizeWindows(), nil
+	case fileIndexMsg:
+		return m.updateFileIndex(msg)
+	case grepResultMsg:
+		return m.updateGrepResults(msg)
+	case lspServerMsg, lspErrorMsg, lspResponseMsg:
+		return m.updateLSP(msg)
+	case clipboardErrorMsg, clipboardSequenceMsg, clipboardPasteMsg:
+		return m.updateClipboard(msg)
 	}
 
 	switch m.mode {
@@ -234,18 +362,39 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.updateInsert(msg)
 	case ModeCommand:
 		return m.updateCommand(msg)
+	case ModeList:
+		return m.updateList(msg)
+	case ModeVisual, ModeVisualLine, ModeVisualBlock:
+		return m.updateVisual(msg)
+	case ModeConfirm:
+		return m.updateConfirm(msg)
+	case ModePicker:
+		return m.updatePicker(msg)
 	}
 	return m, nil
 }
 
 func (m model) View() string {
 	// Get the buffer content
-	bufferContent := m.buffers[m.currBuffer].View()
+	buf := m.searchHighlights(m.buffers[m.currBuffer])
+	searchCounter := m.searchCounter(buf)
+	if isVisualMode(m.mode) {
+		buf.highlights = append(buf.highlights, highlight{textRange: m.Selection(), style: m.style.visual})
+	}
+	if m.mode == ModeConfirm && m.substitute != nil {
+		buf.highlights = append(buf.highlights, highlight{textRange: m.substitute.currentMatch(), style: m.style.searchCurrent})
+	}
+	bufferContent := buf.View()
+	if m.hasSplits() {
+		bufferContent = m.windowsView()
+	}
 	
 	// Build the status bar content
 	var statusBarContent string
 	if m.mode == ModeCommand {
-		statusBarContent = fmt.Sprintf(":%s", m.commandBuffer)
+		statusBarContent = m.commandLineView()
+	} else if m.mode == ModeConfirm && m.substitute != nil {
+		statusBarContent = m.substitutePrompt()
 	} else {
 		buf := m.buffers[m.currBuffer]
 		f := fileNameLabel(buf.filename, buf.state)
@@ -258,7 +407,10 @@ func (m model) View() string {
 		}
 		
 		posInfo := filePossitionInfo(buf.cursorY+1, buf.cursorX+1)
-		width := m.CurrentBuffer().Viewport().Width
+		if searchCounter != "" {
+			posInfo = searchCounter + " " + posInfo
+		}
+		width := m.viewport.Width
 
 		pad := width - len(buff) - len(posInfo)
 		if pad < 1 {
@@ -287,9 +439,12 @@ func (m model) View() string {
 
 		messageContent = messageStyle.Render(messageText)
 	}
+	if m.mode == ModeCommand && m.completion != nil && len(m.completion.candidates) > 1 {
+		messageContent = m.completion.View(m.style, m.viewport.Width)
+	}
 
 	// Calculate available height for content (viewport height minus status bar and message)
-	availableHeight := m.viewport.Height
+	availableHeight := m.viewport.Height - m.tablineHeight()
 	if messageContent != "" {
 		availableHeight -= 1 // Message takes one line
 	}
@@ -300,6 +455,13 @@ func (m model) View() string {
 		availableHeight = 1
 	}
 
+	if m.mode == ModeList && m.list != nil {
+		bufferContent = m.list.View(m.style, m.viewport.Width, availableHeight)
+	}
+	if m.mode == ModePicker && m.picker != nil {
+		bufferContent = m.picker.View(m.style, m.viewport.Width, availableHeight)
+	}
+
 	// Split buffer content into lines and ensure it fits within available height
 	bufferLines := strings.Split(bufferContent, "\n")
 	if len(bufferLines) > availableHeight {
@@ -311,11 +473,22 @@ func (m model) View() string {
 		bufferLines = append(bufferLines, "")
 	}
 
+	if m.popup != nil && (m.mode == ModeNormal || m.mode == ModeInsert) && (m.insertCompletion == nil || len(m.insertCompletion.matches) == 0) {
+		x, y := m.cursorScreenPosition()
+		bufferLines = overlay(bufferLines, m.popup.box(m.style, m.viewport.Width), x, y, m.viewport.Width)
+	}
+	bufferLines = m.completionView(bufferLines)
+
 	// Join the content lines
 	content := strings.Join(bufferLines, "\n")
 
 	// Build the final layout
 	var result strings.Builder
+	result.WriteString(m.clipboardSequence)
+	if m.tablineHeight() > 0 {
+		result.WriteString(m.tablineView())
+		result.WriteRune('\n')
+	}
 	result.WriteString(content)
 	
 	if messageContent != "" {
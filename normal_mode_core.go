@@ -12,11 +12,24 @@ type normalmode struct {
 	buffer      string
 	lastCommand string
 	repeatableCommands map[string]bool
+	// motions only move the cursor, they can be used in visual mode
+	motions map[string]bool
+	// register selected with the " prefix for the next command
+	register rune
+	// motionFuncs are the motions operators can be combined with
+	motionFuncs map[string]motion
 }
 
 func NewNormalMode() *normalmode {
 	nm := &normalmode{}
 	nm.setupCommands()
+	nm.setupMotions()
+	nm.setupSyntaxMotions()
+	nm.setupMarks()
+	nm.setupWindows()
+	nm.setupTabs()
+	nm.setupDiagnostics()
+	nm.setupLSP()
 	return nm
 }
 
@@ -24,6 +37,11 @@ func (nm *normalmode) registerCmd(key string, cmd normalCommand) {
 	nm.commands[key] = cmd
 }
 
+func (nm *normalmode) registerMotion(key string, cmd normalCommand) {
+	nm.commands[key] = cmd
+	nm.motions[key] = true
+}
+
 func (nm *normalmode) registerRepeatableCmd(key string, cmd normalCommand) {
 	nm.commands[key] = cmd
 	nm.repeatableCommands[key] = true
@@ -32,34 +50,58 @@ func (nm *normalmode) registerRepeatableCmd(key string, cmd normalCommand) {
 func (nm *normalmode) setupCommands() {
 	nm.commands = make(map[string]normalCommand)
 	nm.repeatableCommands = make(map[string]bool)
+	nm.motions = make(map[string]bool)
 	
 	// Navigation commands
-	nm.registerCmd("j", nm.commandDown)
-	nm.registerCmd("down", nm.commandDown)
-	nm.registerCmd("k", nm.commandUp)
-	nm.registerCmd("up", nm.commandUp)
-	nm.registerCmd("h", nm.commandLeft)
-	nm.registerCmd("left", nm.commandLeft)
-	nm.registerCmd("l", nm.commandRight)
-	nm.registerCmd("right", nm.commandRight)
-	nm.registerCmd("w", nm.commandNextWord)
-	nm.registerCmd("b", nm.commandPrevWord)
+	nm.registerMotion("j", nm.commandDown)
+	nm.registerMotion("down", nm.commandDown)
+	nm.registerMotion("k", nm.commandUp)
+	nm.registerMotion("up", nm.commandUp)
+	nm.registerMotion("h", nm.commandLeft)
+	nm.registerMotion("left", nm.commandLeft)
+	nm.registerMotion("l", nm.commandRight)
+	nm.registerMotion("right", nm.commandRight)
+	nm.registerMotion("w", nm.commandNextWord)
+	nm.registerMotion("b", nm.commandPrevWord)
 	
 	// File navigation
-	nm.registerCmd("gg", nm.commandGoToBeginingOfTheFile)
-	nm.registerCmd("ge", nm.commandGoToEndOfTheFile)
-	nm.registerCmd("gl", nm.commandGoToLast)
-	nm.registerCmd("gs", nm.commandGoToFirstNonWhiteCharacter)
+	nm.registerMotion("gg", nm.commandGoToBeginingOfTheFile)
+	nm.registerMotion("ge", nm.commandGoToEndOfTheFile)
+	nm.registerMotion("gl", nm.commandGoToLast)
+	nm.registerMotion("gs", nm.commandGoToFirstNonWhiteCharacter)
+	nm.registerMotion("$", nm.commandGoToLast)
+	nm.registerMotion("0", nm.commandGoToLineStart)
 	
-	// Editing commands
-	nm.registerRepeatableCmd("dd", nm.commandDeleteLine)
+	// Editing commands, dd and yy are handled by the operators
 	nm.registerRepeatableCmd("o", nm.commandOpenLineBelow)
 	nm.registerRepeatableCmd("O", nm.commandOpenLineAbove)
+	nm.registerRepeatableCmd("p", nm.commandPutAfter)
+	nm.registerRepeatableCmd("P", nm.commandPutBefore)
+
+	// Search
+	nm.registerCmd("/", nm.commandSearchForward)
+	nm.registerCmd("?", nm.commandSearchBackward)
+	nm.registerMotion("n", nm.commandSearchNext)
+	nm.registerMotion("N", nm.commandSearchPrevious)
+	nm.registerMotion("*", nm.commandSearchWordForward)
+	nm.registerMotion("#", nm.commandSearchWordBackward)
+
+	// History
+	nm.registerCmd("u", nm.commandUndo)
+	nm.registerCmd("U", nm.commandRedo)
+	nm.registerCmd("ctrl+r", nm.commandRedo)
 	
 	// Mode switching
 	nm.registerCmd("esc", nm.commandClearBuffer)
 	nm.registerCmd(":", nm.commandEnterCommandMode)
+	nm.registerCmd(" f", nm.commandOpenFilePicker)
 	nm.registerCmd("i", nm.commandEnterInsertMode)
+	nm.registerCmd("a", nm.commandAppend)
+	nm.registerCmd("A", nm.commandAppendAtLineEnd)
+	nm.registerCmd("I", nm.commandInsertAtFirstNonBlank)
+	nm.registerCmd("v", nm.commandEnterVisualMode)
+	nm.registerCmd("V", nm.commandEnterVisualLineMode)
+	nm.registerCmd("ctrl+v", nm.commandEnterVisualBlockMode)
 	
 	// Viewport commands
 	nm.registerCmd("zt", nm.commandTopViewport)
@@ -71,34 +113,49 @@ func (nm *normalmode) setupCommands() {
 }
 
 func (nm *normalmode) Handle(msg tea.KeyMsg, m model) (*normalmode, tea.Model, tea.Cmd) {
+	return nm.handle(msg, m, false)
+}
+
+// HandleMotion works like Handle but runs only motions. Visual mode uses it
+// to move the cursor.
+func (nm *normalmode) HandleMotion(msg tea.KeyMsg, m model) (*normalmode, tea.Model, tea.Cmd) {
+	return nm.handle(msg, m, true)
+}
+
+func (nm *normalmode) handle(msg tea.KeyMsg, m model, onlyMotions bool) (*normalmode, tea.Model, tea.Cmd) {
 	buff := nm.buffer + msg.String()
-	nCommand, ok := nm.commands[buff]
-	if ok {
-		m, cmd := nCommand(m, nil)
-		// Only store repeatable commands
-		if nm.repeatableCommands[buff] {
-			nm.lastCommand = buff
-		}
-		nm.buffer = ""
-		return nm, m, cmd
-	}
 
-	// let's check if there's any command that starts with the buffer
-	found := false
-	for k := range nm.commands {
-		if strings.HasPrefix(k, buff) {
-			found = true
-			break
+	// "x selects the register used by the next command
+	if strings.HasPrefix(buff, "\"") {
+		nm.buffer = ""
+		if len(buff) == 1 {
+			nm.buffer = buff
+		} else if name := []rune(buff[1:]); len(name) == 1 && isValidRegister(name[0]) {
+			nm.register = name[0]
 		}
+		return nm, m, nil
 	}
 
-	if found {
+	p, state := nm.parse(buff, onlyMotions)
+	switch state {
+	case parseIncomplete:
 		nm.buffer = buff
-	} else {
+		return nm, m, nil
+	case parseInvalid:
 		nm.buffer = ""
+		nm.register = 0
+		return nm, m, nil
 	}
 
-	return nm, m, nil
+	nm.buffer = ""
+	res, cmd := nm.run(p, m)
+	nm.register = 0
+	// Only store repeatable commands
+	if nm.isRepeatable(p) {
+		nm.lastCommand = buff
+	}
+
+	return nm, res, cmd
 }
 
 func (nm *normalmode) commandRepeat(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
@@ -107,10 +164,11 @@ func (nm *normalmode) commandRepeat(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
 		return m, cmd
 	}
 
-	// Find and execute the last command
-	if nCommand, ok := nm.commands[nm.lastCommand]; ok {
-		return nCommand(m, cmd)
+	// Parse and execute the last command again
+	p, state := nm.parse(nm.lastCommand, false)
+	if state != parseDone {
+		return m, cmd
 	}
 
-	return m, cmd
-} 
\ No newline at end of file
+	return nm.run(p, m)
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// setupDiagnostics registers ]d and [d jumping to the next and previous
+// diagnostic.
+func (nm *normalmode) setupDiagnostics() {
+	nm.motionFuncs["]d"] = motion{target: diagnosticTarget(true)}
+	nm.motionFuncs["[d"] = motion{target: diagnosticTarget(false)}
+	nm.registerMotion("]d", nm.commandJumpToDiagnostic("]d"))
+	nm.registerMotion("[d", nm.commandJumpToDiagnostic("[d"))
+}
+
+func (nm *normalmode) commandJumpToDiagnostic(key string) normalCommand {
+	move := nm.moveTo(key)
+	return func(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+		if len(m.CurrentBuffer().diagnostics) == 0 {
+			return m.SetErrorMessage("No diagnostics"), cmd
+		}
+		return move(m, cmd)
+	}
+}
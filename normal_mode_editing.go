@@ -4,18 +4,11 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 )
 
-func (nm *normalmode) commandDeleteLine(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
-	b := m.buffers[m.currBuffer]
-	b = b.DeleteLine(b.cursorY)
-	b = b.SetStateModified()
-	m.buffers[m.currBuffer] = b
-
-	return m, cmd
-}
-
 func (nm normalmode) commandOpenLineBelow(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
 	b := m.buffers[m.currBuffer]
 
+	// The undo step is committed when insert mode is left
+	b = b.beginUndoStep()
 	b = b.InsertLine(b.cursorY+1, "")
 	b = b.SetStateModified()
 
@@ -23,20 +16,41 @@ func (nm normalmode) commandOpenLineBelow(m model, cmd tea.Cmd) (tea.Model, tea.
 	b = b.SetCursorX(0)
 
 	m.buffers[m.currBuffer] = b
-	m.mode = ModeInsert
 
-	return m, cmd
+	return m.EnterInsertMode(), cmd
 }
 
 func (nm normalmode) commandOpenLineAbove(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
 	b := m.buffers[m.currBuffer]
 
+	// The undo step is committed when insert mode is left
+	b = b.beginUndoStep()
 	b = b.InsertLine(b.cursorY, "")
 	b = b.SetStateModified()
 	b = b.SetCursorX(0)
 
 	m.buffers[m.currBuffer] = b
-	m.mode = ModeInsert
 
-	return m, cmd
-} 
\ No newline at end of file
+	return m.EnterInsertMode(), cmd
+}
+
+func (nm *normalmode) commandPutAfter(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+	return nm.put(m, cmd, true)
+}
+
+func (nm *normalmode) commandPutBefore(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+	return nm.put(m, cmd, false)
+}
+
+func (nm *normalmode) put(m model, cmd tea.Cmd, after bool) (tea.Model, tea.Cmd) {
+	if isClipboardRegister(nm.register) {
+		// The clipboard is put once it's read
+		return m, tea.Batch(cmd, m.pasteFromClipboard(nm.register, after))
+	}
+
+	r, ok := m.Register(nm.register)
+	if !ok {
+		return m.SetErrorMessage("Nothing in register " + string(registerName(nm.register))), cmd
+	}
+	return m.putRegister(r, after), cmd
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// operators consume a motion and act on the text between the cursor and the
+// motion's target, e.g. d3w or gUgs.
+var operators = []string{"d", "c", "y", ">", "<", "gu", "gU"}
+
+// motion produces the range an operator works on.
+type motion struct {
+	// target returns the position the motion moves to from the cursor.
+	// count is 0 when no count was given.
+	target    func(m model, count int) position
+	linewise  bool
+	inclusive bool
+	// absolute motions use the count as a line number instead of
+	// repeating the motion, e.g. 5gg
+	absolute bool
+}
+
+type parseState int
+
+const (
+	parseIncomplete parseState = iota
+	parseDone
+	parseInvalid
+)
+
+// parsedKeys is a complete normal mode key sequence:
+//
+//	[count] command
+//	[count] operator [count] (motion | operator | text object)
+//
+// Repeating the operator (dd, >>, gUU) makes it work on count lines. In
+// visual mode a text object on its own selects the object.
+type parsedKeys struct {
+	count      int
+	command    string
+	operator   string
+	opCount    int
+	motion     string
+	linewise   bool
+	textObject string
+}
+
+// splitCount removes a leading count from the keys. 0 is a command on its
+// own unless it follows another digit.
+func splitCount(keys string) (int, string) {
+	i := 0
+	for i < len(keys) && keys[i] >= '0' && keys[i] <= '9' {
+		if i == 0 && keys[i] == '0' {
+			break
+		}
+		i++
+	}
+	if i == 0 {
+		return 0, keys
+	}
+	n, _ := strconv.Atoi(keys[:i])
+	return n, keys[i:]
+}
+
+// parse reads the pending keys. With onlyMotions operators and commands
+// other than motions are rejected, as needed in visual mode.
+func (nm *normalmode) parse(keys string, onlyMotions bool) (parsedKeys, parseState) {
+	var p parsedKeys
+	p.count, keys = splitCount(keys)
+	if keys == "" {
+		return p, parseIncomplete
+	}
+
+	// Commands go first, keys like ctrl+r start with an operator
+	if _, ok := nm.commands[keys]; ok && (!onlyMotions || nm.motions[keys]) {
+		p.command = keys
+		return p, parseDone
+	}
+
+	if onlyMotions {
+		if state := parseTextObject(keys); state != parseInvalid {
+			p.textObject = keys
+			return p, state
+		}
+	} else if !isModifiedKey(keys) {
+		for _, op := range operators {
+			if !strings.HasPrefix(keys, op) {
+				continue
+			}
+			p.operator = op
+			return nm.parseOperand(p, keys[len(op):])
+		}
+	}
+
+	for k := range nm.commands {
+		if onlyMotions && !nm.motions[k] {
+			continue
+		}
+		if strings.HasPrefix(k, keys) {
+			return p, parseIncomplete
+		}
+	}
+	for _, op := range operators {
+		if !onlyMotions && !isModifiedKey(keys) && strings.HasPrefix(op, keys) {
+			return p, parseIncomplete
+		}
+	}
+
+	return p, parseInvalid
+}
+
+// isModifiedKey tells if the keys start with a key pressed with ctrl or alt.
+// Their names start with the letters of the c operator but they aren't one.
+func isModifiedKey(keys string) bool {
+	return strings.HasPrefix(keys, "ctrl+") || strings.HasPrefix(keys, "alt+")
+}
+
+// parseOperand reads what follows an operator.
+func (nm *normalmode) parseOperand(p parsedKeys, keys string) (parsedKeys, parseState) {
+	p.opCount, keys = splitCount(keys)
+	if keys == "" {
+		return p, parseIncomplete
+	}
+
+	// dd, >>, gUU and gUgU work on whole lines
+	repeats := []string{p.operator, p.operator[len(p.operator)-1:]}
+	for _, r := range repeats {
+		if keys == r {
+			p.linewise = true
+			return p, parseDone
+		}
+	}
+
+	if _, ok := nm.motionFuncs[keys]; ok {
+		p.motion = keys
+		return p, parseDone
+	}
+
+	if state := parseTextObject(keys); state != parseInvalid {
+		p.textObject = keys
+		return p, state
+	}
+
+	for k := range nm.motionFuncs {
+		if strings.HasPrefix(k, keys) {
+			return p, parseIncomplete
+		}
+	}
+	for _, r := range repeats {
+		if strings.HasPrefix(r, keys) {
+			return p, parseIncomplete
+		}
+	}
+
+	return p, parseInvalid
+}
+
+// parseTextObject checks if the keys are a text object, like af or i/.
+func parseTextObject(keys string) parseState {
+	if keys == "" || (keys[0] != 'i' && keys[0] != 'a') {
+		return parseInvalid
+	}
+	if len(keys) == 1 {
+		return parseIncomplete
+	}
+	if key := []rune(keys[1:]); len(key) == 1 && isTextObject(key[0]) {
+		return parseDone
+	}
+	return parseInvalid
+}
+
+// isRepeatable reports whether . should repeat the keys.
+func (nm *normalmode) isRepeatable(p parsedKeys) bool {
+	if p.operator != "" {
+		return p.operator != "y"
+	}
+	return nm.repeatableCommands[p.command]
+}
+
+// run executes the parsed keys.
+func (nm *normalmode) run(p parsedKeys, m model) (tea.Model, tea.Cmd) {
+	if p.textObject != "" {
+		r, ok := m.CurrentBuffer().TextObject(rune(p.textObject[1]), p.textObject[0] == 'i', p.totalCount())
+		if !ok {
+			return m, nil
+		}
+		if p.operator == "" {
+			return m.SelectRange(r), nil
+		}
+		return m.applyOperator(p.operator, r, nm.register)
+	}
+
+	if p.operator != "" {
+		return m.applyOperator(p.operator, nm.operatorRange(p, m), nm.register)
+	}
+
+	if mo, ok := nm.motionFuncs[p.command]; ok && mo.absolute && p.count > 0 {
+		target := mo.target(m, p.count)
+		b := m.buffers[m.currBuffer]
+		m.buffers[m.currBuffer] = b.moveCursorTo(firstNonBlank(b.Line(target.line)), target.line)
+		return m, nil
+	}
+
+	// Commands are repeated count times, unless they switch to another mode
+	mode := m.mode
+	var res tea.Model = m
+	var cmd tea.Cmd
+	for range max(1, p.count) {
+		res, cmd = nm.commands[p.command](res.(model), cmd)
+		if res.(model).mode != mode {
+			break
+		}
+	}
+
+	return res, cmd
+}
+
+// totalCount multiplies the counts before and after the operator. It's 0
+// when no count was given.
+func (p parsedKeys) totalCount() int {
+	if p.count == 0 && p.opCount == 0 {
+		return 0
+	}
+	return max(1, p.count) * max(1, p.opCount)
+}
+
+// operatorRange returns the range between the cursor and the target of the
+// motion. The counts before and after the operator are multiplied.
+func (nm *normalmode) operatorRange(p parsedKeys, m model) textRange {
+	b := m.CurrentBuffer()
+	cur := position{line: b.cursorY, col: b.cursorX}
+
+	count := p.totalCount()
+
+	if p.linewise {
+		end := min(cur.line+max(1, count)-1, b.NoOfLines()-1)
+		return textRange{start: cur, end: position{line: end}, kind: rangeLinewise}
+	}
+
+	mo := nm.motionFuncs[p.motion]
+	start, end := cur, mo.target(m, count)
+	if end.before(start) {
+		start, end = end, start
+	}
+
+	if mo.linewise {
+		return textRange{start: start, end: end, kind: rangeLinewise}
+	}
+
+	if mo.inclusive {
+		end.col++
+	}
+
+	// An exclusive motion ending at the start of a line doesn't include the
+	// line break before it. If it also starts before the first non-blank
+	// character, it works on whole lines.
+	if end.line > start.line && end.col == 0 {
+		if start.col <= firstNonBlank(b.Line(start.line)) {
+			return textRange{start: start, end: position{line: end.line - 1}, kind: rangeLinewise}
+		}
+		end = position{line: end.line - 1, col: len(b.Line(end.line - 1))}
+	}
+
+	// cw doesn't change the white space after the word
+	if p.operator == "c" && p.motion == "w" {
+		line := b.Line(end.line)
+		for end.col > start.col && end.col <= len(line) && unicode.IsSpace(rune(line[end.col-1])) {
+			end.col--
+		}
+	}
+
+	return textRange{start: start, end: end, kind: rangeCharwise}
+}
+
+// setupMotions registers the motions operators can be combined with.
+func (nm *normalmode) setupMotions() {
+	left := motion{target: func(m model, count int) position {
+		b := m.CurrentBuffer()
+		return position{line: b.cursorY, col: max(0, b.cursorX-max(1, count))}
+	}}
+	right := motion{target: func(m model, count int) position {
+		b := m.CurrentBuffer()
+		return position{line: b.cursorY, col: min(len(b.Line(b.cursorY)), b.cursorX+max(1, count))}
+	}}
+	down := motion{linewise: true, target: func(m model, count int) position {
+		b := m.CurrentBuffer()
+		return position{line: min(b.NoOfLines()-1, b.cursorY+max(1, count))}
+	}}
+	up := motion{linewise: true, target: func(m model, count int) position {
+		b := m.CurrentBuffer()
+		return position{line: max(0, b.cursorY-max(1, count))}
+	}}
+
+	nm.motionFuncs = map[string]motion{
+		"h":     left,
+		"left":  left,
+		"l":     right,
+		"right": right,
+		"j":     down,
+		"down":  down,
+		"k":     up,
+		"up":    up,
+		"w": {target: func(m model, count int) position {
+			b := m.CurrentBuffer()
+			cur := position{line: b.cursorY, col: b.cursorX}
+			target := nm.repeatMotion(nm.commandNextWord, m, count)
+			if target.before(cur) {
+				// The word motion wraps around at the end of the buffer
+				last := b.NoOfLines() - 1
+				return position{line: last, col: len(b.Line(last))}
+			}
+			// The last word of a line ends at the line break
+			if target.line > cur.line {
+				return position{line: target.line - 1, col: len(b.Line(target.line - 1))}
+			}
+			return target
+		}},
+		"b": {target: func(m model, count int) position {
+			b := m.CurrentBuffer()
+			cur := position{line: b.cursorY, col: b.cursorX}
+			target := nm.repeatMotion(nm.commandPrevWord, m, count)
+			if cur.before(target) {
+				return position{}
+			}
+			return target
+		}},
+		"gg": {linewise: true, absolute: true, target: func(m model, count int) position {
+			return position{line: min(max(0, count-1), m.CurrentBuffer().NoOfLines()-1)}
+		}},
+		"ge": {linewise: true, absolute: true, target: func(m model, count int) position {
+			b := m.CurrentBuffer()
+			if count > 0 {
+				return position{line: min(count-1, b.NoOfLines()-1)}
+			}
+			return position{line: b.NoOfLines() - 1}
+		}},
+		"gl": {target: func(m model, count int) position {
+			b := m.CurrentBuffer()
+			return position{line: b.cursorY, col: len(b.Line(b.cursorY))}
+		}},
+		"$": {target: func(m model, count int) position {
+			b := m.CurrentBuffer()
+			return position{line: b.cursorY, col: len(b.Line(b.cursorY))}
+		}},
+		"gs": {target: func(m model, count int) position {
+			b := m.CurrentBuffer()
+			return position{line: b.cursorY, col: firstNonBlank(b.Line(b.cursorY))}
+		}},
+		"0": {target: func(m model, count int) position {
+			return position{line: m.CurrentBuffer().cursorY}
+		}},
+	}
+}
+
+// repeatMotion runs a cursor movement command count times and returns
+// where the cursor ends up.
+func (nm *normalmode) repeatMotion(cmd normalCommand, m model, count int) position {
+	// The commands write to the buffers slice, don't change the real one
+	m.buffers = append([]buffer(nil), m.buffers...)
+	var res tea.Model = m
+	for range max(1, count) {
+		res, _ = cmd(res.(model), nil)
+	}
+	b := res.(model).CurrentBuffer()
+	return position{line: b.cursorY, col: b.cursorX}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func grammarTestModel(content string) model {
+	m := initialModel()
+	m.buffers[0] = newBuffer(m.style, bufferWithContent("", content))
+	return m
+}
+
+func TestOperatorsWithMotions(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		keys    string
+		want    []string
+	}{
+		{"delete words", "one two three four", "d2w", []string{"three four"}},
+		{"counts multiply", "a b c d e f g", "2d2w", []string{"e f g"}},
+		{"delete to end of line", "one two", "wd$", []string{"one "}},
+		{"delete to line start", "one two", "wd0", []string{"two"}},
+		{"delete word back", "one two", "$db", []string{"one "}},
+		{"delete last word", "one two\nthree", "wdw", []string{"one ", "three"}},
+		{"delete lines down", "a\nb\nc\nd", "dj", []string{"c", "d"}},
+		{"delete lines up", "a\nb\nc\nd", "jjdk", []string{"a", "d"}},
+		{"delete with count", "a\nb\nc\nd", "3dd", []string{"d"}},
+		{"delete to end of file", "a\nb\nc", "jdge", []string{"a"}},
+		{"delete to start of file", "a\nb\nc", "jdgg", []string{"c"}},
+		{"uppercase word", "one two", "gUw", []string{"ONE two"}},
+		{"uppercase line", "one two", "gUU", []string{"ONE TWO"}},
+		{"lowercase line", "ONE TWO", "gugu", []string{"one two"}},
+		{"indent lines", "a\nb\nc", "2>>", []string{"\ta", "\tb", "c"}},
+		{"indent with motion", "a\nb\nc", ">j", []string{"\ta", "\tb", "c"}},
+		{"unindent", "\ta\n\tb", "<<", []string{"a", "\tb"}},
+		{"change word", "one two", "cwnew", []string{"new two"}},
+		{"change only line", "hello", "ccX", []string{"X"}},
+		{"change whole file", "a\nb\nc", "ggcgeX", []string{"X"}},
+		{"change line keeps indent", "\tone\ntwo", "ccX", []string{"\tX", "two"}},
+		{"change last lines", "a\nb\nc", "jcjX", []string{"a", "X"}},
+		{"repeat", "a\nb\nc\nd\ne", "2dd.", []string{"e"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := pressKeys(grammarTestModel(tt.content), runeKeys(tt.keys)...)
+			if got := m.CurrentBuffer().Lines(); !slices.Equal(got, tt.want) {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCountedMotions(t *testing.T) {
+	m := grammarTestModel("a\nb\nc\nd\ne\nf")
+
+	m = pressKeys(m, runeKeys("3j")...)
+	if y := m.CurrentBuffer().cursorY; y != 3 {
+		t.Errorf("Expected 3j to move to line 3, got %d", y)
+	}
+
+	m = pressKeys(m, runeKeys("2gg")...)
+	if y := m.CurrentBuffer().cursorY; y != 1 {
+		t.Errorf("Expected 2gg to move to line 1, got %d", y)
+	}
+
+	m = pressKeys(m, runeKeys("10ge")...)
+	if y := m.CurrentBuffer().cursorY; y != 5 {
+		t.Errorf("Expected 10ge to stop at the last line, got %d", y)
+	}
+}
+
+func TestYankWithMotion(t *testing.T) {
+	m := grammarTestModel("a\nb\nc")
+
+	m = pressKeys(m, runeKeys(`"ay2j`)...)
+	r, _ := m.Register('a')
+	if !r.linewise || !slices.Equal(r.lines, []string{"a", "b", "c"}) {
+		t.Errorf("Expected \"a to hold all lines linewise, got %v", r)
+	}
+	if m.normalmode.lastCommand != "" {
+		t.Errorf("Expected yank not to be repeatable, got %q", m.normalmode.lastCommand)
+	}
+}
+
+func TestInvalidOperatorSequence(t *testing.T) {
+	m := grammarTestModel("one two")
+
+	m = pressKeys(m, runeKeys("dz")...)
+	if m.normalmode.buffer != "" {
+		t.Errorf("Expected buffer to be cleared, got %q", m.normalmode.buffer)
+	}
+	if got := m.CurrentBuffer().Line(0); got != "one two" {
+		t.Errorf("Expected buffer to stay unchanged, got %q", got)
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func (nm *normalmode) commandUndo(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+	b := m.buffers[m.currBuffer]
+	if !b.CanUndo() {
+		return m.SetInfoMessage("Already at oldest change"), cmd
+	}
+
+	m.buffers[m.currBuffer] = b.Undo()
+
+	return m, cmd
+}
+
+func (nm *normalmode) commandRedo(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+	b := m.buffers[m.currBuffer]
+	if !b.CanRedo() {
+		return m.SetInfoMessage("Already at newest change"), cmd
+	}
+
+	m.buffers[m.currBuffer] = b.Redo()
+
+	return m, cmd
+}
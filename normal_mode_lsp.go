@@ -0,0 +1,38 @@
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// setupLSP registers the keys asking the language server about the symbol
+// under the cursor.
+func (nm *normalmode) setupLSP() {
+	nm.registerCmd("gd", nm.commandGoToLocations("textDocument/definition", "definition"))
+	nm.registerCmd("gy", nm.commandGoToLocations("textDocument/typeDefinition", "type definition"))
+	nm.registerCmd("gi", nm.commandGoToLocations("textDocument/implementation", "implementation"))
+	nm.registerCmd("gr", nm.commandReferences)
+	nm.registerCmd("K", nm.commandHover)
+	nm.registerCmd(" a", nm.commandCodeActions)
+}
+
+func (nm *normalmode) commandGoToLocations(method, what string) normalCommand {
+	return func(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+		return m.requestLocations(method, what, m.CurrentBuffer().cursorPositionParams()), cmd
+	}
+}
+
+func (nm *normalmode) commandReferences(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+	params := lspReferenceParams{
+		lspTextDocumentPositionParams: m.CurrentBuffer().cursorPositionParams(),
+		Context:                       lspReferenceContext{IncludeDeclaration: true},
+	}
+	return m.requestLocations("textDocument/references", "references", params), cmd
+}
+
+func (nm *normalmode) commandHover(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+	return m.requestHover(), cmd
+}
+
+func (nm *normalmode) commandCodeActions(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+	return m.requestCodeActions(), cmd
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// setupMarks registers m{a-z} to set a mark and the '{mark} and `{mark}
+// motions jumping to its line or exact position.
+func (nm *normalmode) setupMarks() {
+	names := []rune{'<', '>'}
+	for name := 'a'; name <= 'z'; name++ {
+		names = append(names, name)
+		nm.registerCmd("m"+string(name), nm.commandSetMark(name))
+	}
+
+	for _, name := range names {
+		line, exact := "'"+string(name), "`"+string(name)
+		nm.motionFuncs[line] = motion{linewise: true, target: markTarget(name, true)}
+		nm.motionFuncs[exact] = motion{target: markTarget(name, false)}
+		nm.registerMotion(line, nm.commandJumpToMark(line, name))
+		nm.registerMotion(exact, nm.commandJumpToMark(exact, name))
+	}
+}
+
+func (nm *normalmode) commandSetMark(name rune) normalCommand {
+	return func(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+		b := m.buffers[m.currBuffer]
+		m.buffers[m.currBuffer] = b.SetMark(name, position{line: b.cursorY, col: b.cursorX})
+		return m, cmd
+	}
+}
+
+func (nm *normalmode) commandJumpToMark(key string, name rune) normalCommand {
+	move := nm.moveTo(key)
+	return func(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+		if _, ok := m.CurrentBuffer().Mark(name); !ok {
+			return m.SetErrorMessage("Mark not set: " + string(name)), cmd
+		}
+		return move(m, cmd)
+	}
+}
+
+// markTarget returns a motion target for the mark. With linewise it goes to
+// the first non-blank character of the mark's line. Without the mark the
+// cursor doesn't move.
+func markTarget(name rune, linewise bool) func(m model, count int) position {
+	return func(m model, count int) position {
+		b := m.CurrentBuffer()
+		p, ok := b.Mark(name)
+		if !ok {
+			return position{line: b.cursorY, col: b.cursorX}
+		}
+		if linewise {
+			return position{line: p.line, col: firstNonBlank(b.Line(p.line))}
+		}
+		return p
+	}
+}
@@ -72,7 +72,7 @@ func (nm *normalmode) commandGoToBeginingOfTheFile(m model, cmd tea.Cmd) (tea.Mo
 
 func (nm *normalmode) commandGoToEndOfTheFile(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
 	b := m.buffers[m.currBuffer]
-	b = b.SetCursorY(len(b.Lines()) - 1)
+	b = b.SetCursorY(b.NoOfLines() - 1)
 
 	m.buffers[m.currBuffer] = b
 
@@ -88,6 +88,12 @@ func (nm *normalmode) commandGoToLast(m model, cmd tea.Cmd) (tea.Model, tea.Cmd)
 	return m, cmd
 }
 
+func (nm *normalmode) commandGoToLineStart(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+	m.buffers[m.currBuffer] = m.buffers[m.currBuffer].SetCursorX(0)
+
+	return m, cmd
+}
+
 func (nm normalmode) commandGoToFirstNonWhiteCharacter(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
 	b := m.buffers[m.currBuffer]
 	l := b.Line(b.cursorY)
@@ -141,6 +147,8 @@ func (nm *normalmode) commandNextWord(m model, cmd tea.Cmd) (tea.Model, tea.Cmd)
 			}
 
 			line = []rune(b.Line(cursorY))
+			// a line break ends the word
+			inWord = false
 
 			// Skip empty lines
 			if len(line) == 0 {
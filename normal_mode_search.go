@@ -0,0 +1,29 @@
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func (nm *normalmode) commandSearchForward(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+	return m.StartSearch(false), cmd
+}
+
+func (nm *normalmode) commandSearchBackward(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+	return m.StartSearch(true), cmd
+}
+
+func (nm *normalmode) commandSearchNext(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+	return m.searchNext(false), cmd
+}
+
+func (nm *normalmode) commandSearchPrevious(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+	return m.searchNext(true), cmd
+}
+
+func (nm *normalmode) commandSearchWordForward(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+	return m.searchWord(false), cmd
+}
+
+func (nm *normalmode) commandSearchWordBackward(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+	return m.searchWord(true), cmd
+}
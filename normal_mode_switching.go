@@ -6,15 +6,52 @@ import (
 
 func (nm *normalmode) commandEnterCommandMode(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
 	m.mode = ModeCommand
-	return m, cmd
+	m.history.browsing = false
+	return m.setCommandLine(""), cmd
+}
+
+func (nm *normalmode) commandOpenFilePicker(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+	return m.OpenFilePicker(".")
 }
 
 func (nm *normalmode) commandEnterInsertMode(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
-	m.mode = ModeInsert
-	return m, cmd
+	// Everything typed until esc is undone as a single step
+	m.buffers[m.currBuffer] = m.buffers[m.currBuffer].beginUndoStep()
+	return m.EnterInsertMode(), cmd
+}
+
+func (nm *normalmode) commandAppend(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+	b := m.buffers[m.currBuffer]
+	m.buffers[m.currBuffer] = b.moveCursorTo(b.cursorX+1, b.cursorY)
+	return nm.commandEnterInsertMode(m, cmd)
+}
+
+func (nm *normalmode) commandAppendAtLineEnd(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+	b := m.buffers[m.currBuffer]
+	m.buffers[m.currBuffer] = b.moveCursorTo(len(b.Line(b.cursorY)), b.cursorY)
+	return nm.commandEnterInsertMode(m, cmd)
+}
+
+func (nm *normalmode) commandInsertAtFirstNonBlank(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+	b := m.buffers[m.currBuffer]
+	m.buffers[m.currBuffer] = b.moveCursorTo(firstNonBlank(b.Line(b.cursorY)), b.cursorY)
+	return nm.commandEnterInsertMode(m, cmd)
 }
 
 func (nm *normalmode) commandClearBuffer(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
 	nm.buffer = ""
+	m.search.highlight = false
 	return m, cmd
-} 
\ No newline at end of file
+}
+
+func (nm *normalmode) commandEnterVisualMode(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+	return m.EnterVisualMode(ModeVisual), cmd
+}
+
+func (nm *normalmode) commandEnterVisualLineMode(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+	return m.EnterVisualMode(ModeVisualLine), cmd
+}
+
+func (nm *normalmode) commandEnterVisualBlockMode(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+	return m.EnterVisualMode(ModeVisualBlock), cmd
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"slices"
+
+	tea "github.com/charmbracelet/bubbletea"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// expandStep is a selection replaced by expanding it to a syntax node, so
+// shrinking can restore it.
+type expandStep struct {
+	mode           editorMode
+	anchor, cursor position
+	expanded       textRange
+}
+
+// setupSyntaxMotions adds the motions jumping between syntax tree nodes.
+func (nm *normalmode) setupSyntaxMotions() {
+	nodeMotions := map[string]motion{
+		"]f": {target: nextNodeTarget(textObjectFunction, true)},
+		"[f": {target: nextNodeTarget(textObjectFunction, false)},
+		"]t": {target: nextNodeTarget(textObjectClass, true)},
+		"[t": {target: nextNodeTarget(textObjectClass, false)},
+		// The same keys as in Helix
+		"alt+b": {target: parentNodeTarget(false)},
+		"alt+e": {target: parentNodeTarget(true), inclusive: true},
+		"alt+n": {target: siblingNodeTarget(true)},
+		"alt+p": {target: siblingNodeTarget(false)},
+	}
+
+	for key, mo := range nodeMotions {
+		nm.motionFuncs[key] = mo
+		nm.registerMotion(key, nm.moveTo(key))
+	}
+
+	nm.registerMotion("alt+o", nm.commandExpandSelection)
+	nm.registerMotion("alt+i", nm.commandShrinkSelection)
+}
+
+// moveTo returns a command moving the cursor to the target of the motion.
+func (nm *normalmode) moveTo(key string) normalCommand {
+	return func(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+		target := nm.motionFuncs[key].target(m, 1)
+		m.buffers[m.currBuffer] = m.buffers[m.currBuffer].moveCursorTo(target.col, target.line)
+		return m, cmd
+	}
+}
+
+// nextNodeTarget returns a motion target jumping to the start of the next
+// (or previous) node of a text object, like a function.
+func nextNodeTarget(key rune, forward bool) func(m model, count int) position {
+	return func(m model, count int) position {
+		b := m.CurrentBuffer()
+		cur := position{line: b.cursorY, col: b.cursorX}
+
+		var starts []position
+		b.walkSyntaxTree(func(n *tree_sitter.Node) {
+			if slices.Contains(textObjects[languageName(b.filename)][key].nodes, n.Kind()) {
+				starts = append(starts, nodeRange(n).start)
+			}
+		})
+
+		target := cur
+		for range max(1, count) {
+			next, ok := nextPosition(starts, target, forward)
+			if !ok {
+				break
+			}
+			target = next
+		}
+		return target
+	}
+}
+
+// nextPosition returns the first position after (or the last one before) p.
+// positions have to be sorted.
+func nextPosition(positions []position, p position, forward bool) (position, bool) {
+	if forward {
+		for _, pos := range positions {
+			if p.before(pos) {
+				return pos, true
+			}
+		}
+		return p, false
+	}
+
+	for _, pos := range slices.Backward(positions) {
+		if pos.before(p) {
+			return pos, true
+		}
+	}
+	return p, false
+}
+
+// parentNodeTarget returns a motion target jumping to the start (or the last
+// character) of the node enclosing the one under the cursor.
+func parentNodeTarget(end bool) func(m model, count int) position {
+	return func(m model, count int) position {
+		b := m.CurrentBuffer()
+		cur := position{line: b.cursorY, col: b.cursorX}
+
+		nodeTarget := func(n *tree_sitter.Node) position {
+			r := b.trimNodeRange(nodeRange(n))
+			if end {
+				return position{line: r.end.line, col: max(0, r.end.col-1)}
+			}
+			return r.start
+		}
+
+		target := cur
+		b.withNodeAt(cur, func(n *tree_sitter.Node) {
+			for range max(1, count) {
+				// Skip the parents the cursor is already at
+				n = parentNode(n)
+				for n != nil && nodeTarget(n) == target {
+					n = parentNode(n)
+				}
+				if n == nil {
+					return
+				}
+				target = nodeTarget(n)
+			}
+		})
+		return target
+	}
+}
+
+// parentNode returns the closest named ancestor that isn't the same text as
+// the node.
+func parentNode(n *tree_sitter.Node) *tree_sitter.Node {
+	for p := n.Parent(); p != nil; p = p.Parent() {
+		if p.IsNamed() && (p.StartByte() != n.StartByte() || p.EndByte() != n.EndByte()) {
+			return p
+		}
+	}
+	return nil
+}
+
+// siblingNodeTarget returns a motion target jumping to the start of the next
+// (or previous) sibling of the node starting at the cursor.
+func siblingNodeTarget(forward bool) func(m model, count int) position {
+	return func(m model, count int) position {
+		b := m.CurrentBuffer()
+		cur := position{line: b.cursorY, col: b.cursorX}
+
+		target := cur
+		b.withNodeAt(cur, func(n *tree_sitter.Node) {
+			// Use the largest node starting at the cursor
+			for p := n.Parent(); p != nil && p.Parent() != nil && p.StartByte() == n.StartByte(); p = p.Parent() {
+				n = p
+			}
+
+			for range max(1, count) {
+				sibling := siblingNode(n, forward)
+				if sibling == nil {
+					break
+				}
+				n = sibling
+			}
+			target = nodeRange(n).start
+		})
+		return target
+	}
+}
+
+// siblingNode returns the next (or previous) named sibling of the node, or of
+// the closest ancestor having one.
+func siblingNode(n *tree_sitter.Node, forward bool) *tree_sitter.Node {
+	for ; n != nil; n = n.Parent() {
+		var sibling *tree_sitter.Node
+		if forward {
+			sibling = n.NextNamedSibling()
+		} else {
+			sibling = n.PrevNamedSibling()
+		}
+		for sibling != nil && sibling.IsExtra() && !slices.Contains(commentKinds, sibling.Kind()) {
+			if forward {
+				sibling = sibling.NextNamedSibling()
+			} else {
+				sibling = sibling.PrevNamedSibling()
+			}
+		}
+		if sibling != nil {
+			return sibling
+		}
+	}
+	return nil
+}
+
+// commentKinds are comment nodes of all supported languages.
+var commentKinds = []string{"comment", "line_comment", "block_comment"}
+
+// walkSyntaxTree calls f for every node of the buffer's syntax tree in the
+// order they start.
+func (b buffer) walkSyntaxTree(f func(n *tree_sitter.Node)) {
+	tree, _ := b.syntaxTree()
+	if tree == nil {
+		return
+	}
+	defer tree.Close()
+
+	var walk func(n *tree_sitter.Node)
+	walk = func(n *tree_sitter.Node) {
+		f(n)
+		for i := range n.ChildCount() {
+			walk(n.Child(i))
+		}
+	}
+	walk(tree.RootNode())
+}
+
+// withNodeAt calls f with the smallest named node at the position.
+func (b buffer) withNodeAt(p position, f func(n *tree_sitter.Node)) {
+	tree, _ := b.syntaxTree()
+	if tree == nil {
+		return
+	}
+	defer tree.Close()
+
+	offset := b.byteOffset(p)
+	f(tree.RootNode().NamedDescendantForByteRange(offset, offset))
+}
+
+// trimNodeRange moves the end of a range ending with a line break to the end
+// of the previous line.
+func (b buffer) trimNodeRange(r textRange) textRange {
+	if r.end.col == 0 && r.end.line > r.start.line {
+		r.end = position{line: r.end.line - 1, col: len(b.Line(r.end.line - 1))}
+	}
+	return r
+}
+
+// commandExpandSelection selects the syntax node around the selection, or the
+// node under the cursor outside of visual mode.
+func (nm *normalmode) commandExpandSelection(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+	b := m.CurrentBuffer()
+	cur := position{line: b.cursorY, col: b.cursorX}
+
+	sel := textRange{start: cur, end: position{line: cur.line, col: cur.col + 1}}
+	if isVisualMode(m.mode) {
+		sel = m.Selection()
+	}
+	if sel.kind == rangeBlockwise {
+		sel.start = b.rangeStart(sel)
+		sel.end.col = columnOffset(b.Line(sel.end.line), sel.end.col)
+	}
+
+	tree, _ := b.syntaxTree()
+	if tree == nil {
+		return m, cmd
+	}
+	defer tree.Close()
+
+	start, end := b.byteOffset(sel.start), b.byteOffset(sel.end)
+	n := tree.RootNode().NamedDescendantForByteRange(start, end)
+	r := b.trimNodeRange(nodeRange(n))
+	// The node is already selected, use its parent
+	for n != nil && isVisualMode(m.mode) && b.trimNodeRange(sel) == r {
+		if n = parentNode(n); n != nil {
+			r = b.trimNodeRange(nodeRange(n))
+		}
+	}
+	if n == nil {
+		return m, cmd
+	}
+
+	step := expandStep{mode: m.mode, anchor: m.visualAnchor, cursor: cur, expanded: r}
+	if !isVisualMode(m.mode) {
+		m.expandHistory = nil
+	}
+	m.expandHistory = append(m.expandHistory, step)
+
+	m.mode = ModeVisual
+	return m.SelectRange(r), cmd
+}
+
+// commandShrinkSelection restores the selection from before the last
+// expansion.
+func (nm *normalmode) commandShrinkSelection(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+	if len(m.expandHistory) == 0 || !isVisualMode(m.mode) {
+		return m, cmd
+	}
+
+	last := m.expandHistory[len(m.expandHistory)-1]
+	if m.Selection() != last.expanded {
+		// The selection was changed in another way
+		m.expandHistory = nil
+		return m, cmd
+	}
+
+	m.expandHistory = m.expandHistory[:len(m.expandHistory)-1]
+	m.mode = last.mode
+	m.visualAnchor = last.anchor
+	m.buffers[m.currBuffer] = m.buffers[m.currBuffer].moveCursorTo(last.cursor.col, last.cursor.line)
+
+	return m, cmd
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const syntaxNavigationSource = `package main
+
+type point struct {
+	x, y int
+}
+
+func add(a, b int) int {
+	return a + b
+}
+
+type size int
+
+func sub(a, b int) int {
+	return a - b
+}
+`
+
+func altKey(r rune) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}, Alt: true}
+}
+
+func syntaxTestModel(line, col int) model {
+	m := initialModel()
+	m.buffers[0] = newBuffer(m.style, bufferWithContent("main.go", syntaxNavigationSource))
+	m.buffers[0] = m.buffers[0].moveCursorTo(col, line)
+	return m
+}
+
+func cursorOf(m model) position {
+	b := m.CurrentBuffer()
+	return position{line: b.cursorY, col: b.cursorX}
+}
+
+func TestNodeMotions(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor position
+		keys   []tea.KeyMsg
+		want   position
+	}{
+		{"next function", position{}, runeKeys("]f"), position{line: 6}},
+		{"next function with count", position{}, runeKeys("2]f"), position{line: 12}},
+		{"no next function", position{line: 13}, runeKeys("]f"), position{line: 13}},
+		{"previous function", position{line: 13, col: 2}, runeKeys("[f"), position{line: 12}},
+		{"next type", position{line: 3}, runeKeys("]t"), position{line: 10}},
+		{"previous type", position{line: 10}, runeKeys("[t"), position{line: 2}},
+		{"parent start", position{line: 7, col: 12}, []tea.KeyMsg{altKey('b')}, position{line: 7, col: 8}},
+		{"parent end", position{line: 7, col: 8}, []tea.KeyMsg{altKey('e')}, position{line: 7, col: 12}},
+		{"parent end at the end", position{line: 7, col: 12}, []tea.KeyMsg{altKey('e')}, position{line: 8, col: 0}},
+		{"parent of parent", position{line: 7, col: 12}, append(runeKeys("2"), altKey('b')), position{line: 7, col: 1}},
+		{"next sibling", position{line: 2}, []tea.KeyMsg{altKey('n')}, position{line: 6}},
+		{"previous sibling", position{line: 6}, []tea.KeyMsg{altKey('p')}, position{line: 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := pressKeys(syntaxTestModel(tt.cursor.line, tt.cursor.col), tt.keys...)
+			if got := cursorOf(m); got != tt.want {
+				t.Errorf("Expected cursor at %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestOperatorWithNodeMotion(t *testing.T) {
+	m := pressKeys(syntaxTestModel(2, 0), runeKeys("d]f")...)
+
+	// The motion starts at the beginning of a line, the whole lines are deleted
+	if got := m.CurrentBuffer().Line(2); !strings.HasPrefix(got, "func add") {
+		t.Errorf("Expected the type to be deleted up to the function, got %q", got)
+	}
+}
+
+func TestExpandAndShrinkSelection(t *testing.T) {
+	// On the b in "a + b"
+	m := syntaxTestModel(7, 12)
+
+	m = pressKeys(m, altKey('o'))
+	if m.mode != ModeVisual {
+		t.Fatalf("Expected visual mode, got %s", m.mode)
+	}
+	if text := m.CurrentBuffer().Text(m.Selection()).Text(); text != "b" {
+		t.Errorf("Expected b to be selected, got %q", text)
+	}
+
+	m = pressKeys(m, altKey('o'))
+	if text := m.CurrentBuffer().Text(m.Selection()).Text(); text != "a + b" {
+		t.Errorf("Expected the expression to be selected, got %q", text)
+	}
+
+	m = pressKeys(m, altKey('o'))
+	if text := m.CurrentBuffer().Text(m.Selection()).Text(); text != "return a + b" {
+		t.Errorf("Expected the statement to be selected, got %q", text)
+	}
+
+	m = pressKeys(m, altKey('i'))
+	if text := m.CurrentBuffer().Text(m.Selection()).Text(); text != "a + b" {
+		t.Errorf("Expected shrinking back to the expression, got %q", text)
+	}
+
+	m = pressKeys(m, altKey('i'), altKey('i'))
+	if m.mode != ModeNormal || cursorOf(m) != (position{line: 7, col: 12}) {
+		t.Errorf("Expected to be back in normal mode at the start, got %s at %v", m.mode, cursorOf(m))
+	}
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// setupTabs registers gt and gT going through the tab pages.
+func (nm *normalmode) setupTabs() {
+	nm.registerCmd("gt", nm.commandCycleTab(1))
+	nm.registerCmd("gT", nm.commandCycleTab(-1))
+}
+
+func (nm *normalmode) commandCycleTab(n int) normalCommand {
+	return func(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+		return m.cycleTab(n), cmd
+	}
+}
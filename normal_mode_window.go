@@ -0,0 +1,69 @@
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// setupWindows registers the ctrl-w commands working with the windows.
+func (nm *normalmode) setupWindows() {
+	nm.registerCmd("ctrl+ws", nm.commandSplitWindow(false))
+	nm.registerCmd("ctrl+wv", nm.commandSplitWindow(true))
+	nm.registerCmd("ctrl+ww", nm.commandCycleWindow(1))
+	nm.registerCmd("ctrl+wctrl+w", nm.commandCycleWindow(1))
+	nm.registerCmd("ctrl+wW", nm.commandCycleWindow(-1))
+	nm.registerCmd("ctrl+wc", nm.commandCloseWindow)
+	nm.registerCmd("ctrl+wq", nm.commandQuitWindow)
+	nm.registerCmd("ctrl+wo", nm.commandOnlyWindow)
+
+	directions := []struct {
+		keys   []string
+		dx, dy int
+	}{
+		{[]string{"h", "left", "ctrl+h"}, -1, 0},
+		{[]string{"j", "down", "ctrl+j"}, 0, 1},
+		{[]string{"k", "up", "ctrl+k"}, 0, -1},
+		{[]string{"l", "right", "ctrl+l"}, 1, 0},
+	}
+	for _, d := range directions {
+		for _, key := range d.keys {
+			nm.registerCmd("ctrl+w"+key, nm.commandFocusWindow(d.dx, d.dy))
+		}
+	}
+}
+
+func (nm *normalmode) commandSplitWindow(vertical bool) normalCommand {
+	return func(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+		return m.splitWindow(vertical), cmd
+	}
+}
+
+func (nm *normalmode) commandCycleWindow(n int) normalCommand {
+	return func(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+		return m.cycleWindow(n), cmd
+	}
+}
+
+func (nm *normalmode) commandFocusWindow(dx, dy int) normalCommand {
+	return func(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+		if i := m.neighbourWindow(dx, dy); i >= 0 {
+			m = m.focusWindow(i)
+		}
+		return m, cmd
+	}
+}
+
+func (nm *normalmode) commandCloseWindow(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+	m, err := m.closeWindow(m.windows.curr)
+	if err != nil {
+		return m.SetErrorMessage(err.Error()), cmd
+	}
+	return m, cmd
+}
+
+func (nm *normalmode) commandQuitWindow(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+	return commandQuit{}.Update(m, nil, nil)
+}
+
+func (nm *normalmode) commandOnlyWindow(m model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+	return m.onlyWindow(), cmd
+}
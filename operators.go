@@ -0,0 +1,119 @@
+package main
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// applyOperator runs the operator on the range of the current buffer. reg
+// is the register selected with the " prefix, or 0.
+func (m model) applyOperator(op string, r textRange, reg rune) (model, tea.Cmd) {
+	b := m.buffers[m.currBuffer]
+	b = b.beginUndoStep()
+
+	switch op {
+	case "d":
+		m = m.Delete(reg, b.Text(r))
+		b = b.DeleteRange(r)
+	case "y":
+		m = m.Yank(reg, b.Text(r))
+		start := b.rangeStart(r)
+		b = b.moveCursorTo(start.col, start.line)
+	case "c":
+		m = m.Delete(reg, b.Text(r))
+		var block *blockInsert
+		if r.kind == rangeLinewise {
+			indent := leadingWhitespace(b.Line(r.start.line))
+			whole := r.start.line == 0 && r.end.line >= b.NoOfLines()-1
+			b = b.DeleteRange(r)
+			if whole {
+				// Deleting every line leaves the empty line to change
+				b = b.ReplaceLine(0, indent)
+			} else {
+				b = b.InsertLine(r.start.line, indent)
+			}
+			b = b.moveCursorTo(len(indent), r.start.line)
+		} else {
+			block = b.blockInsert(r)
+			b = b.DeleteRange(r)
+		}
+		// The undo step is committed when insert mode is left
+		m.buffers[m.currBuffer] = b.SetStateModified()
+		m = m.EnterInsertMode()
+		m.blockInsert = block
+		return m, nil
+	case ">":
+		b = b.IndentLines(r, 1)
+	case "<":
+		b = b.IndentLines(r, -1)
+	case "~":
+		b = b.MapRange(r, toggleCase)
+		start := b.rangeStart(r)
+		b = b.moveCursorTo(start.col, start.line)
+	case "gu":
+		b = b.MapRange(r, strings.ToLower)
+		start := b.rangeStart(r)
+		b = b.moveCursorTo(start.col, start.line)
+	case "gU":
+		b = b.MapRange(r, strings.ToUpper)
+		start := b.rangeStart(r)
+		b = b.moveCursorTo(start.col, start.line)
+	}
+
+	if op != "y" {
+		b = b.SetStateModified()
+	}
+	m.buffers[m.currBuffer] = b.commitUndoStep()
+
+	return m, nil
+}
+
+func leadingWhitespace(line string) string {
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
+
+// blockInsert is a block changed with c. The text typed on its first line
+// is repeated on the other lines reaching into the block.
+type blockInsert struct {
+	start position
+	lines []int
+}
+
+// blockInsert returns the block change of the range, nil for the other
+// kinds of ranges.
+func (b buffer) blockInsert(r textRange) *blockInsert {
+	if r.kind != rangeBlockwise {
+		return nil
+	}
+	block := &blockInsert{start: r.start}
+	for y := r.start.line + 1; y <= r.end.line && y < b.NoOfLines(); y++ {
+		if line := b.Line(y); displayColumn(line, len(line)) > r.start.col {
+			block.lines = append(block.lines, y)
+		}
+	}
+	return block
+}
+
+// repeatBlockInsert types the text inserted on the first line of a block
+// change on the other lines of the block, when insert mode is left. Nothing
+// is repeated when the cursor left the inserted text.
+func (m model) repeatBlockInsert(b buffer) buffer {
+	block := m.blockInsert
+	if block == nil || b.cursorY != block.start.line {
+		return b
+	}
+	line := b.Line(block.start.line)
+	start := columnOffset(line, block.start.col)
+	if b.cursorX < start {
+		return b
+	}
+	text := line[start:min(b.cursorX, len(line))]
+
+	for _, y := range block.lines {
+		line := b.Line(y)
+		i := columnOffset(line, block.start.col)
+		b = b.ReplaceLine(y, line[:i]+text+line[i:])
+	}
+	return b
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+const (
+	popupMaxWidth  = 80
+	popupMaxHeight = 12
+)
+
+// popup is text floating over the buffer next to the cursor, like the
+// documentation shown by K.
+type popup struct {
+	lines []string
+	// signature popups stay open while typing in insert mode, the other
+	// ones are closed by the next key
+	signature bool
+}
+
+func newPopup(text string, signature bool) *popup {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	return &popup{lines: strings.Split(text, "\n"), signature: signature}
+}
+
+// box renders the popup as lines of the same width, fitting the screen.
+func (p popup) box(style editorStyle, screenWidth int) []string {
+	width := 0
+	for _, line := range p.lines {
+		width = max(width, ansi.StringWidth(expandTabs(line)))
+	}
+	width = min(width+2, popupMaxWidth, screenWidth)
+	if width <= 2 {
+		return nil
+	}
+
+	var box []string
+	for _, line := range p.lines {
+		wrapped := ansi.Wrap(expandTabs(line), width-2, "")
+		for _, l := range strings.Split(wrapped, "\n") {
+			pad := width - 2 - ansi.StringWidth(l)
+			box = append(box, style.popup.Render(" "+l+strings.Repeat(" ", max(pad, 0))+" "))
+		}
+	}
+	if len(box) > popupMaxHeight {
+		box = box[:popupMaxHeight]
+	}
+	return box
+}
+
+// overlay draws the box over the lines below the screen position x, y, or
+// above it when there's no room below. The box is moved left to fit the
+// width of the screen.
+func overlay(lines, box []string, x, y, screenWidth int) []string {
+	if len(box) == 0 {
+		return lines
+	}
+	top := y + 1
+	if top+len(box) > len(lines) && y-len(box) >= 0 {
+		top = y - len(box)
+	}
+	width := ansi.StringWidth(box[0])
+	left := max(min(x, screenWidth-width), 0)
+
+	lines = append([]string(nil), lines...)
+	for i, row := range box {
+		if top+i < 0 || top+i >= len(lines) {
+			continue
+		}
+		line := lines[top+i]
+		before := ansi.Truncate(line, left, "")
+		before += strings.Repeat(" ", max(left-ansi.StringWidth(before), 0))
+		after := ansi.TruncateLeft(line, left+width, "")
+		lines[top+i] = before + row + after
+	}
+	return lines
+}
+
+// cursorScreenPosition is where the cursor is drawn in the buffer area.
+func (m model) cursorScreenPosition() (int, int) {
+	b := m.CurrentBuffer()
+	x := b.gutterWidth() + visualCursorX(b.Line(b.cursorY), b.cursorX) - b.cursorXOffset
+	y := b.cursorY - b.cursorYOffset
+	if m.hasSplits() {
+		r := m.windowRects()[m.windows.curr]
+		x += r.x
+		y += r.y
+	}
+	return x, y
+}
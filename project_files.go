@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// errWalkStopped is returned by walkProject when visit asked to stop.
+var errWalkStopped = errors.New("walk stopped")
+
+// walkProject calls visit with every file under the root which isn't
+// ignored by a .gitignore file. The paths are relative to the root and use
+// slashes. The walk stops when the context is cancelled or visit returns
+// false.
+func walkProject(ctx context.Context, root string, visit func(name string) bool) error {
+	return walkProjectDir(ctx, root, "", nil, visit)
+}
+
+func walkProjectDir(ctx context.Context, root, dir string, stack []ignoreRules, visit func(name string) bool) error {
+	entries, err := os.ReadDir(filepath.Join(root, filepath.FromSlash(dir)))
+	if err != nil {
+		if dir == "" {
+			return err
+		}
+		// Unreadable directories are skipped
+		return nil
+	}
+
+	if f, err := os.Open(filepath.Join(root, filepath.FromSlash(dir), ".gitignore")); err == nil {
+		// The stack is shared with the parent directory
+		stack = append(stack[:len(stack):len(stack)], parseIgnoreRules(dir, f))
+		f.Close()
+	}
+
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		name := path.Join(dir, e.Name())
+		isDir := e.IsDir()
+		if isIgnored(stack, name, isDir) {
+			continue
+		}
+
+		if isDir {
+			if err := walkProjectDir(ctx, root, name, stack, visit); err != nil {
+				return err
+			}
+			continue
+		}
+		if !e.Type().IsRegular() && e.Type()&fs.ModeSymlink == 0 {
+			continue
+		}
+		if !visit(name) {
+			return errWalkStopped
+		}
+	}
+	return nil
+}
+
+// binarySniffLen is how much of a file is checked for being binary.
+const binarySniffLen = 8000
+
+// isBinary tells if the content looks like a binary file, the way git does
+// by looking for a NUL byte at its beginning.
+func isBinary(content []byte) bool {
+	return bytes.IndexByte(content[:min(len(content), binarySniffLen)], 0) >= 0
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// register holds yanked or deleted text. Linewise registers are put as
+// whole lines, charwise ones are put inside the current line.
+type register struct {
+	lines    []string
+	linewise bool
+}
+
+func (r register) Text() string {
+	text := strings.Join(r.lines, "\n")
+	if r.linewise {
+		text += "\n"
+	}
+	return text
+}
+
+// registers maps register names to their content. The unnamed register is
+// stored under '"'.
+type registers map[rune]register
+
+const unnamedRegister = '"'
+
+// isValidRegister reports whether the name can be used with the " prefix.
+func isValidRegister(name rune) bool {
+	switch {
+	case name == unnamedRegister, name == '.':
+		return true
+	case isClipboardRegister(name):
+		return true
+	case name >= '0' && name <= '9':
+		return true
+	case name >= 'a' && name <= 'z', name >= 'A' && name <= 'Z':
+		return true
+	}
+	return false
+}
+
+func (m model) Register(name rune) (register, bool) {
+	if name == 0 {
+		name = unnamedRegister
+	}
+
+	r, ok := m.registers[unicode.ToLower(name)]
+	return r, ok
+}
+
+// registerFromText converts text from outside of the editor to a register.
+// Text ending with a newline is linewise.
+func registerFromText(text string) register {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if strings.HasSuffix(text, "\n") {
+		return register{lines: strings.Split(strings.TrimSuffix(text, "\n"), "\n"), linewise: true}
+	}
+	return register{lines: strings.Split(text, "\n")}
+}
+
+// setRegister stores the text in a named register. Uppercase names append
+// to the lowercase register.
+func (m model) setRegister(name rune, r register) model {
+	if m.registers == nil {
+		m.registers = registers{}
+	}
+
+	if unicode.IsUpper(name) {
+		name = unicode.ToLower(name)
+		if old, ok := m.registers[name]; ok {
+			r = appendRegister(old, r)
+		}
+	}
+
+	r.lines = slices.Clone(r.lines)
+	m.registers[name] = r
+	m.registers[unnamedRegister] = r
+
+	if isClipboardRegister(name) {
+		m.clipboardCopies = append(slices.Clip(m.clipboardCopies), clipboardCopy{text: r.Text(), primary: name == '*'})
+	}
+
+	return m
+}
+
+// appendRegister joins both registers. The result is linewise if either of
+// them is.
+func appendRegister(old, r register) register {
+	if old.linewise || r.linewise {
+		return register{lines: append(slices.Clone(old.lines), r.lines...), linewise: true}
+	}
+
+	lines := slices.Clone(old.lines)
+	lines[len(lines)-1] += r.lines[0]
+	return register{lines: append(lines, r.lines[1:]...)}
+}
+
+// Yank stores yanked text in the given register, or in "0 when no register
+// was selected.
+func (m model) Yank(name rune, r register) model {
+	if name != 0 && name != unnamedRegister {
+		if name == '.' {
+			return m
+		}
+		return m.setRegister(name, r)
+	}
+
+	return m.setRegister('0', r)
+}
+
+// Delete stores deleted text in the given register. Without a register the
+// text is pushed to the numbered ring "1 to "9.
+func (m model) Delete(name rune, r register) model {
+	if name != 0 && name != unnamedRegister {
+		if name == '.' {
+			return m
+		}
+		return m.setRegister(name, r)
+	}
+
+	if m.registers == nil {
+		m.registers = registers{}
+	}
+	for i := '9'; i > '1'; i-- {
+		if prev, ok := m.registers[i-1]; ok {
+			m.registers[i] = prev
+		}
+	}
+
+	return m.setRegister('1', r)
+}
+
+// setLastInsert updates the read-only ". register.
+func (m model) setLastInsert(text string) model {
+	if m.registers == nil {
+		m.registers = registers{}
+	}
+	m.registers['.'] = register{lines: strings.Split(text, "\n")}
+	return m
+}
+
+// putRegister puts the register after (or before) the cursor as a single
+// change.
+func (m model) putRegister(r register, after bool) model {
+	b := m.buffers[m.currBuffer]
+	b = b.beginUndoStep()
+	b = b.Put(r, after)
+	b = b.SetStateModified()
+	b = b.commitUndoStep()
+	m.buffers[m.currBuffer] = b
+	return m
+}
+
+// Put inserts the register content after (or before) the cursor. Linewise
+// registers are put below (or above) the current line.
+func (b buffer) Put(r register, after bool) buffer {
+	if len(r.lines) == 0 {
+		return b
+	}
+
+	if r.linewise {
+		y := b.cursorY
+		if after {
+			y++
+		}
+		for i, line := range r.lines {
+			b = b.InsertLine(y+i, line)
+		}
+		b = b.SetCursorY(y)
+		return b.SetCursorX(firstNonBlank(b.Line(y)))
+	}
+
+	line := b.Line(b.cursorY)
+	pos := min(b.cursorX, len(line))
+	if after && len(line) > 0 {
+		pos = min(pos+1, len(line))
+	}
+
+	before, rest := line[:pos], line[pos:]
+	if len(r.lines) == 1 {
+		b = b.ReplaceLine(b.cursorY, before+r.lines[0]+rest)
+		return b.SetCursorX(pos + max(0, len(r.lines[0])-1))
+	}
+
+	last := len(r.lines) - 1
+	b = b.ReplaceLine(b.cursorY, before+r.lines[0])
+	for i := 1; i < last; i++ {
+		b = b.InsertLine(b.cursorY+i, r.lines[i])
+	}
+	b = b.InsertLine(b.cursorY+last, r.lines[last]+rest)
+
+	return b.SetCursorX(pos)
+}
+
+func firstNonBlank(line string) int {
+	for i, r := range line {
+		if !unicode.IsSpace(r) {
+			return i
+		}
+	}
+	return 0
+}
+
+func registerName(name rune) rune {
+	if name == 0 {
+		return unnamedRegister
+	}
+	return name
+}
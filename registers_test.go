@@ -0,0 +1,118 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestDeleteAndPutLine(t *testing.T) {
+	m := initialModel()
+	m.buffers[0] = newBuffer(m.style, bufferWithContent("", "one\ntwo\nthree"))
+
+	m = pressKeys(m, runeKeys("ddp")...)
+	if got := m.CurrentBuffer().Lines(); !slices.Equal(got, []string{"two", "one", "three"}) {
+		t.Errorf("Expected [two one three], got %v", got)
+	}
+
+	r, _ := m.Register('1')
+	if !r.linewise || r.lines[0] != "one" {
+		t.Errorf("Expected \"1 to hold the deleted line, got %v", r)
+	}
+
+	m.buffers[0] = m.buffers[0].SetCursorY(0)
+	m = pressKeys(m, runeKeys("P")...)
+	if got := m.CurrentBuffer().Lines(); !slices.Equal(got, []string{"one", "two", "one", "three"}) {
+		t.Errorf("Expected [one two one three], got %v", got)
+	}
+}
+
+func TestDeleteRing(t *testing.T) {
+	m := initialModel()
+	m.buffers[0] = newBuffer(m.style, bufferWithContent("", "a\nb\nc"))
+
+	m = pressKeys(m, runeKeys("dddd")...)
+
+	if r, _ := m.Register('1'); r.lines[0] != "b" {
+		t.Errorf("Expected \"1 to be b, got %v", r.lines)
+	}
+	if r, _ := m.Register('2'); r.lines[0] != "a" {
+		t.Errorf("Expected \"2 to be a, got %v", r.lines)
+	}
+}
+
+func TestNamedRegisters(t *testing.T) {
+	m := initialModel()
+	m.buffers[0] = newBuffer(m.style, bufferWithContent("", "a\nb"))
+
+	m = pressKeys(m, runeKeys(`"ayyj"Ayy`)...)
+	r, _ := m.Register('a')
+	if !slices.Equal(r.lines, []string{"a", "b"}) || !r.linewise {
+		t.Errorf("Expected \"a to hold both lines, got %v", r.lines)
+	}
+
+	// A yank into a named register doesn't touch "0
+	if _, ok := m.Register('0'); ok {
+		t.Error("Expected \"0 to be empty")
+	}
+
+	m = pressKeys(m, runeKeys(`"ap`)...)
+	if got := m.CurrentBuffer().Lines(); !slices.Equal(got, []string{"a", "b", "a", "b"}) {
+		t.Errorf("Expected [a b a b], got %v", got)
+	}
+}
+
+func TestLastInsertRegister(t *testing.T) {
+	m := initialModel()
+	m.buffers[0] = newBuffer(m.style, bufferWithContent("", "xy"))
+
+	m = pressKeys(m, runeKeys("ihello")...)
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyBackspace}, tea.KeyMsg{Type: tea.KeyEsc})
+
+	r, _ := m.Register('.')
+	if r.linewise || !slices.Equal(r.lines, []string{"hell"}) {
+		t.Fatalf("Expected \". to be charwise hell, got %v", r.lines)
+	}
+
+	// Charwise put inserts after the cursor
+	m.buffers[0] = m.buffers[0].SetCursorX(0)
+	m = pressKeys(m, runeKeys(`".p`)...)
+	if got := m.CurrentBuffer().Line(0); got != "hhellellxy" {
+		t.Errorf("Expected hhellellxy, got %q", got)
+	}
+
+	// The ". register is read-only
+	m = m.Yank('.', register{lines: []string{"other"}})
+	if r, _ := m.Register('.'); r.lines[0] != "hell" {
+		t.Errorf("Expected \". to stay unchanged, got %v", r.lines)
+	}
+}
+
+func TestPutMultilineCharwise(t *testing.T) {
+	b := newBuffer(newEditorStyle(), bufferWithContent("", "abcd"))
+	b = b.SetCursorX(1)
+	b = b.Put(register{lines: []string{"X", "Y"}}, true)
+
+	if got := b.Lines(); !slices.Equal(got, []string{"abX", "Ycd"}) {
+		t.Errorf("Expected [abX Ycd], got %v", got)
+	}
+}
+
+func TestRegistersCommand(t *testing.T) {
+	m := initialModel()
+	m.viewport = tea.WindowSizeMsg{Width: 80, Height: 24}
+	m.buffers[0] = newBuffer(m.style, bufferWithContent("", "a\nb"))
+	m = pressKeys(m, runeKeys("yy")...)
+
+	m.commandBuffer = "registers"
+	newModel, _ := m.updateCommand(tea.KeyMsg{Type: tea.KeyEnter})
+	m = newModel.(model)
+
+	if m.mode != ModeList || len(m.list.items) != 2 {
+		t.Fatalf("Expected a list with 2 registers, got mode %s", m.mode)
+	}
+	if m.list.items[0].label != `l  ""  a` {
+		t.Errorf("Expected unnamed register first, got %q", m.list.items[0].label)
+	}
+}
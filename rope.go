@@ -0,0 +1,253 @@
+package main
+
+import "strings"
+
+// rope is an immutable sequence of lines stored in a balanced binary tree.
+// Every modification copies only the path from the root to the changed
+// line, so inserts and deletes are O(log n) and keeping old versions around
+// (e.g. for undo) is free.
+type rope struct {
+	root *ropeNode
+}
+
+type ropeNode struct {
+	left, right *ropeNode
+	line        string
+	// size is the number of lines in the subtree
+	size   int
+	height int
+}
+
+func newRope(lines []string) rope {
+	return rope{root: buildRope(lines)}
+}
+
+func buildRope(lines []string) *ropeNode {
+	if len(lines) == 0 {
+		return nil
+	}
+	mid := len(lines) / 2
+	return newRopeNode(buildRope(lines[:mid]), lines[mid], buildRope(lines[mid+1:]))
+}
+
+func newRopeNode(left *ropeNode, line string, right *ropeNode) *ropeNode {
+	return &ropeNode{
+		left:   left,
+		right:  right,
+		line:   line,
+		size:   left.len() + right.len() + 1,
+		height: max(left.depth(), right.depth()) + 1,
+	}
+}
+
+func (n *ropeNode) len() int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+func (n *ropeNode) depth() int {
+	if n == nil {
+		return 0
+	}
+	return n.height
+}
+
+// balanceRope joins the subtrees like newRopeNode, rotating them when their
+// heights differ by more than one (AVL).
+func balanceRope(l *ropeNode, line string, r *ropeNode) *ropeNode {
+	switch {
+	case l.depth() > r.depth()+1:
+		if l.left.depth() >= l.right.depth() {
+			return newRopeNode(l.left, l.line, newRopeNode(l.right, line, r))
+		}
+		return newRopeNode(
+			newRopeNode(l.left, l.line, l.right.left),
+			l.right.line,
+			newRopeNode(l.right.right, line, r),
+		)
+	case r.depth() > l.depth()+1:
+		if r.right.depth() >= r.left.depth() {
+			return newRopeNode(newRopeNode(l, line, r.left), r.line, r.right)
+		}
+		return newRopeNode(
+			newRopeNode(l, line, r.left.left),
+			r.left.line,
+			newRopeNode(r.left.right, r.line, r.right),
+		)
+	}
+	return newRopeNode(l, line, r)
+}
+
+func (r rope) Len() int {
+	return r.root.len()
+}
+
+// Line returns the line at index i. The index must be in range.
+func (r rope) Line(i int) string {
+	n := r.root
+	for n != nil {
+		ls := n.left.len()
+		switch {
+		case i < ls:
+			n = n.left
+		case i > ls:
+			i -= ls + 1
+			n = n.right
+		default:
+			return n.line
+		}
+	}
+	return ""
+}
+
+// Insert returns a rope with s inserted before index i.
+func (r rope) Insert(i int, s string) rope {
+	return rope{root: ropeInsert(r.root, i, s)}
+}
+
+func ropeInsert(n *ropeNode, i int, s string) *ropeNode {
+	if n == nil {
+		return newRopeNode(nil, s, nil)
+	}
+	ls := n.left.len()
+	if i <= ls {
+		return balanceRope(ropeInsert(n.left, i, s), n.line, n.right)
+	}
+	return balanceRope(n.left, n.line, ropeInsert(n.right, i-ls-1, s))
+}
+
+// Delete returns a rope without the line at index i.
+func (r rope) Delete(i int) rope {
+	return rope{root: ropeDelete(r.root, i)}
+}
+
+func ropeDelete(n *ropeNode, i int) *ropeNode {
+	ls := n.left.len()
+	switch {
+	case i < ls:
+		return balanceRope(ropeDelete(n.left, i), n.line, n.right)
+	case i > ls:
+		return balanceRope(n.left, n.line, ropeDelete(n.right, i-ls-1))
+	}
+
+	if n.left == nil {
+		return n.right
+	}
+	if n.right == nil {
+		return n.left
+	}
+	first := n.right
+	for first.left != nil {
+		first = first.left
+	}
+	return balanceRope(n.left, first.line, ropeDelete(n.right, 0))
+}
+
+// Set returns a rope with the line at index i replaced by s.
+func (r rope) Set(i int, s string) rope {
+	return rope{root: ropeSet(r.root, i, s)}
+}
+
+func ropeSet(n *ropeNode, i int, s string) *ropeNode {
+	c := *n
+	ls := n.left.len()
+	switch {
+	case i < ls:
+		c.left = ropeSet(n.left, i, s)
+	case i > ls:
+		c.right = ropeSet(n.right, i-ls-1, s)
+	default:
+		c.line = s
+	}
+	return &c
+}
+
+// Lines returns all lines as a slice.
+func (r rope) Lines() []string {
+	lines := make([]string, 0, r.Len())
+	r.each(func(line string) bool {
+		lines = append(lines, line)
+		return true
+	})
+	return lines
+}
+
+// Slice returns the lines from index from up to to. Only the nodes holding
+// them are visited.
+func (r rope) Slice(from, to int) []string {
+	lines := make([]string, 0, max(0, to-from))
+	return ropeSlice(r.root, from, to, lines)
+}
+
+func ropeSlice(n *ropeNode, from, to int, lines []string) []string {
+	if n == nil || from >= to {
+		return lines
+	}
+	ls := n.left.len()
+	if from < ls {
+		lines = ropeSlice(n.left, from, min(to, ls), lines)
+	}
+	if from <= ls && ls < to {
+		lines = append(lines, n.line)
+	}
+	if to > ls+1 {
+		lines = ropeSlice(n.right, max(0, from-ls-1), to-ls-1, lines)
+	}
+	return lines
+}
+
+// String returns the lines joined with newlines, without copying them into
+// a slice first.
+func (r rope) String() string {
+	var sb strings.Builder
+	first := true
+	r.each(func(line string) bool {
+		if !first {
+			sb.WriteByte('\n')
+		}
+		first = false
+		sb.WriteString(line)
+		return true
+	})
+	return sb.String()
+}
+
+// each calls fn for every line in order until fn returns false.
+func (r rope) each(fn func(line string) bool) {
+	var stack []*ropeNode
+	n := r.root
+	for n != nil || len(stack) > 0 {
+		for n != nil {
+			stack = append(stack, n)
+			n = n.left
+		}
+		n = stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if !fn(n.line) {
+			return
+		}
+		n = n.right
+	}
+}
+
+// Equal reports whether both ropes hold the same lines. Versions derived
+// from each other share nodes, so unchanged ropes are compared in O(1).
+func (r rope) Equal(o rope) bool {
+	if r.root == o.root {
+		return true
+	}
+	if r.Len() != o.Len() {
+		return false
+	}
+
+	i := 0
+	equal := true
+	r.each(func(line string) bool {
+		equal = line == o.Line(i)
+		i++
+		return equal
+	})
+	return equal
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRopeMatchesSlice(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	lines := []string{"a", "b", "c"}
+	r := newRope(lines)
+
+	for i := 0; i < 2000; i++ {
+		switch op := rnd.Intn(3); {
+		case op == 0 || len(lines) == 0:
+			n := rnd.Intn(len(lines) + 1)
+			s := fmt.Sprintf("line %d", i)
+			lines = slices.Insert(lines, n, s)
+			r = r.Insert(n, s)
+		case op == 1:
+			n := rnd.Intn(len(lines))
+			lines = slices.Delete(lines, n, n+1)
+			r = r.Delete(n)
+		default:
+			n := rnd.Intn(len(lines))
+			s := fmt.Sprintf("changed %d", i)
+			lines[n] = s
+			r = r.Set(n, s)
+		}
+
+		if r.Len() != len(lines) {
+			t.Fatalf("step %d: expected %d lines, got %d", i, len(lines), r.Len())
+		}
+	}
+
+	if !slices.Equal(r.Lines(), lines) {
+		t.Errorf("rope content differs from slice")
+	}
+	for i, l := range lines {
+		if r.Line(i) != l {
+			t.Errorf("Line(%d) = %q, want %q", i, r.Line(i), l)
+		}
+	}
+	if !r.Equal(newRope(lines)) {
+		t.Error("Expected ropes with the same lines to be equal")
+	}
+	if r.String() != strings.Join(lines, "\n") {
+		t.Error("Expected String to join the lines")
+	}
+	for range 100 {
+		from := rnd.Intn(len(lines) + 1)
+		to := from + rnd.Intn(len(lines)-from+1)
+		if got := r.Slice(from, to); !slices.Equal(got, lines[from:to]) {
+			t.Fatalf("Slice(%d, %d) = %q, want %q", from, to, got, lines[from:to])
+		}
+	}
+}
+
+func TestRopeIsImmutable(t *testing.T) {
+	r := newRope([]string{"a", "b", "c"})
+	changed := r.Set(1, "x").Delete(0).Insert(0, "y")
+
+	if got := r.Lines(); !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Errorf("Expected original rope to be unchanged, got %v", got)
+	}
+	if got := changed.Lines(); !slices.Equal(got, []string{"y", "x", "c"}) {
+		t.Errorf("Expected [y x c], got %v", got)
+	}
+	if r.Equal(changed) {
+		t.Error("Expected ropes with different lines not to be equal")
+	}
+}
+
+const benchmarkLines = 500_000
+
+func benchmarkContent() []string {
+	lines := make([]string, benchmarkLines)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("2025-01-01 12:00:00 INFO request %d handled", i)
+	}
+	return lines
+}
+
+// sliceInsertLine is the []string implementation buffer used before the rope.
+func sliceInsertLine(lines []string, n int, s string) []string {
+	return append(lines[:n], append([]string{s}, lines[n:]...)...)
+}
+
+func sliceDeleteLine(lines []string, n int) []string {
+	return append(lines[:n], lines[n+1:]...)
+}
+
+func BenchmarkInsertLineNearTop(b *testing.B) {
+	b.Run("slice", func(b *testing.B) {
+		lines := benchmarkContent()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			lines = sliceInsertLine(lines, 10, "new line")
+			lines = sliceDeleteLine(lines, 10)
+		}
+	})
+	b.Run("rope", func(b *testing.B) {
+		r := newRope(benchmarkContent())
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			r = r.Insert(10, "new line")
+			r = r.Delete(10)
+		}
+	})
+}
+
+func BenchmarkReplaceLine(b *testing.B) {
+	b.Run("slice", func(b *testing.B) {
+		lines := benchmarkContent()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			// A snapshot for undo needs a copy of the whole slice
+			lines = slices.Clone(lines)
+			lines[i%benchmarkLines] = "changed"
+		}
+	})
+	b.Run("rope", func(b *testing.B) {
+		r := newRope(benchmarkContent())
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			r = r.Set(i%benchmarkLines, "changed")
+		}
+	})
+}
+
+func BenchmarkLineAccess(b *testing.B) {
+	b.Run("slice", func(b *testing.B) {
+		lines := benchmarkContent()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_ = lines[i%benchmarkLines]
+		}
+	})
+	b.Run("rope", func(b *testing.B) {
+		r := newRope(benchmarkContent())
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_ = r.Line(i % benchmarkLines)
+		}
+	})
+}
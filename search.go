@@ -0,0 +1,352 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// searchState is the last search and the prompt used to type a new one.
+type searchState struct {
+	pattern  string
+	backward bool
+	// wholeWord matches only whole words, used by * and #
+	wholeWord bool
+	// highlight shows the matches until it's turned off with esc or :noh
+	highlight bool
+
+	// prompt is set while a pattern is typed in the command line
+	prompt         bool
+	promptBackward bool
+	// origin is the cursor position from before the pattern was typed
+	origin position
+}
+
+// compileSearch compiles the pattern. It ignores case unless the pattern
+// contains an upper case letter (smartcase).
+func compileSearch(pattern string) (*regexp.Regexp, error) {
+	if !strings.ContainsFunc(pattern, unicode.IsUpper) {
+		pattern = "(?i)" + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+// searchMatches returns all matches of the pattern in the lines from first
+// to last.
+func (b buffer) searchMatches(re *regexp.Regexp, wholeWord bool, first, last int) []textRange {
+	var matches []textRange
+	for y := max(0, first); y <= last && y < b.NoOfLines(); y++ {
+		for _, loc := range lineMatches(b.Line(y), re, wholeWord) {
+			matches = append(matches, textRange{
+				start: position{line: y, col: loc[0]},
+				end:   position{line: y, col: loc[1]},
+			})
+		}
+	}
+	return matches
+}
+
+// lineMatches returns the byte offsets of the matches in the line.
+func lineMatches(line string, re *regexp.Regexp, wholeWord bool) [][]int {
+	locs := re.FindAllStringIndex(line, -1)
+	if wholeWord {
+		locs = slices.DeleteFunc(locs, func(loc []int) bool {
+			return !isWholeWord(line, loc[0], loc[1])
+		})
+	}
+	return locs
+}
+
+// isWholeWord reports whether the text between start and end isn't a part
+// of a longer word.
+func isWholeWord(line string, start, end int) bool {
+	isWordRune := func(r rune) bool {
+		return !slices.Contains(nextWordSkipCharacters(), r)
+	}
+	if r, size := utf8.DecodeLastRuneInString(line[:start]); size > 0 && isWordRune(r) {
+		return false
+	}
+	if r, size := utf8.DecodeRuneInString(line[end:]); size > 0 && isWordRune(r) {
+		return false
+	}
+	return true
+}
+
+// wordAt returns the word under the cursor and the byte offset it starts at.
+func wordAt(line string, x int) (string, int, bool) {
+	isWordRune := func(r rune) bool {
+		return !slices.Contains(nextWordSkipCharacters(), r)
+	}
+	if r, size := utf8.DecodeRuneInString(line[min(x, len(line)):]); size == 0 || !isWordRune(r) {
+		return "", 0, false
+	}
+
+	start, end := x, x
+	for start > 0 {
+		r, size := utf8.DecodeLastRuneInString(line[:start])
+		if !isWordRune(r) {
+			break
+		}
+		start -= size
+	}
+	for end < len(line) {
+		r, size := utf8.DecodeRuneInString(line[end:])
+		if !isWordRune(r) {
+			break
+		}
+		end += size
+	}
+	return line[start:end], start, true
+}
+
+// findMatch returns the first match after the position, or the last one
+// before it when searching backward. The search wraps around the end of the
+// buffer. Lines are searched going away from the position, so the rest of
+// the buffer isn't read once a match is found.
+func (b buffer) findMatch(re *regexp.Regexp, wholeWord bool, from position, backward bool) (textRange, bool, bool) {
+	n := b.NoOfLines()
+	// The line of the position is searched again last, for the matches on
+	// its other side
+	for i := 0; i <= n; i++ {
+		y := from.line + i
+		if backward {
+			y = from.line - i
+		}
+		wrapped := y < 0 || y >= n
+		y = (y + n) % n
+
+		matches := b.searchMatches(re, wholeWord, y, y)
+		if backward {
+			slices.Reverse(matches)
+		}
+		for _, r := range matches {
+			switch {
+			case i > 0:
+			case backward && r.start.before(from):
+			case !backward && from.before(r.start):
+			default:
+				continue
+			}
+			return r, true, wrapped || i == n
+		}
+	}
+	return textRange{}, false, false
+}
+
+// activeSearch returns the pattern whose matches are highlighted. While a
+// pattern is typed it's the pattern in the command line.
+func (m model) activeSearch() (*regexp.Regexp, bool) {
+	pattern, wholeWord := m.search.pattern, m.search.wholeWord
+	switch {
+	case m.mode == ModeCommand && m.search.prompt:
+		pattern, wholeWord = m.commandBuffer, false
+	case !m.search.highlight:
+		return nil, false
+	}
+	if pattern == "" {
+		return nil, false
+	}
+
+	re, err := m.searchCache.compile(pattern)
+	if err != nil {
+		return nil, false
+	}
+	return re, wholeWord
+}
+
+// searchHighlights adds highlights for the matches on the visible lines.
+func (m model) searchHighlights(b buffer) buffer {
+	re, wholeWord := m.activeSearch()
+	if re == nil {
+		return b
+	}
+
+	cur := position{line: b.cursorY, col: b.cursorX}
+	for _, r := range b.searchMatches(re, wholeWord, b.cursorYOffset, b.cursorYOffset+b.viewport.Height) {
+		style := m.style.search
+		if r.start == cur {
+			style = m.style.searchCurrent
+		}
+		b.highlights = append(b.highlights, highlight{textRange: r, style: style})
+	}
+	return b
+}
+
+// searchCounter returns the match counter for the status bar: the match at
+// or before the cursor and the number of matches.
+func (m model) searchCounter(b buffer) string {
+	re, wholeWord := m.activeSearch()
+	if re == nil {
+		return ""
+	}
+
+	counts := m.searchCache.matchCounts(b.lines, re, wholeWord)
+	total := counts[len(counts)-1]
+	if total == 0 {
+		return ""
+	}
+	current := counts[b.cursorY]
+	for _, loc := range lineMatches(b.Line(b.cursorY), re, wholeWord) {
+		if loc[0] <= b.cursorX {
+			current++
+		}
+	}
+	return fmt.Sprintf("[%d/%d]", current, total)
+}
+
+// searchCache keeps the compiled search pattern and the number of its
+// matches on the lines of the text they were counted in, so a redraw doesn't
+// search the whole buffer again. Ropes are immutable, the same root is the
+// same text.
+type searchCache struct {
+	pattern string
+	re      *regexp.Regexp
+	err     error
+
+	lines     rope
+	wholeWord bool
+	// counts[y] is the number of matches before the line y, the last one
+	// is the number of all matches
+	counts []int
+}
+
+// compile returns the compiled pattern. It's compiled again only when the
+// pattern changes.
+func (c *searchCache) compile(pattern string) (*regexp.Regexp, error) {
+	if c == nil {
+		return compileSearch(pattern)
+	}
+	if c.pattern != pattern || (c.re == nil && c.err == nil) {
+		c.pattern = pattern
+		c.re, c.err = compileSearch(pattern)
+		c.counts = nil
+	}
+	return c.re, c.err
+}
+
+// matchCounts returns the number of matches before every line.
+func (c *searchCache) matchCounts(lines rope, re *regexp.Regexp, wholeWord bool) []int {
+	if c != nil && c.counts != nil && c.re == re && c.wholeWord == wholeWord && c.lines == lines {
+		return c.counts
+	}
+
+	counts := make([]int, 1, lines.Len()+1)
+	lines.each(func(line string) bool {
+		counts = append(counts, counts[len(counts)-1]+len(lineMatches(line, re, wholeWord)))
+		return true
+	})
+	if c != nil && c.re == re {
+		c.lines, c.wholeWord, c.counts = lines, wholeWord, counts
+	}
+	return counts
+}
+
+// StartSearch opens the command line for typing a search pattern.
+func (m model) StartSearch(backward bool) model {
+	b := m.CurrentBuffer()
+	m.mode = ModeCommand
+	m.commandBuffer = ""
+	m.search.prompt = true
+	m.search.promptBackward = backward
+	m.search.origin = position{line: b.cursorY, col: b.cursorX}
+	return m
+}
+
+// previewSearch moves the cursor to the first match of the pattern typed so
+// far.
+func (m model) previewSearch() model {
+	b := m.CurrentBuffer()
+	origin := m.search.origin
+	b = b.moveCursorTo(origin.col, origin.line)
+
+	if re, err := m.searchCache.compile(m.commandBuffer); err == nil && m.commandBuffer != "" {
+		if r, ok, _ := b.findMatch(re, false, origin, m.search.promptBackward); ok {
+			b = b.moveCursorTo(r.start.col, r.start.line)
+		}
+	}
+
+	m.buffers[m.currBuffer] = b
+	return m
+}
+
+// cancelSearch closes the search prompt and moves the cursor back.
+func (m model) cancelSearch() model {
+	origin := m.search.origin
+	m.buffers[m.currBuffer] = m.CurrentBuffer().moveCursorTo(origin.col, origin.line)
+	m.search.prompt = false
+	m.commandBuffer = ""
+	m.mode = ModeNormal
+	return m
+}
+
+// finishSearch searches for the typed pattern. An empty pattern repeats the
+// last search.
+func (m model) finishSearch() (tea.Model, tea.Cmd) {
+	pattern := m.commandBuffer
+	m.search.prompt = false
+	m.commandBuffer = ""
+	m.mode = ModeNormal
+
+	origin := m.search.origin
+	m.buffers[m.currBuffer] = m.CurrentBuffer().moveCursorTo(origin.col, origin.line)
+
+	if pattern != "" {
+		m.search.pattern = pattern
+		m.search.wholeWord = false
+	}
+	m.search.backward = m.search.promptBackward
+
+	return m.searchNext(false), nil
+}
+
+// searchNext jumps to the next match of the last search. reverse searches
+// in the other direction.
+func (m model) searchNext(reverse bool) model {
+	if m.search.pattern == "" {
+		return m.SetErrorMessage("No previous search pattern")
+	}
+
+	re, err := m.searchCache.compile(m.search.pattern)
+	if err != nil {
+		return m.SetErrorMessage("Invalid pattern: " + err.Error())
+	}
+
+	m.search.highlight = true
+	backward := m.search.backward != reverse
+
+	b := m.CurrentBuffer()
+	r, ok, wrapped := b.findMatch(re, m.search.wholeWord, position{line: b.cursorY, col: b.cursorX}, backward)
+	if !ok {
+		return m.SetErrorMessage("Pattern not found: " + m.search.pattern)
+	}
+
+	m.buffers[m.currBuffer] = b.moveCursorTo(r.start.col, r.start.line)
+	if wrapped {
+		if backward {
+			return m.SetInfoMessage("Search hit TOP, continuing at BOTTOM")
+		}
+		return m.SetInfoMessage("Search hit BOTTOM, continuing at TOP")
+	}
+	return m
+}
+
+// searchWord searches for the whole word under the cursor.
+func (m model) searchWord(backward bool) model {
+	b := m.CurrentBuffer()
+	word, start, ok := wordAt(b.Line(b.cursorY), b.cursorX)
+	if !ok {
+		return m.SetErrorMessage("No word under the cursor")
+	}
+	// Searching backward from the middle of the word would find the word
+	m.buffers[m.currBuffer] = b.moveCursorTo(start, b.cursorY)
+
+	m.search.pattern = regexp.QuoteMeta(word)
+	m.search.wholeWord = true
+	m.search.backward = backward
+	return m.searchNext(false)
+}
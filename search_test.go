@@ -0,0 +1,165 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestIncrementalSearch(t *testing.T) {
+	m := visualTestModel("one\nfoo two\nthree foo\nfour")
+
+	m = pressKeys(m, runeKeys("/fo")...)
+	if m.mode != ModeCommand || !strings.Contains(m.View(), "/fo") {
+		t.Fatalf("Expected the search prompt, got mode %s", m.mode)
+	}
+	if got := cursorOf(m); got != (position{line: 1}) {
+		t.Errorf("Expected the cursor to follow the first match, got %v", got)
+	}
+
+	m = pressKeys(m, runeKeys("ur")...)
+	if got := cursorOf(m); got != (position{line: 3}) {
+		t.Errorf("Expected the cursor to follow the pattern, got %v", got)
+	}
+
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyBackspace}, tea.KeyMsg{Type: tea.KeyBackspace})
+	m = pressKeys(m, runeKeys("o")...)
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.mode != ModeNormal || cursorOf(m) != (position{line: 1}) {
+		t.Fatalf("Expected the search to end at the first foo, got %s at %v", m.mode, cursorOf(m))
+	}
+	if !strings.Contains(m.View(), "[1/2]") {
+		t.Errorf("Expected the match counter in the status bar")
+	}
+
+	m = pressKeys(m, runeKeys("n")...)
+	if got := cursorOf(m); got != (position{line: 2, col: 6}) {
+		t.Errorf("Expected n to go to the second match, got %v", got)
+	}
+	if !strings.Contains(m.View(), "[2/2]") {
+		t.Errorf("Expected the match counter to be updated")
+	}
+
+	m = pressKeys(m, runeKeys("n")...)
+	if got := cursorOf(m); got != (position{line: 1}) {
+		t.Errorf("Expected n to wrap around, got %v", got)
+	}
+	if m.currentMessage == nil || !strings.Contains(m.currentMessage.text, "BOTTOM") {
+		t.Errorf("Expected a message about wrapping around")
+	}
+
+	m = pressKeys(m, runeKeys("N")...)
+	if got := cursorOf(m); got != (position{line: 2, col: 6}) {
+		t.Errorf("Expected N to wrap around backward, got %v", got)
+	}
+}
+
+func TestCancelSearch(t *testing.T) {
+	m := visualTestModel("one\ntwo")
+
+	m = pressKeys(m, runeKeys("/two")...)
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEsc})
+	if m.mode != ModeNormal || cursorOf(m) != (position{}) {
+		t.Errorf("Expected the cursor to go back, got %s at %v", m.mode, cursorOf(m))
+	}
+	if m.search.pattern != "" {
+		t.Errorf("Expected the pattern not to be stored, got %q", m.search.pattern)
+	}
+}
+
+func TestSearchBackward(t *testing.T) {
+	m := visualTestModel("a x\nb x\nc x")
+	m.buffers[0] = m.buffers[0].moveCursorTo(0, 1)
+
+	m = pressKeys(m, runeKeys("?x")...)
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if got := cursorOf(m); got != (position{col: 2}) {
+		t.Errorf("Expected ? to search backward, got %v", got)
+	}
+
+	// n keeps the direction of the search
+	m = pressKeys(m, runeKeys("n")...)
+	if got := cursorOf(m); got != (position{line: 2, col: 2}) {
+		t.Errorf("Expected n to continue backward and wrap, got %v", got)
+	}
+}
+
+func TestSmartcase(t *testing.T) {
+	m := visualTestModel("Foo\nfoo")
+	m.buffers[0] = m.buffers[0].moveCursorTo(0, 1)
+
+	m = pressKeys(m, runeKeys("/foo")...)
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if got := cursorOf(m); got != (position{}) {
+		t.Errorf("Expected a lower case pattern to ignore case, got %v", got)
+	}
+
+	m = pressKeys(m, runeKeys("/Foo")...)
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if got := cursorOf(m); got != (position{}) {
+		t.Errorf("Expected an upper case pattern to match case, got %v", got)
+	}
+	if m.currentMessage == nil || !strings.Contains(m.currentMessage.text, "BOTTOM") {
+		t.Errorf("Expected Foo to be found only after wrapping around")
+	}
+}
+
+func TestSearchWordUnderCursor(t *testing.T) {
+	m := visualTestModel("foo.bar foobar\nbar(foo)")
+	m.buffers[0] = m.buffers[0].moveCursorTo(5, 0)
+
+	m = pressKeys(m, runeKeys("*")...)
+	if got := cursorOf(m); got != (position{line: 1}) {
+		t.Errorf("Expected * to skip foobar, got %v", got)
+	}
+
+	m = pressKeys(m, runeKeys("#")...)
+	if got := cursorOf(m); got != (position{col: 4}) {
+		t.Errorf("Expected # to go back to the first bar, got %v", got)
+	}
+}
+
+func TestSearchHighlights(t *testing.T) {
+	m := visualTestModel("ab ab ab")
+
+	m = pressKeys(m, runeKeys("/ab")...)
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEnter})
+
+	b, counter := m.searchHighlights(m.CurrentBuffer()), m.searchCounter(m.CurrentBuffer())
+	if len(b.highlights) != 3 || counter != "[2/3]" {
+		t.Errorf("Expected 3 highlights and [2/3], got %d and %q", len(b.highlights), counter)
+	}
+
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEsc})
+	if b := m.searchHighlights(m.CurrentBuffer()); len(b.highlights) != 0 {
+		t.Errorf("Expected esc to hide the highlights")
+	}
+
+	m = pressKeys(m, runeKeys("n")...)
+	m.commandBuffer = "noh"
+	newModel, _ := m.updateCommand(tea.KeyMsg{Type: tea.KeyEnter})
+	if b := newModel.(model).searchHighlights(m.CurrentBuffer()); len(b.highlights) != 0 {
+		t.Errorf("Expected :noh to hide the highlights")
+	}
+}
+
+func TestSearchHighlightsVisibleLines(t *testing.T) {
+	m := visualTestModel(strings.Repeat("ab\n", 100))
+
+	m = pressKeys(m, runeKeys("/ab")...)
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEnter})
+	b := m.searchHighlights(m.CurrentBuffer())
+	if len(b.highlights) > b.viewport.Height+1 {
+		t.Errorf("Expected only the visible lines highlighted, got %d", len(b.highlights))
+	}
+	if counter := m.searchCounter(m.CurrentBuffer()); counter != "[2/100]" {
+		t.Errorf("Expected [2/100], got %q", counter)
+	}
+
+	// The cached count follows the changes of the buffer
+	m = pressKeys(m, runeKeys("dd")...)
+	if counter := m.searchCounter(m.CurrentBuffer()); counter != "[2/99]" {
+		t.Errorf("Expected [2/99] after deleting a line, got %q", counter)
+	}
+}
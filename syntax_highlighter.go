@@ -107,7 +107,7 @@ func (s editorStyle) GetTokenStyle(tokenType string) lipgloss.Style {
 // HighlightLine applies syntax highlighting to a single line
 func (b buffer) HighlightLine(lineIndex int) string {
 	if b.parser == nil {
-		return b.lines[lineIndex]
+		return b.Line(lineIndex)
 	}
 
 	// Get the content up to and including this line
@@ -116,7 +116,7 @@ func (b buffer) HighlightLine(lineIndex int) string {
 		if i > 0 {
 			content.WriteString("\n")
 		}
-		content.WriteString(b.lines[i])
+		content.WriteString(b.Line(i))
 	}
 
 	fullContent := content.String()
@@ -128,12 +128,12 @@ func (b buffer) HighlightLine(lineIndex int) string {
 	// Find tokens that belong to this line
 	lineStart := 0
 	for i := 0; i < lineIndex; i++ {
-		lineStart += len(b.lines[i]) + 1 // +1 for newline
+		lineStart += len(b.Line(i)) + 1 // +1 for newline
 	}
-	lineEnd := lineStart + len(b.lines[lineIndex])
+	lineEnd := lineStart + len(b.Line(lineIndex))
 
 	// Apply highlighting to the line
-	return b.applyHighlighting(b.lines[lineIndex], tokens, lineStart, lineEnd)
+	return b.applyHighlighting(b.Line(lineIndex), tokens, lineStart, lineEnd)
 }
 
 // applyHighlighting applies token highlighting to a line
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var errLastTab = errors.New("Cannot close last tab page")
+
+// tabWindows returns the windows of the current tab page. A screen that
+// isn't split is stored as a set with a single window.
+func (m model) tabWindows() windowSet {
+	m = m.saveWindow()
+	if len(m.windows.windows) > 0 {
+		return m.windows
+	}
+	return windowSet{windows: []window{{buffer: m.currBuffer, view: m.buffers[m.currBuffer].view}}}
+}
+
+// saveTab stores the windows of the current tab page. The tab pages are
+// tracked from the first :tabnew on, before that there's only one.
+func (m model) saveTab() model {
+	if len(m.tabs) == 0 {
+		m.tabs = []windowSet{m.tabWindows()}
+		return m
+	}
+	m.tabs = slices.Clone(m.tabs)
+	m.tabs[m.currTab] = m.tabWindows()
+	return m
+}
+
+// loadTab shows the windows of the current tab page.
+func (m model) loadTab() model {
+	ws := m.tabs[m.currTab]
+	m.windows = ws
+	m = m.loadWindow()
+	if len(ws.windows) == 1 {
+		m.windows = windowSet{}
+	}
+	return m.resizeWindows()
+}
+
+// newTab opens a tab page after the current one with a new empty buffer.
+func (m model) newTab() model {
+	m = m.saveTab()
+	m = m.addBuffer(newBuffer(m.style))
+
+	m.currTab++
+	m.tabs = slices.Insert(m.tabs, m.currTab, windowSet{windows: []window{{buffer: len(m.buffers) - 1}}})
+	return m.loadTab()
+}
+
+// selectTab goes to the tab page.
+func (m model) selectTab(i int) model {
+	if len(m.tabs) < 2 || i == m.currTab {
+		return m
+	}
+	m = m.saveTab()
+	m.currTab = i
+	return m.loadTab()
+}
+
+// cycleTab goes to the next tab page, or to the previous one when n is
+// negative. It wraps around at the ends.
+func (m model) cycleTab(n int) model {
+	if len(m.tabs) < 2 {
+		return m
+	}
+	return m.selectTab(((m.currTab+n)%len(m.tabs) + len(m.tabs)) % len(m.tabs))
+}
+
+// closeTab closes the tab page with its windows, the buffers stay loaded.
+// The tab page after it becomes current, or the one before when it was the
+// last one.
+func (m model) closeTab(i int) (model, error) {
+	if len(m.tabs) < 2 {
+		return m, errLastTab
+	}
+	m = m.saveTab()
+
+	m.tabs = slices.Delete(m.tabs, i, i+1)
+	if m.currTab > i || m.currTab == len(m.tabs) {
+		m.currTab--
+	}
+	m = m.loadTab()
+
+	if len(m.tabs) == 1 {
+		m.tabs = nil
+		m.currTab = 0
+	}
+	return m, nil
+}
+
+// tablineHeight is the number of lines taken by the tabline. It's shown
+// only with more than one tab page.
+func (m model) tablineHeight() int {
+	if len(m.tabs) > 1 {
+		return 1
+	}
+	return 0
+}
+
+// tabLabel is the name of the file in the focused window of the tab page.
+// The + marks a tab page showing a modified buffer.
+func (m model) tabLabel(i int) string {
+	ws := m.tabs[i]
+	if i == m.currTab {
+		ws = m.tabWindows()
+	}
+
+	b := m.buffers[ws.windows[ws.curr].buffer]
+	name := "[No name]"
+	if b.filename != "" {
+		name = filepath.Base(b.filename)
+	}
+
+	for _, w := range ws.windows {
+		if m.buffers[w.buffer].state == bufferStateModified {
+			return fmt.Sprintf(" %d + %s ", i+1, name)
+		}
+	}
+	return fmt.Sprintf(" %d %s ", i+1, name)
+}
+
+// tablineView renders the tab pages in a single line.
+func (m model) tablineView() string {
+	var line strings.Builder
+	used := 0
+	for i := range m.tabs {
+		label := m.tabLabel(i)
+		if m.viewport.Width > 0 && used+lipgloss.Width(label) > m.viewport.Width {
+			break
+		}
+
+		s := m.style.tabline
+		if i == m.currTab {
+			s = m.style.tablineSelected
+		}
+		line.WriteString(s.Render(label))
+		used += lipgloss.Width(label)
+	}
+	if used < m.viewport.Width {
+		line.WriteString(m.style.tabline.Render(strings.Repeat(" ", m.viewport.Width-used)))
+	}
+	return line.String()
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTabPages(t *testing.T) {
+	m := windowTestModel("one\ntwo")
+	m = runCommand(m, "vsplit")
+
+	m = runCommand(m, "tabnew")
+	if len(m.tabs) != 2 || m.currTab != 1 || m.hasSplits() {
+		t.Fatalf("Expected a new tab page with a single window, got %d tab pages", len(m.tabs))
+	}
+	if m.CurrentBuffer().filename != "" || len(m.buffers) != 2 {
+		t.Errorf("Expected the tab page to show a new empty buffer")
+	}
+	if got := m.CurrentBuffer().Viewport().Height; got != 23 {
+		t.Errorf("Expected the tabline to take a line, got height %d", got)
+	}
+
+	m = pressKeys(m, runeKeys("gt")...)
+	if m.currTab != 0 || len(m.windows.windows) != 2 || m.CurrentBuffer().filename != "file.txt" {
+		t.Errorf("Expected gt to wrap around to the first tab page with its layout, got %d", m.currTab)
+	}
+
+	m = pressKeys(m, runeKeys("gT")...)
+	if m.currTab != 1 || m.hasSplits() {
+		t.Errorf("Expected gT to go back, got %d", m.currTab)
+	}
+
+	m = runCommand(m, "tabnext 1")
+	if m.currTab != 0 {
+		t.Errorf("Expected :tabnext to go to the tab page with the number, got %d", m.currTab)
+	}
+	m = runCommand(m, "tabnext 3")
+	if m.currentMessage == nil || m.currentMessage.text != "Tab page 3 does not exist" {
+		t.Errorf("Expected an error, got %v", m.currentMessage)
+	}
+}
+
+func TestTabline(t *testing.T) {
+	m := windowTestModel("one")
+	if strings.Contains(m.View(), " 1 file.txt ") {
+		t.Errorf("Expected no tabline with a single tab page")
+	}
+
+	m = pressKeys(m, runeKeys("dd")...)
+	m = runCommand(m, "tabnew")
+	view := m.View()
+	if !strings.Contains(view, " 1 + file.txt ") || !strings.Contains(view, " 2 [No name] ") {
+		t.Errorf("Expected the tab pages in the tabline, got\n%s", view)
+	}
+	if first, _, _ := strings.Cut(view, "\n"); !strings.Contains(first, "file.txt") {
+		t.Errorf("Expected the tabline at the top, got %q", first)
+	}
+}
+
+func TestCloseTab(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "other.txt")
+	if err := os.WriteFile(file, []byte("other"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := windowTestModel("one")
+	m = runCommand(m, "tabnew "+file)
+	if len(m.buffers) != 2 || m.CurrentBuffer().filename != file {
+		t.Fatalf("Expected the file to be opened in the new tab page, got %q", bufferNames(m))
+	}
+	m = runCommand(m, "tabnew")
+	m = runCommand(m, "tabnext 2")
+
+	m = runCommand(m, "tabclose")
+	if len(m.tabs) != 2 || m.currTab != 1 || m.CurrentBuffer().filename != "" {
+		t.Errorf("Expected the next tab page to become current, got %d", m.currTab)
+	}
+
+	m = runCommand(m, "q")
+	if len(m.tabs) != 0 || m.CurrentBuffer().filename != "file.txt" || m.CurrentBuffer().Viewport().Height != 24 {
+		t.Errorf("Expected :q to close the tab page, got %d tab pages", len(m.tabs))
+	}
+
+	m = runCommand(m, "tabclose")
+	if m.currentMessage == nil || m.currentMessage.text != "Cannot close last tab page" {
+		t.Errorf("Expected an error, got %v", m.currentMessage)
+	}
+}
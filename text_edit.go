@@ -0,0 +1,105 @@
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+// textEdit replaces the text between start and end, in bytes, with text.
+type textEdit struct {
+	start, end position
+	text       string
+}
+
+type lspTextEdit struct {
+	Range   lspRange `json:"range"`
+	NewText string   `json:"newText"`
+}
+
+// textEditFromLSP converts the UTF-16 positions of the edit to bytes.
+func (b buffer) textEditFromLSP(e lspTextEdit) textEdit {
+	return textEdit{start: b.bytePosition(e.Range.Start), end: b.bytePosition(e.Range.End), text: e.NewText}
+}
+
+// sortTextEdits orders the edits by their position. Edits inserting at the
+// same position keep their order.
+func sortTextEdits(edits []textEdit) []textEdit {
+	edits = slices.Clone(edits)
+	slices.SortStableFunc(edits, func(a, b textEdit) int {
+		if a.start.before(b.start) {
+			return -1
+		}
+		if b.start.before(a.start) {
+			return 1
+		}
+		return 0
+	})
+	return edits
+}
+
+// applyTextEdits makes the edits, which mustn't overlap. Their positions
+// are in the text before any of them is made, like the ones sent by the
+// language servers.
+func (b buffer) applyTextEdits(edits []textEdit) buffer {
+	edits = sortTextEdits(edits)
+	for _, e := range slices.Backward(edits) {
+		b = b.replaceText(e)
+	}
+	return b
+}
+
+// replaceText replaces the text of the edit. Positions past the end of the
+// buffer are moved to its end.
+func (b buffer) replaceText(e textEdit) buffer {
+	clamp := func(p position) position {
+		if p.line >= b.NoOfLines() {
+			last := b.NoOfLines() - 1
+			return position{line: last, col: len(b.Line(last))}
+		}
+		return position{line: p.line, col: min(p.col, len(b.Line(p.line)))}
+	}
+	start, end := clamp(e.start), clamp(e.end)
+	if end.before(start) {
+		end = start
+	}
+
+	text := b.Line(start.line)[:start.col] + e.text + b.Line(end.line)[end.col:]
+	for y := end.line; y > start.line; y-- {
+		b = b.DeleteLine(y)
+	}
+	lines := strings.Split(text, "\n")
+	b = b.ReplaceLine(start.line, lines[0])
+	for i, line := range lines[1:] {
+		b = b.InsertLine(start.line+1+i, line)
+	}
+	return b
+}
+
+// positionAfterEdits maps the position in the text before the edits to the
+// text after them. Only the edits ending before the position move it.
+func positionAfterEdits(p position, edits []textEdit) position {
+	// Positions on lastLine from lastCol on are moved by colDelta, all the
+	// lines after it by lineDelta
+	lineDelta, colDelta, lastLine := 0, 0, -1
+	move := func(q position) position {
+		moved := position{line: q.line + lineDelta, col: q.col}
+		if q.line == lastLine {
+			moved.col += colDelta
+		}
+		return moved
+	}
+
+	for _, e := range sortTextEdits(edits) {
+		if p.before(e.end) {
+			break
+		}
+		start := move(e.start)
+		lines := strings.Split(e.text, "\n")
+		end := position{line: start.line + len(lines) - 1, col: len(lines[len(lines)-1])}
+		if len(lines) == 1 {
+			end.col += start.col
+		}
+		lineDelta, colDelta, lastLine = end.line-e.end.line, end.col-e.end.col, e.end.line
+	}
+	return move(p)
+}
@@ -0,0 +1,397 @@
+package main
+
+import (
+	"slices"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// textObjectSpec describes the syntax tree nodes a text object is made of.
+type textObjectSpec struct {
+	// nodes are the kinds of nodes the object selects. For arguments they
+	// are the kinds of the lists holding the arguments.
+	nodes []string
+	// bodies are the kinds of the inner part of a node, used when the node
+	// doesn't have a body field.
+	bodies []string
+}
+
+// Text object keys used after i and a.
+const (
+	textObjectFunction = 'f'
+	textObjectClass    = 'c'
+	textObjectArgument = 'a'
+	textObjectBlock    = 'b'
+	textObjectComment  = '/'
+)
+
+// textObjects maps language names, as returned by languageName, to the
+// nodes of their text objects.
+var textObjects = map[string]map[rune]textObjectSpec{
+	"go": {
+		textObjectFunction: {nodes: []string{"function_declaration", "method_declaration", "func_literal"}, bodies: []string{"block"}},
+		textObjectClass:    {nodes: []string{"type_declaration"}, bodies: []string{"field_declaration_list", "interface_type"}},
+		textObjectArgument: {nodes: []string{"parameter_list", "argument_list", "type_parameter_list", "type_arguments"}},
+		textObjectBlock:    {nodes: []string{"block"}},
+		textObjectComment:  {nodes: []string{"comment"}},
+	},
+	"c": {
+		textObjectFunction: {nodes: []string{"function_definition"}, bodies: []string{"compound_statement"}},
+		textObjectClass:    {nodes: []string{"struct_specifier", "union_specifier", "enum_specifier"}, bodies: []string{"field_declaration_list", "enumerator_list"}},
+		textObjectArgument: {nodes: []string{"parameter_list", "argument_list"}},
+		textObjectBlock:    {nodes: []string{"compound_statement"}},
+		textObjectComment:  {nodes: []string{"comment"}},
+	},
+	"cpp": {
+		textObjectFunction: {nodes: []string{"function_definition", "lambda_expression"}, bodies: []string{"compound_statement"}},
+		textObjectClass:    {nodes: []string{"class_specifier", "struct_specifier", "union_specifier", "enum_specifier"}, bodies: []string{"field_declaration_list", "enumerator_list"}},
+		textObjectArgument: {nodes: []string{"parameter_list", "argument_list", "template_parameter_list", "template_argument_list"}},
+		textObjectBlock:    {nodes: []string{"compound_statement"}},
+		textObjectComment:  {nodes: []string{"comment"}},
+	},
+	"html": {
+		textObjectArgument: {nodes: []string{"start_tag", "self_closing_tag"}},
+		textObjectBlock:    {nodes: []string{"element", "script_element", "style_element"}},
+		textObjectComment:  {nodes: []string{"comment"}},
+	},
+	"java": {
+		textObjectFunction: {nodes: []string{"method_declaration", "constructor_declaration", "lambda_expression"}, bodies: []string{"block", "constructor_body"}},
+		textObjectClass:    {nodes: []string{"class_declaration", "interface_declaration", "enum_declaration", "record_declaration"}, bodies: []string{"class_body", "interface_body", "enum_body"}},
+		textObjectArgument: {nodes: []string{"formal_parameters", "argument_list", "type_arguments", "type_parameters"}},
+		textObjectBlock:    {nodes: []string{"block", "constructor_body", "switch_block"}},
+		textObjectComment:  {nodes: []string{"line_comment", "block_comment"}},
+	},
+	"json": {
+		textObjectArgument: {nodes: []string{"object", "array"}},
+		textObjectBlock:    {nodes: []string{"object", "array"}},
+		textObjectComment:  {nodes: []string{"comment"}},
+	},
+	"python": {
+		textObjectFunction: {nodes: []string{"function_definition", "lambda"}, bodies: []string{"block"}},
+		textObjectClass:    {nodes: []string{"class_definition"}, bodies: []string{"block"}},
+		textObjectArgument: {nodes: []string{"parameters", "argument_list", "lambda_parameters"}},
+		textObjectBlock:    {nodes: []string{"block"}},
+		textObjectComment:  {nodes: []string{"comment"}},
+	},
+	"ruby": {
+		textObjectFunction: {nodes: []string{"method", "singleton_method", "lambda"}, bodies: []string{"body_statement", "block_body"}},
+		textObjectClass:    {nodes: []string{"class", "module", "singleton_class"}, bodies: []string{"body_statement"}},
+		textObjectArgument: {nodes: []string{"method_parameters", "argument_list", "block_parameters", "lambda_parameters"}},
+		textObjectBlock:    {nodes: []string{"do_block", "block", "begin"}, bodies: []string{"body_statement", "block_body"}},
+		textObjectComment:  {nodes: []string{"comment"}},
+	},
+	"rust": {
+		textObjectFunction: {nodes: []string{"function_item", "closure_expression"}, bodies: []string{"block"}},
+		textObjectClass:    {nodes: []string{"struct_item", "enum_item", "union_item", "trait_item", "impl_item"}, bodies: []string{"field_declaration_list", "enum_variant_list", "declaration_list"}},
+		textObjectArgument: {nodes: []string{"parameters", "arguments", "closure_parameters", "type_arguments", "type_parameters"}},
+		textObjectBlock:    {nodes: []string{"block", "match_block", "unsafe_block", "async_block"}},
+		textObjectComment:  {nodes: []string{"line_comment", "block_comment"}},
+	},
+}
+
+// Delimiters around the inner part of a node.
+var (
+	openingDelimiters = []string{"{", "(", "[", "start_tag"}
+	closingDelimiters = []string{"}", ")", "]", "end_tag"}
+)
+
+// commentMarkers are removed from comments by the inner comment object.
+var commentMarkers = []string{"///", "//!", "//", "/*", "#", "<!--"}
+
+func isTextObject(key rune) bool {
+	switch key {
+	case textObjectFunction, textObjectClass, textObjectArgument, textObjectBlock, textObjectComment:
+		return true
+	}
+	return false
+}
+
+// syntaxTree parses the buffer content. The caller has to close the tree.
+func (b buffer) syntaxTree() (*tree_sitter.Tree, []byte) {
+	if b.parser == nil {
+		return nil, nil
+	}
+	content := []byte(b.Content())
+	return b.parser.Parse(content, nil), content
+}
+
+// byteOffset returns the offset of the position in the buffer content.
+func (b buffer) byteOffset(p position) uint {
+	offset := 0
+	for y := range min(p.line, b.NoOfLines()) {
+		offset += len(b.Line(y)) + 1
+	}
+	return uint(offset + min(p.col, len(b.Line(p.line))))
+}
+
+// TextObject returns the range of the text object around the cursor. count
+// selects the count-th enclosing object.
+func (b buffer) TextObject(key rune, inner bool, count int) (textRange, bool) {
+	spec, ok := textObjects[languageName(b.filename)][key]
+	if !ok {
+		return textRange{}, false
+	}
+
+	tree, content := b.syntaxTree()
+	if tree == nil {
+		return textRange{}, false
+	}
+	defer tree.Close()
+
+	offset := b.byteOffset(position{line: b.cursorY, col: b.cursorX})
+	node := tree.RootNode().DescendantForByteRange(offset, offset)
+
+	if key == textObjectArgument {
+		return b.argumentObject(node, spec, inner, count)
+	}
+
+	for i := range max(1, count) {
+		if i > 0 {
+			node = node.Parent()
+		}
+		node = enclosingNode(node, spec.nodes)
+		if node == nil {
+			return textRange{}, false
+		}
+	}
+
+	switch {
+	case key == textObjectComment && inner:
+		return innerComment(node, content), true
+	case key == textObjectComment:
+		return b.fullLines(commentGroup(node, spec.nodes)), true
+	case inner:
+		return b.innerNode(node, spec), true
+	}
+
+	return b.fullLines(nodeRange(node)), true
+}
+
+// enclosingNode returns the node or its closest ancestor of one of the kinds.
+func enclosingNode(n *tree_sitter.Node, kinds []string) *tree_sitter.Node {
+	for ; n != nil; n = n.Parent() {
+		if slices.Contains(kinds, n.Kind()) {
+			return n
+		}
+	}
+	return nil
+}
+
+func nodeRange(n *tree_sitter.Node) textRange {
+	return nodesRange(n, n)
+}
+
+// nodesRange returns the charwise range from the start of the first node to
+// the end of the last one.
+func nodesRange(first, last *tree_sitter.Node) textRange {
+	start, end := first.StartPosition(), last.EndPosition()
+	return textRange{
+		start: position{line: int(start.Row), col: int(start.Column)},
+		end:   position{line: int(end.Row), col: int(end.Column)},
+	}
+}
+
+// fullLines makes the range linewise when it covers whole lines, apart from
+// indentation and trailing white space.
+func (b buffer) fullLines(r textRange) textRange {
+	// Nodes ending with a line break end at the start of the next line
+	if r.end.col == 0 && r.end.line > r.start.line {
+		r.end = position{line: r.end.line - 1, col: len(b.Line(r.end.line - 1))}
+	}
+
+	before := b.Line(r.start.line)[:min(r.start.col, len(b.Line(r.start.line)))]
+	after := b.Line(r.end.line)[min(r.end.col, len(b.Line(r.end.line))):]
+	if strings.TrimSpace(before) == "" && strings.TrimSpace(after) == "" {
+		r.kind = rangeLinewise
+	}
+	return r
+}
+
+// innerNode returns the range of the body of the node without its
+// delimiters.
+func (b buffer) innerNode(n *tree_sitter.Node, spec textObjectSpec) textRange {
+	body := n.ChildByFieldName("body")
+	if body == nil {
+		body = findDescendant(n, spec.bodies)
+	}
+	if body == nil {
+		body = n
+	}
+
+	var open, close *tree_sitter.Node
+	for i := range body.ChildCount() {
+		child := body.Child(i)
+		if open == nil && slices.Contains(openingDelimiters, child.Kind()) {
+			open = child
+		}
+		if slices.Contains(closingDelimiters, child.Kind()) {
+			close = child
+		}
+	}
+	if open == nil || close == nil {
+		return b.fullLines(nodeRange(body))
+	}
+
+	r := textRange{
+		start: nodesRange(open, open).end,
+		end:   nodesRange(close, close).start,
+	}
+
+	// A multiline body is made of the lines between the delimiters
+	if r.start.line < r.end.line &&
+		strings.TrimSpace(b.Line(r.start.line)[r.start.col:]) == "" &&
+		strings.TrimSpace(b.Line(r.end.line)[:r.end.col]) == "" {
+		if r.end.line-r.start.line < 2 {
+			// Nothing between the delimiters
+			return textRange{start: r.start, end: r.start}
+		}
+		return textRange{
+			start: position{line: r.start.line + 1},
+			end:   position{line: r.end.line - 1},
+			kind:  rangeLinewise,
+		}
+	}
+
+	return r
+}
+
+// findDescendant returns the first descendant of one of the kinds, searching
+// the closest ones first.
+func findDescendant(n *tree_sitter.Node, kinds []string) *tree_sitter.Node {
+	queue := []*tree_sitter.Node{n}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for i := range node.NamedChildCount() {
+			child := node.NamedChild(i)
+			if slices.Contains(kinds, child.Kind()) {
+				return child
+			}
+			queue = append(queue, child)
+		}
+	}
+	return nil
+}
+
+// argumentObject returns the range of the argument around the node. The outer
+// object includes the separator, so deleting it leaves a valid list.
+func (b buffer) argumentObject(n *tree_sitter.Node, spec textObjectSpec, inner bool, count int) (textRange, bool) {
+	var arg *tree_sitter.Node
+	for i := range max(1, count) {
+		if i > 0 {
+			n = arg.Parent()
+		}
+		for ; n != nil && n.Parent() != nil; n = n.Parent() {
+			if slices.Contains(spec.nodes, n.Parent().Kind()) {
+				break
+			}
+		}
+		if n == nil || n.Parent() == nil {
+			return textRange{}, false
+		}
+
+		// On a separator or a delimiter, use the closest argument
+		arg = n
+		if !arg.IsNamed() || arg.IsExtra() {
+			arg = n.PrevNamedSibling()
+			if arg == nil {
+				arg = n.NextNamedSibling()
+			}
+		}
+		if arg == nil {
+			return textRange{}, false
+		}
+	}
+
+	r := nodeRange(arg)
+	if inner {
+		return r, true
+	}
+
+	if next := arg.NextSibling(); next != nil && next.Kind() == "," {
+		r.end = nodeRange(next).end
+		if following := next.NextNamedSibling(); following != nil {
+			r.end = nodeRange(following).start
+		}
+		return r, true
+	}
+
+	if prev := arg.PrevSibling(); prev != nil {
+		switch {
+		case prev.Kind() == ",":
+			if before := prev.PrevSibling(); before != nil {
+				r.start = nodeRange(before).end
+			} else {
+				r.start = nodeRange(prev).start
+			}
+		case prev.IsNamed():
+			// Lists without separators, like HTML attributes
+			r.start = nodeRange(prev).end
+		}
+	}
+
+	return r, true
+}
+
+// commentGroup returns the range of the comment together with the comments on
+// the lines right before and after it.
+func commentGroup(n *tree_sitter.Node, kinds []string) textRange {
+	first, last := n, n
+	for {
+		prev := first.PrevSibling()
+		if prev == nil || !slices.Contains(kinds, prev.Kind()) ||
+			prev.EndPosition().Row+1 < first.StartPosition().Row {
+			break
+		}
+		first = prev
+	}
+	for {
+		next := last.NextSibling()
+		if next == nil || !slices.Contains(kinds, next.Kind()) ||
+			last.EndPosition().Row+1 < next.StartPosition().Row {
+			break
+		}
+		last = next
+	}
+	return nodesRange(first, last)
+}
+
+// innerComment returns the text of the comment without its markers and the
+// white space around it.
+func innerComment(n *tree_sitter.Node, content []byte) textRange {
+	start, end := n.StartByte(), n.EndByte()
+	text := string(content[start:end])
+
+	for _, marker := range commentMarkers {
+		if strings.HasPrefix(text, marker) {
+			start += uint(len(marker))
+			break
+		}
+	}
+	for _, marker := range []string{"*/", "-->"} {
+		if strings.HasSuffix(text, marker) {
+			end -= uint(len(marker))
+			break
+		}
+	}
+	for start < end && isBlank(content[start]) {
+		start++
+	}
+	for end > start && isBlank(content[end-1]) {
+		end--
+	}
+
+	return textRange{start: bytePosition(content, start), end: bytePosition(content, end)}
+}
+
+func isBlank(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// bytePosition converts an offset in the content to a position.
+func bytePosition(content []byte, offset uint) position {
+	line := strings.Count(string(content[:offset]), "\n")
+	col := int(offset) - (strings.LastIndex(string(content[:offset]), "\n") + 1)
+	return position{line: line, col: col}
+}
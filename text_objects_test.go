@@ -0,0 +1,258 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTextObjectNodeKindsExist(t *testing.T) {
+	for name, objects := range textObjects {
+		lang := languageByName(name)
+		if lang == nil {
+			t.Errorf("Unknown language %s", name)
+			continue
+		}
+		for key, spec := range objects {
+			for _, kind := range slices.Concat(spec.nodes, spec.bodies) {
+				if lang.IdForNodeKind(kind, true) == 0 {
+					t.Errorf("%s: text object %c uses unknown node kind %q", name, key, kind)
+				}
+			}
+		}
+	}
+}
+
+const textObjectGoSource = `package main
+
+// add returns the sum
+// of both numbers
+func add(a int, b int) int {
+	sum := a + b
+	return sum
+}
+
+type point struct {
+	x, y int
+}
+
+func (p point) String() string {
+	return fmt.Sprint(p.x, p.y)
+}
+`
+
+func TestTextObjects(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		cursor   position
+		keys     string
+		expected string
+	}{
+		{
+			name:    "delete inner function",
+			file:    "main.go",
+			content: textObjectGoSource,
+			cursor:  position{line: 5, col: 2},
+			keys:    "dif",
+			expected: `package main
+
+// add returns the sum
+// of both numbers
+func add(a int, b int) int {
+}
+
+type point struct {
+	x, y int
+}
+
+func (p point) String() string {
+	return fmt.Sprint(p.x, p.y)
+}
+`,
+		},
+		{
+			name:    "delete a method",
+			file:    "main.go",
+			content: textObjectGoSource,
+			cursor:  position{line: 14, col: 3},
+			keys:    "daf",
+			expected: `package main
+
+// add returns the sum
+// of both numbers
+func add(a int, b int) int {
+	sum := a + b
+	return sum
+}
+
+type point struct {
+	x, y int
+}
+
+`,
+		},
+		{
+			name:     "delete inner class",
+			file:     "main.go",
+			content:  "type point struct {\n\tx, y int\n}",
+			cursor:   position{line: 1, col: 1},
+			keys:     "dic",
+			expected: "type point struct {\n}",
+		},
+		{
+			name:     "delete an argument",
+			file:     "main.go",
+			content:  "func add(a int, b int) {}",
+			cursor:   position{col: 9},
+			keys:     "daa",
+			expected: "func add(b int) {}",
+		},
+		{
+			name:     "delete the last argument",
+			file:     "main.go",
+			content:  "func add(a int, b int) {}",
+			cursor:   position{col: 17},
+			keys:     "daa",
+			expected: "func add(a int) {}",
+		},
+		{
+			name:     "change inner argument",
+			file:     "main.rs",
+			content:  "fn main() { add(1, 2); }",
+			cursor:   position{col: 16},
+			keys:     "cia3",
+			expected: "fn main() { add(3, 2); }",
+		},
+		{
+			name:     "delete a comment",
+			file:     "main.go",
+			content:  textObjectGoSource,
+			cursor:   position{line: 2, col: 5},
+			keys:     "da/",
+			expected: strings.Replace(textObjectGoSource, "// add returns the sum\n// of both numbers\n", "", 1),
+		},
+		{
+			name:     "delete inner comment",
+			file:     "main.c",
+			content:  "int x; /* the x */",
+			cursor:   position{col: 10},
+			keys:     "di/",
+			expected: "int x; /*  */",
+		},
+		{
+			name:     "delete inner block",
+			file:     "main.py",
+			content:  "def f():\n    if x:\n        a()\n        b()\n    return 1",
+			cursor:   position{line: 2, col: 8},
+			keys:     "dib",
+			expected: "def f():\n    if x:\n    return 1",
+		},
+		{
+			name:     "uppercase inner python function",
+			file:     "main.py",
+			content:  "def f():\n    return x\n",
+			cursor:   position{line: 1, col: 4},
+			keys:     "gUif",
+			expected: "def f():\n    RETURN X\n",
+		},
+		{
+			name:     "delete outer function with count",
+			file:     "main.go",
+			content:  "func f() {\n\tg := func() {\n\t\treturn\n\t}\n}\nvar x = 1",
+			cursor:   position{line: 2, col: 2},
+			keys:     "2daf",
+			expected: "var x = 1",
+		},
+		{
+			name:     "delete inner html element",
+			file:     "index.html",
+			content:  "<p><b>bold</b> text</p>",
+			cursor:   position{col: 7},
+			keys:     "dib",
+			expected: "<p><b></b> text</p>",
+		},
+		{
+			name:     "delete html attribute",
+			file:     "index.html",
+			content:  `<a href="x" class="y">link</a>`,
+			cursor:   position{col: 14},
+			keys:     "daa",
+			expected: `<a href="x">link</a>`,
+		},
+		{
+			name:     "delete json pair",
+			file:     "data.json",
+			content:  `{"a": 1, "b": 2}`,
+			cursor:   position{col: 2},
+			keys:     "daa",
+			expected: `{"b": 2}`,
+		},
+		{
+			name:     "delete ruby method body",
+			file:     "main.rb",
+			content:  "def hello\n  puts 1\n  puts 2\nend",
+			cursor:   position{line: 1, col: 2},
+			keys:     "dif",
+			expected: "def hello\nend",
+		},
+		{
+			name:     "delete java class",
+			file:     "Main.java",
+			content:  "class A {\n  void f() {}\n}\nclass B {}",
+			cursor:   position{line: 1, col: 3},
+			keys:     "dac",
+			expected: "class B {}",
+		},
+		{
+			name:     "delete c++ function body",
+			file:     "main.cpp",
+			content:  "int main() {\n  return 0;\n}",
+			cursor:   position{line: 1, col: 3},
+			keys:     "dif",
+			expected: "int main() {\n}",
+		},
+		{
+			name:     "no text object without a language",
+			file:     "notes.txt",
+			content:  "some text",
+			keys:     "dif",
+			expected: "some text",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := initialModel()
+			m.buffers[0] = newBuffer(m.style, bufferWithContent(tt.file, tt.content))
+			m.buffers[0] = m.buffers[0].moveCursorTo(tt.cursor.col, tt.cursor.line)
+
+			m = pressKeys(m, runeKeys(tt.keys)...)
+			if got := strings.Join(m.CurrentBuffer().Lines(), "\n"); got != tt.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestVisualTextObject(t *testing.T) {
+	m := initialModel()
+	m.buffers[0] = newBuffer(m.style, bufferWithContent("main.go", textObjectGoSource))
+	m.buffers[0] = m.buffers[0].moveCursorTo(2, 14)
+
+	m = pressKeys(m, runeKeys("vaf")...)
+	if m.mode != ModeVisualLine {
+		t.Fatalf("Expected a whole method to switch to visual line mode, got %s", m.mode)
+	}
+	r := m.Selection()
+	if r.start.line != 13 || r.end.line != 15 {
+		t.Errorf("Expected lines 13-15 to be selected, got %d-%d", r.start.line, r.end.line)
+	}
+
+	m = pressKeys(m, runeKeys("y")...)
+	reg, _ := m.Register('0')
+	if len(reg.lines) != 3 || !strings.HasPrefix(reg.lines[0], "func (p point)") {
+		t.Errorf("Expected the method to be yanked, got %v", reg.lines)
+	}
+}
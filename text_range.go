@@ -0,0 +1,168 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+type position struct {
+	line, col int
+}
+
+func (p position) before(o position) bool {
+	return p.line < o.line || (p.line == o.line && p.col < o.col)
+}
+
+type rangeKind int
+
+const (
+	rangeCharwise rangeKind = iota
+	rangeLinewise
+	rangeBlockwise
+)
+
+// textRange is a part of the buffer an operator works on. For charwise
+// ranges end is exclusive, linewise ranges cover whole lines from
+// start.line to end.line and blockwise ranges cover the screen columns
+// [start.col, end.col) on every line between them. The columns of the other
+// ranges are byte offsets.
+type textRange struct {
+	start, end position
+	kind       rangeKind
+}
+
+// columns returns the byte range of the line y covered by the range. end is
+// -1 when the range continues past the end of the line.
+func (r textRange) columns(y int, line string) (int, int, bool) {
+	if y < r.start.line || y > r.end.line {
+		return 0, 0, false
+	}
+
+	switch r.kind {
+	case rangeLinewise:
+		return 0, -1, true
+	case rangeBlockwise:
+		return columnOffset(line, r.start.col), columnOffset(line, r.end.col), true
+	}
+
+	start, end := 0, -1
+	if y == r.start.line {
+		start = r.start.col
+	}
+	if y == r.end.line {
+		end = r.end.col
+	}
+	return start, end, true
+}
+
+// clampColumns limits the columns returned by columns to the given line.
+func clampColumns(line string, start, end int) (int, int) {
+	if end < 0 || end > len(line) {
+		end = len(line)
+	}
+	start = min(start, end)
+	return start, end
+}
+
+// rangeStart returns the position the range starts at, with the column as a
+// byte offset.
+func (b buffer) rangeStart(r textRange) position {
+	if r.kind == rangeBlockwise {
+		return position{line: r.start.line, col: columnOffset(b.Line(r.start.line), r.start.col)}
+	}
+	return r.start
+}
+
+// Text returns the content of the range as a register.
+func (b buffer) Text(r textRange) register {
+	reg := register{linewise: r.kind == rangeLinewise}
+	for y := r.start.line; y <= r.end.line && y < b.NoOfLines(); y++ {
+		line := b.Line(y)
+		start, end, _ := r.columns(y, line)
+		start, end = clampColumns(line, start, end)
+		reg.lines = append(reg.lines, line[start:end])
+	}
+	return reg
+}
+
+// DeleteRange removes the text covered by the range and moves the cursor to
+// its start.
+func (b buffer) DeleteRange(r textRange) buffer {
+	switch r.kind {
+	case rangeLinewise:
+		for y := r.end.line; y >= r.start.line; y-- {
+			b = b.DeleteLine(y)
+		}
+		y := min(r.start.line, b.NoOfLines()-1)
+		b = b.SetCursorY(y)
+		return b.SetCursorX(firstNonBlank(b.Line(y)))
+	case rangeBlockwise:
+		b = b.MapRange(r, func(string) string { return "" })
+		start := b.rangeStart(r)
+		return b.moveCursorTo(start.col, start.line)
+	}
+
+	first, last := b.Line(r.start.line), b.Line(r.end.line)
+	start, _ := clampColumns(first, r.start.col, -1)
+	_, end := clampColumns(last, 0, r.end.col)
+	b = b.ReplaceLine(r.start.line, first[:start]+last[end:])
+	for y := r.end.line; y > r.start.line; y-- {
+		b = b.DeleteLine(y)
+	}
+
+	return b.moveCursorTo(r.start.col, r.start.line)
+}
+
+// MapRange replaces the text covered by the range on every line with the
+// result of f.
+func (b buffer) MapRange(r textRange, f func(string) string) buffer {
+	for y := r.start.line; y <= r.end.line && y < b.NoOfLines(); y++ {
+		line := b.Line(y)
+		start, end, _ := r.columns(y, line)
+		start, end = clampColumns(line, start, end)
+		b = b.ReplaceLine(y, line[:start]+f(line[start:end])+line[end:])
+	}
+	return b
+}
+
+// IndentLines adds (or removes for negative n) n levels of indentation to
+// every line of the range.
+func (b buffer) IndentLines(r textRange, n int) buffer {
+	for y := r.start.line; y <= r.end.line && y < b.NoOfLines(); y++ {
+		line := b.Line(y)
+		if n > 0 {
+			if line != "" {
+				line = strings.Repeat("\t", n) + line
+			}
+		} else {
+			for range -n {
+				line = unindent(line)
+			}
+		}
+		b = b.ReplaceLine(y, line)
+	}
+
+	return b.moveCursorTo(firstNonBlank(b.Line(r.start.line)), r.start.line)
+}
+
+// unindent removes one tab or up to tabSize spaces from the beginning of the
+// line.
+func unindent(line string) string {
+	if strings.HasPrefix(line, "\t") {
+		return line[1:]
+	}
+	i := 0
+	for i < len(line) && i < tabSize && line[i] == ' ' {
+		i++
+	}
+	return line[i:]
+}
+
+func toggleCase(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsUpper(r) {
+			return unicode.ToLower(r)
+		}
+		return unicode.ToUpper(r)
+	}, s)
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"time"
+)
+
+// undoState is a snapshot of the buffer content together with the cursor
+// position where the change that produced it started. The rope shares
+// unchanged lines with the buffer, so snapshots are cheap.
+type undoState struct {
+	lines            rope
+	cursorX, cursorY int
+}
+
+// undoNode is a single state in the undo tree. Nodes are never removed, so
+// the index of a node is also its change number.
+type undoNode struct {
+	state  undoState
+	parent int
+	// lastChild is the child that was created or visited most recently,
+	// redo follows it.
+	lastChild int
+	time      time.Time
+}
+
+// undoHistory keeps every state of the buffer as a tree. Undoing and then
+// making a new change starts a new branch instead of dropping the old one.
+// nodes[cur] always matches the content of the buffer when no change is in
+// progress.
+type undoHistory struct {
+	nodes []undoNode
+	cur   int
+
+	// depth counts nested beginUndoStep calls, so an insert session or a
+	// multi-line command is recorded as a single step.
+	depth              int
+	pendingX, pendingY int
+
+	// saved is the change number of the state written to the file, -1 when
+	// no state matches the file.
+	saved int
+}
+
+// now is replaced in tests to control the timestamps of undo states.
+var now = time.Now
+
+func (b buffer) snapshot() undoState {
+	return undoState{
+		lines:   b.lines,
+		cursorX: b.cursorX,
+		cursorY: b.cursorY,
+	}
+}
+
+func (h undoHistory) addNode(s undoState) undoHistory {
+	h.nodes = append(h.nodes, undoNode{state: s, parent: h.cur, lastChild: -1, time: now()})
+	h.nodes[h.cur].lastChild = len(h.nodes) - 1
+	h.cur = len(h.nodes) - 1
+	return h
+}
+
+// beginUndoStep marks the start of a change. Every call must be paired with
+// commitUndoStep once the change is done.
+func (b buffer) beginUndoStep() buffer {
+	h := b.history
+	if len(h.nodes) == 0 {
+		h.nodes = []undoNode{{state: b.snapshot(), parent: -1, lastChild: -1, time: now()}}
+		h.cur = 0
+		h.saved = -1
+		if b.state != bufferStateModified {
+			h.saved = 0
+		}
+	} else if h.depth == 0 && !h.nodes[h.cur].state.lines.Equal(b.lines) {
+		// The buffer was changed outside of an undo step, don't lose it.
+		// Inside a step the changes belong to it.
+		h = h.addNode(b.snapshot())
+	}
+
+	if h.depth == 0 {
+		h.pendingX, h.pendingY = b.cursorX, b.cursorY
+	}
+	h.depth++
+	b.history = h
+
+	return b
+}
+
+// commitUndoStep records the buffer content as a new undo state if it was
+// changed since the matching beginUndoStep.
+func (b buffer) commitUndoStep() buffer {
+	h := b.history
+	if h.depth == 0 {
+		return b
+	}
+	h.depth--
+	if h.depth > 0 {
+		b.history = h
+		return b
+	}
+
+	if !h.nodes[h.cur].state.lines.Equal(b.lines) {
+		s := b.snapshot()
+		s.cursorX, s.cursorY = h.pendingX, h.pendingY
+		h = h.addNode(s)
+	}
+	b.history = h
+
+	return b
+}
+
+func (b buffer) CanUndo() bool {
+	return len(b.history.nodes) > 0 && b.history.nodes[b.history.cur].parent >= 0
+}
+
+func (b buffer) CanRedo() bool {
+	return len(b.history.nodes) > 0 && b.history.nodes[b.history.cur].lastChild >= 0
+}
+
+// Undo restores the state before the last change and moves the cursor to
+// the place where the change started.
+func (b buffer) Undo() buffer {
+	if !b.CanUndo() {
+		return b
+	}
+
+	changed := b.history.nodes[b.history.cur]
+	b.history.cur = changed.parent
+	b = b.restoreState(b.history.cur)
+
+	return b.moveCursorTo(changed.state.cursorX, changed.state.cursorY)
+}
+
+// Redo applies the change that was undone last.
+func (b buffer) Redo() buffer {
+	if !b.CanRedo() {
+		return b
+	}
+
+	return b.UndoJump(b.history.nodes[b.history.cur].lastChild)
+}
+
+// UndoJump restores the state with the given change number, which may be on
+// a different branch of the undo tree.
+func (b buffer) UndoJump(n int) buffer {
+	h := b.history
+	if n < 0 || n >= len(h.nodes) || n == h.cur {
+		return b
+	}
+
+	// Make redo follow the path to the restored state.
+	for i := n; h.nodes[i].parent >= 0; i = h.nodes[i].parent {
+		h.nodes[h.nodes[i].parent].lastChild = i
+	}
+	h.cur = n
+	b.history = h
+
+	s := h.nodes[n].state
+	b = b.restoreState(n)
+
+	return b.moveCursorTo(s.cursorX, s.cursorY)
+}
+
+// UndoSeq returns the current change number and the number of the last
+// change in the history.
+func (b buffer) UndoSeq() (int, int) {
+	return b.history.cur, len(b.history.nodes) - 1
+}
+
+// undoSeqAt returns the last change recorded at or before t.
+func (b buffer) undoSeqAt(t time.Time) int {
+	n := 0
+	for i, node := range b.history.nodes {
+		if !node.time.After(t) {
+			n = i
+		}
+	}
+	return n
+}
+
+// restoreState brings back the content of the change. Going back to the
+// state written to the file makes the buffer saved again.
+func (b buffer) restoreState(n int) buffer {
+	b.lines = b.history.nodes[n].state.lines
+	if n == b.history.saved {
+		b.state = bufferStateSaved
+		if b.filename == "" {
+			b.state = bufferStateUnnamed
+		}
+		return b
+	}
+	return b.SetStateModified()
+}
+
+// markSaved remembers the current change as the one matching the file.
+func (h undoHistory) markSaved(lines rope) undoHistory {
+	if len(h.nodes) == 0 {
+		return h
+	}
+	h.saved = -1
+	if h.depth == 0 && h.nodes[h.cur].state.lines.Equal(lines) {
+		h.saved = h.cur
+	}
+	return h
+}
+
+// moveCursorTo places the cursor at the given position, clamped to the
+// buffer content.
+func (b buffer) moveCursorTo(x, y int) buffer {
+	y = max(0, min(y, b.NoOfLines()-1))
+	x = max(0, min(x, len(b.Line(y))))
+	b.cursorY = y
+	return b.SetCursorX(x)
+}
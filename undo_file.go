@@ -0,0 +1,225 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// undoFileVersion is bumped whenever the undo file layout changes. Files
+// with a different version are ignored.
+//
+// Version 2 stores the undo tree instead of a linear list of states.
+// Version 3 stores every state as the change from its parent.
+const undoFileVersion = 3
+
+type undoFile struct {
+	Version int            `json:"version"`
+	Path    string         `json:"path"`
+	Hash    string         `json:"hash"`
+	Cur     int            `json:"cur"`
+	Nodes   []undoFileNode `json:"nodes"`
+}
+
+// undoFileNode is a state of the undo tree. Deleted lines of the parent
+// state from Start on are replaced with Lines. The root has no parent, it
+// stores all of its lines.
+type undoFileNode struct {
+	Start     int       `json:"start"`
+	Deleted   int       `json:"deleted"`
+	Lines     []string  `json:"lines"`
+	CursorX   int       `json:"cursor_x"`
+	CursorY   int       `json:"cursor_y"`
+	Parent    int       `json:"parent"`
+	LastChild int       `json:"last_child"`
+	Time      time.Time `json:"time"`
+}
+
+// undoDir returns the directory where undo files are stored.
+func undoDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "goku", "undo"), nil
+}
+
+// undoFilePath returns the undo file for the given file, keyed by its
+// absolute path.
+func undoFilePath(filename string) (string, error) {
+	dir, err := undoDir()
+	if err != nil {
+		return "", err
+	}
+
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256([]byte(abs))
+	return filepath.Join(dir, hex.EncodeToString(sum[:])+".json"), nil
+}
+
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// saveUndoFile stores the undo history of the buffer next to the hash of
+// the content that was just written to disk.
+func (b buffer) saveUndoFile() error {
+	if b.filename == "" || len(b.history.nodes) == 0 {
+		return nil
+	}
+
+	path, err := undoFilePath(b.filename)
+	if err != nil {
+		return err
+	}
+
+	abs, err := filepath.Abs(b.filename)
+	if err != nil {
+		return err
+	}
+
+	f := undoFile{
+		Version: undoFileVersion,
+		Path:    abs,
+		Hash:    contentHash(b.Content()),
+		Cur:     b.history.cur,
+	}
+	for _, n := range b.history.nodes {
+		var parent rope
+		if n.parent >= 0 {
+			parent = b.history.nodes[n.parent].state.lines
+		}
+		start, deleted, lines := lineDiff(parent, n.state.lines)
+		f.Nodes = append(f.Nodes, undoFileNode{
+			Start:     start,
+			Deleted:   deleted,
+			Lines:     lines,
+			CursorX:   n.state.cursorX,
+			CursorY:   n.state.cursorY,
+			Parent:    n.parent,
+			LastChild: n.lastChild,
+			Time:      n.time,
+		})
+	}
+
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+// loadUndoFile restores the undo history saved for the buffer's file. The
+// history is dropped when the file was changed outside of the editor or was
+// written by an incompatible version.
+func (b buffer) loadUndoFile(content string) buffer {
+	h, err := readUndoFile(b.filename, content)
+	if err != nil {
+		return b
+	}
+
+	if !h.nodes[h.cur].state.lines.Equal(b.lines) {
+		return b
+	}
+
+	h.saved = h.cur
+	b.history = h
+	return b
+}
+
+func readUndoFile(filename, content string) (undoHistory, error) {
+	path, err := undoFilePath(filename)
+	if err != nil {
+		return undoHistory{}, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return undoHistory{}, err
+	}
+
+	var f undoFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return undoHistory{}, err
+	}
+
+	if f.Version != undoFileVersion {
+		return undoHistory{}, errors.New("unsupported undo file version")
+	}
+
+	if f.Hash != contentHash(content) {
+		return undoHistory{}, errors.New("file changed since the undo file was written")
+	}
+
+	if f.Cur < 0 || f.Cur >= len(f.Nodes) {
+		return undoHistory{}, errors.New("corrupted undo file")
+	}
+
+	h := undoHistory{cur: f.Cur}
+	for i, n := range f.Nodes {
+		if n.Parent >= i || n.LastChild >= len(f.Nodes) {
+			return undoHistory{}, errors.New("corrupted undo file")
+		}
+		var parent rope
+		if n.Parent >= 0 {
+			parent = h.nodes[n.Parent].state.lines
+		}
+		if n.Start < 0 || n.Deleted < 0 || n.Start+n.Deleted > parent.Len() {
+			return undoHistory{}, errors.New("corrupted undo file")
+		}
+		h.nodes = append(h.nodes, undoNode{
+			state:     undoState{lines: applyLineDiff(parent, n.Start, n.Deleted, n.Lines), cursorX: n.CursorX, cursorY: n.CursorY},
+			parent:    n.Parent,
+			lastChild: n.LastChild,
+			time:      n.Time,
+		})
+	}
+
+	return h, nil
+}
+
+// lineDiff finds the lines changed between two states: deleted lines of
+// from, starting at start, were replaced with lines. The lines both states
+// start and end with aren't part of it.
+func lineDiff(from, to rope) (start, deleted int, lines []string) {
+	n, m := from.Len(), to.Len()
+	for start < n && start < m && from.Line(start) == to.Line(start) {
+		start++
+	}
+	end := 0
+	for end < n-start && end < m-start && from.Line(n-1-end) == to.Line(m-1-end) {
+		end++
+	}
+	for i := start; i < m-end; i++ {
+		lines = append(lines, to.Line(i))
+	}
+	return start, n - start - end, lines
+}
+
+// applyLineDiff makes the change found by lineDiff.
+func applyLineDiff(r rope, start, deleted int, lines []string) rope {
+	if r.Len() == 0 {
+		return newRope(lines)
+	}
+	for range deleted {
+		r = r.Delete(start)
+	}
+	for i, line := range lines {
+		r = r.Insert(start+i, line)
+	}
+	return r
+}
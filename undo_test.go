@@ -0,0 +1,346 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func pressKeys(m model, keys ...tea.KeyMsg) model {
+	for _, k := range keys {
+		newModel, _ := m.Update(k)
+		m = newModel.(model)
+	}
+	return m
+}
+
+func runeKeys(s string) []tea.KeyMsg {
+	var keys []tea.KeyMsg
+	for _, r := range s {
+		keys = append(keys, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	return keys
+}
+
+func TestUndoDeleteLine(t *testing.T) {
+	m := initialModel()
+	m.viewport = tea.WindowSizeMsg{Width: 80, Height: 24}
+	m.buffers[0] = newBuffer(m.style, bufferWithContent("", "line 1\nline 2\nline 3"))
+	m.buffers[0] = m.buffers[0].SetCursorY(1)
+
+	m = pressKeys(m, runeKeys("dd")...)
+	if m.CurrentBuffer().NoOfLines() != 2 {
+		t.Fatalf("Expected 2 lines after dd, got %d", m.CurrentBuffer().NoOfLines())
+	}
+
+	m = pressKeys(m, runeKeys("u")...)
+	b := m.CurrentBuffer()
+	if b.NoOfLines() != 3 || b.Line(1) != "line 2" {
+		t.Errorf("Expected deleted line to be restored, got %v", b.Lines())
+	}
+	if b.CursorY() != 1 {
+		t.Errorf("Expected cursor Y to be restored to 1, got %d", b.CursorY())
+	}
+
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyCtrlR})
+	if m.CurrentBuffer().NoOfLines() != 2 {
+		t.Errorf("Expected 2 lines after redo, got %d", m.CurrentBuffer().NoOfLines())
+	}
+}
+
+func TestUndoGroupsInsertSession(t *testing.T) {
+	m := initialModel()
+	m.viewport = tea.WindowSizeMsg{Width: 80, Height: 24}
+	m.buffers[0] = newBuffer(m.style, bufferWithContent("", "first"))
+
+	m = pressKeys(m, runeKeys("o")...)
+	m = pressKeys(m, runeKeys("abc")...)
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEnter})
+	m = pressKeys(m, runeKeys("def")...)
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEsc})
+
+	if m.CurrentBuffer().NoOfLines() != 3 {
+		t.Fatalf("Expected 3 lines after insert session, got %v", m.CurrentBuffer().Lines())
+	}
+
+	m = pressKeys(m, runeKeys("u")...)
+	b := m.CurrentBuffer()
+	if b.NoOfLines() != 1 || b.Line(0) != "first" {
+		t.Errorf("Expected the whole insert session to be undone, got %v", b.Lines())
+	}
+
+	m = pressKeys(m, runeKeys("u")...)
+	if m.currentMessage == nil || m.currentMessage.text != "Already at oldest change" {
+		t.Errorf("Expected oldest change message, got %v", m.currentMessage)
+	}
+
+	m = pressKeys(m, runeKeys("U")...)
+	b = m.CurrentBuffer()
+	if b.NoOfLines() != 3 || b.Line(1) != "abc" || b.Line(2) != "def" {
+		t.Errorf("Expected redo to restore the insert session, got %v", b.Lines())
+	}
+}
+
+func TestUndoCommands(t *testing.T) {
+	m := initialModel()
+	m.buffers[0] = newBuffer(m.style, bufferWithContent("", "a\nb"))
+	m = pressKeys(m, runeKeys("dd")...)
+
+	m.commandBuffer = "undo"
+	newModel, _ := m.updateCommand(tea.KeyMsg{Type: tea.KeyEnter})
+	m = newModel.(model)
+	if m.CurrentBuffer().NoOfLines() != 2 {
+		t.Errorf("Expected 2 lines after :undo, got %d", m.CurrentBuffer().NoOfLines())
+	}
+
+	m.commandBuffer = "redo"
+	newModel, _ = m.updateCommand(tea.KeyMsg{Type: tea.KeyEnter})
+	m = newModel.(model)
+	if m.CurrentBuffer().NoOfLines() != 1 {
+		t.Errorf("Expected 1 line after :redo, got %d", m.CurrentBuffer().NoOfLines())
+	}
+}
+
+func TestUndoHistoryPersistsAcrossRestarts(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	filename := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(filename, []byte("one\ntwo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := initialModel(WithFile(filename))
+	m = pressKeys(m, runeKeys("dd")...)
+
+	m.commandBuffer = "w"
+	newModel, _ := m.updateCommand(tea.KeyMsg{Type: tea.KeyEnter})
+	m = newModel.(model)
+
+	// Reopen the file in a fresh editor and undo the change from before
+	m = initialModel(WithFile(filename))
+	if !m.CurrentBuffer().CanUndo() {
+		t.Fatal("Expected undo history to be loaded from the undo file")
+	}
+	m = pressKeys(m, runeKeys("u")...)
+	if m.CurrentBuffer().NoOfLines() != 2 || m.CurrentBuffer().Line(0) != "one" {
+		t.Errorf("Expected deleted line to be restored, got %v", m.CurrentBuffer().Lines())
+	}
+
+	// Changing the file outside of the editor invalidates the history
+	if err := os.WriteFile(filename, []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m = initialModel(WithFile(filename))
+	if m.CurrentBuffer().CanUndo() {
+		t.Error("Expected undo history to be ignored after the file changed")
+	}
+}
+
+func TestUndoFileWithUnknownVersionIsIgnored(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	filename := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(filename, []byte("one"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := undoFilePath(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := fmt.Sprintf(`{"version":%d,"hash":%q,"cur":1,"nodes":[{"lines":["x"],"parent":-1},{"lines":["one"],"parent":0}]}`,
+		undoFileVersion+1, contentHash("one"))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := initialModel(WithFile(filename))
+	if m.CurrentBuffer().CanUndo() {
+		t.Error("Expected undo file with unknown version to be ignored")
+	}
+}
+
+func TestUndoTreeKeepsBranches(t *testing.T) {
+	m := initialModel()
+	m.buffers[0] = newBuffer(m.style, bufferWithContent("", "a\nb\nc"))
+
+	// Delete "a", undo it and delete "c" instead, starting a new branch
+	m = pressKeys(m, runeKeys("dd")...)
+	m = pressKeys(m, runeKeys("u")...)
+	m.buffers[0] = m.buffers[0].SetCursorY(2)
+	m = pressKeys(m, runeKeys("dd")...)
+
+	if got := m.CurrentBuffer().Lines(); len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Fatalf("Expected [a b], got %v", got)
+	}
+
+	// The first branch is still reachable by change number
+	m.commandBuffer = "earlier 1"
+	newModel, _ := m.updateCommand(tea.KeyMsg{Type: tea.KeyEnter})
+	m = newModel.(model)
+	if got := m.CurrentBuffer().Lines(); len(got) != 2 || got[0] != "b" || got[1] != "c" {
+		t.Errorf("Expected [b c] after :earlier 1, got %v", got)
+	}
+
+	m.commandBuffer = "later"
+	newModel, _ = m.updateCommand(tea.KeyMsg{Type: tea.KeyEnter})
+	m = newModel.(model)
+	if got := m.CurrentBuffer().Lines(); len(got) != 2 || got[1] != "b" {
+		t.Errorf("Expected [a b] after :later, got %v", got)
+	}
+}
+
+func TestUndoEarlierByTime(t *testing.T) {
+	defer func(f func() time.Time) { now = f }(now)
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := start
+	now = func() time.Time { return clock }
+
+	m := initialModel()
+	m.buffers[0] = newBuffer(m.style, bufferWithContent("", "1\n2\n3\n4"))
+
+	for range 3 {
+		m = pressKeys(m, runeKeys("dd")...)
+		clock = clock.Add(5 * time.Minute)
+	}
+
+	m.commandBuffer = "earlier 6m"
+	newModel, _ := m.updateCommand(tea.KeyMsg{Type: tea.KeyEnter})
+	m = newModel.(model)
+
+	if cur, _ := m.CurrentBuffer().UndoSeq(); cur != 1 {
+		t.Errorf("Expected change #1 after :earlier 6m, got #%d", cur)
+	}
+}
+
+func TestUndoTravelWithoutHistory(t *testing.T) {
+	tests := []struct {
+		command string
+		message string
+	}{
+		{"earlier 5m", "Already at oldest change"},
+		{"later 5m", "Already at newest change"},
+		{"earlier 3", "Already at oldest change"},
+		{"later 3", "Already at newest change"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			m := initialModel()
+			m.buffers[0] = newBuffer(m.style, bufferWithContent("", "a"))
+
+			m = runCommand(m, tt.command)
+			if m.currentMessage == nil || m.currentMessage.text != tt.message {
+				t.Errorf("Expected %q, got %+v", tt.message, m.currentMessage)
+			}
+		})
+	}
+}
+
+func TestUndoTreeList(t *testing.T) {
+	m := initialModel()
+	m.viewport = tea.WindowSizeMsg{Width: 80, Height: 24}
+	m.buffers[0] = newBuffer(m.style, bufferWithContent("", "a\nb"))
+	m = pressKeys(m, runeKeys("dd")...)
+
+	m.commandBuffer = "undotree"
+	newModel, _ := m.updateCommand(tea.KeyMsg{Type: tea.KeyEnter})
+	m = newModel.(model)
+	if m.mode != ModeList || m.list == nil || len(m.list.items) != 2 {
+		t.Fatalf("Expected undo tree list with 2 states, got mode %s", m.mode)
+	}
+	if !strings.Contains(m.View(), "#1") {
+		t.Error("Expected undo tree to be rendered")
+	}
+
+	m = pressKeys(m, runeKeys("k")...)
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.mode != ModeNormal || m.CurrentBuffer().NoOfLines() != 2 {
+		t.Errorf("Expected original state to be restored, got %v", m.CurrentBuffer().Lines())
+	}
+}
+
+func TestUndoToWrittenStateIsSaved(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	filename := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(filename, []byte("one\ntwo\nthree"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := initialModel(WithFile(filename))
+	m = pressKeys(m, runeKeys("dd")...)
+	if m.CurrentBuffer().state != bufferStateModified {
+		t.Fatal("Expected the buffer modified after dd")
+	}
+	m = pressKeys(m, runeKeys("u")...)
+	if m.CurrentBuffer().state != bufferStateSaved {
+		t.Errorf("Expected undoing to the loaded state to be saved, got %s", m.CurrentBuffer().state)
+	}
+
+	m = pressKeys(m, runeKeys("dddd")...)
+	m = runCommand(m, "w")
+	m = pressKeys(m, runeKeys("u")...)
+	if m.CurrentBuffer().state != bufferStateModified {
+		t.Errorf("Expected undoing past the write to be modified, got %s", m.CurrentBuffer().state)
+	}
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyCtrlR})
+	if m.CurrentBuffer().state != bufferStateSaved {
+		t.Errorf("Expected redoing to the written state to be saved, got %s", m.CurrentBuffer().state)
+	}
+}
+
+func TestUndoFileStoresChanges(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	filename := filepath.Join(t.TempDir(), "notes.txt")
+	var lines []string
+	for i := range 1000 {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	if err := os.WriteFile(filename, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Two branches of changes in the middle of the file
+	m := initialModel(WithFile(filename))
+	m = pressKeys(m, runeKeys("500gg")...)
+	if got := cursorOf(m); got.line != 499 {
+		t.Fatalf("Expected the cursor on line 499, got %v", got)
+	}
+	m = pressKeys(m, runeKeys("dd")...)
+	m = pressKeys(m, runeKeys("ox")...)
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEsc})
+	m = pressKeys(m, runeKeys("uu")...)
+	m = pressKeys(m, runeKeys("ggddgedd")...)
+	if got := m.CurrentBuffer(); got.NoOfLines() != 998 || got.Line(0) != "line 1" || got.Line(997) != "line 998" {
+		t.Fatalf("Expected the first and the last line deleted, got %d lines", got.NoOfLines())
+	}
+	m = runCommand(m, "w")
+	want := m.CurrentBuffer().history
+
+	path, err := undoFilePath(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) > len(strings.Join(lines, "\n"))+4096 {
+		t.Errorf("Expected the undo file to store the changes, it has %d bytes", len(data))
+	}
+
+	got := initialModel(WithFile(filename)).CurrentBuffer().history
+	if len(got.nodes) != len(want.nodes) || got.cur != want.cur {
+		t.Fatalf("Expected %d nodes at %d, got %d at %d", len(want.nodes), want.cur, len(got.nodes), got.cur)
+	}
+	for i := range want.nodes {
+		if !got.nodes[i].state.lines.Equal(want.nodes[i].state.lines) {
+			t.Errorf("Expected the state %d to be restored", i)
+		}
+	}
+}
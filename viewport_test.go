@@ -93,7 +93,7 @@ func TestViewportAdjustment(t *testing.T) {
 			if len(lines) == 0 {
 				lines = []string{""}
 			}
-			m.buffers[0].lines = lines
+			m.buffers[0].lines = newRope(lines)
 			m.buffers[0].cursorY = tt.initialCursorY
 			m.buffers[0].viewport = m.viewport
 
@@ -123,7 +123,7 @@ func TestViewportAdjustment(t *testing.T) {
 				visualX := visualCursorX(line, cursorX)
 				
 				// The cursor should be visible (within the viewport width)
-				lineNumberWidth := len(fmt.Sprintf("%d", len(m.buffers[0].lines))) + 1
+				lineNumberWidth := len(fmt.Sprintf("%d", m.buffers[0].NoOfLines())) + 1
 				availableWidth := m.viewport.Width - lineNumberWidth
 				
 				if visualX < cursorXOffset || visualX >= cursorXOffset+availableWidth {
@@ -0,0 +1,447 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var errLastWindow = errors.New("Cannot close last window")
+
+// window shows a buffer. Windows showing the same buffer share its content
+// but each has its own cursor and scroll position.
+type window struct {
+	buffer int
+	view   view
+}
+
+// windowLayout is the tree of splits. A leaf shows a window, a node places
+// its children side by side when vertical or one above the other otherwise.
+type windowLayout struct {
+	win      int
+	vertical bool
+	children []windowLayout
+}
+
+// windowSet holds the windows on the screen. It's empty until the screen is
+// split, a single window is the buffer shown full screen.
+//
+// The view of the focused window lives in its buffer, so the commands can
+// move the cursor as usual. It's saved back into the window when the focus
+// moves to another one.
+type windowSet struct {
+	windows []window
+	curr    int
+	layout  windowLayout
+}
+
+type rect struct {
+	x, y, width, height int
+}
+
+// size is the viewport of a buffer shown in the rectangle. The rectangle
+// includes the status line of the window while the buffer leaves two lines
+// of its viewport for the status bar and the messages.
+func (r rect) size() tea.WindowSizeMsg {
+	return tea.WindowSizeMsg{Width: r.width, Height: r.height + 1}
+}
+
+// split places the window next to the target, before it. A split in the
+// same direction as the parent node adds the window to the node.
+func (l windowLayout) split(target, win int, vertical bool) windowLayout {
+	if l.children == nil {
+		if l.win != target {
+			return l
+		}
+		return windowLayout{vertical: vertical, children: []windowLayout{{win: win}, l}}
+	}
+
+	children := make([]windowLayout, 0, len(l.children)+1)
+	for _, c := range l.children {
+		if c.children == nil && c.win == target && l.vertical == vertical {
+			children = append(children, windowLayout{win: win}, c)
+			continue
+		}
+		children = append(children, c.split(target, win, vertical))
+	}
+	l.children = children
+	return l
+}
+
+// remove takes the window out of the layout. Windows after it are
+// renumbered and a node left with a single child is replaced by the child.
+func (l windowLayout) remove(win int) windowLayout {
+	if l.children == nil {
+		if l.win > win {
+			l.win--
+		}
+		return l
+	}
+
+	var children []windowLayout
+	for _, c := range l.children {
+		if c.children == nil && c.win == win {
+			continue
+		}
+		children = append(children, c.remove(win))
+	}
+	if len(children) == 1 {
+		return children[0]
+	}
+	l.children = children
+	return l
+}
+
+// order returns the windows from the top left to the bottom right.
+func (l windowLayout) order() []int {
+	if l.children == nil {
+		return []int{l.win}
+	}
+
+	var wins []int
+	for _, c := range l.children {
+		wins = append(wins, c.order()...)
+	}
+	return wins
+}
+
+// rects divides the area between the windows. Windows side by side are
+// separated by a column.
+func (l windowLayout) rects(area rect, out []rect) {
+	if l.children == nil {
+		out[l.win] = area
+		return
+	}
+
+	n := len(l.children)
+	if l.vertical {
+		free := area.width - (n - 1)
+		x := area.x
+		for i, c := range l.children {
+			w := free / n
+			if i < free%n {
+				w++
+			}
+			c.rects(rect{x: x, y: area.y, width: w, height: area.height}, out)
+			x += w + 1
+		}
+		return
+	}
+
+	y := area.y
+	for i, c := range l.children {
+		h := area.height / n
+		if i < area.height%n {
+			h++
+		}
+		c.rects(rect{x: area.x, y: y, width: area.width, height: h}, out)
+		y += h
+	}
+}
+
+// hasSplits tells if the screen is split into windows.
+func (m model) hasSplits() bool {
+	return len(m.windows.windows) > 1
+}
+
+// windowArea is the part of the screen shared by the windows. The line for
+// the messages is always kept so the windows don't jump.
+func (m model) windowArea() rect {
+	return rect{width: m.viewport.Width, height: max(m.viewport.Height-2-m.tablineHeight(), 1)}
+}
+
+func (m model) windowRects() []rect {
+	rects := make([]rect, len(m.windows.windows))
+	m.windows.layout.rects(m.windowArea(), rects)
+	return rects
+}
+
+// windowSize is the viewport of the buffer in the focused window.
+func (m model) windowSize() tea.WindowSizeMsg {
+	if !m.hasSplits() {
+		size := m.viewport
+		size.Height -= m.tablineHeight()
+		return size
+	}
+	return m.windowRects()[m.windows.curr].size()
+}
+
+// saveWindow stores the current buffer and its view in the focused window.
+func (m model) saveWindow() model {
+	if len(m.windows.windows) == 0 {
+		return m
+	}
+	m.windows.windows = slices.Clone(m.windows.windows)
+	m.windows.windows[m.windows.curr] = window{buffer: m.currBuffer, view: m.buffers[m.currBuffer].view}
+	return m
+}
+
+// loadWindow makes the buffer of the focused window current and gives it the
+// view of the window.
+func (m model) loadWindow() model {
+	w := m.windows.windows[m.windows.curr]
+	m.currBuffer = w.buffer
+	m.buffers[w.buffer] = m.buffers[w.buffer].withView(w.view)
+	return m
+}
+
+// withView returns the buffer seen from the view. The cursor is kept inside
+// the text, which may have been changed in another window.
+func (b buffer) withView(v view) buffer {
+	b.view = v
+	b.cursorY = max(min(b.cursorY, b.NoOfLines()-1), 0)
+	b.cursorX = max(min(b.cursorX, len(b.Line(b.cursorY))), 0)
+	return b.adjustViewportForCursor()
+}
+
+// resizeWindows gives every window its part of the screen.
+func (m model) resizeWindows() model {
+	if !m.hasSplits() {
+		m.buffers[m.currBuffer].viewport = m.windowSize()
+		return m
+	}
+
+	m = m.saveWindow()
+	for i, r := range m.windowRects() {
+		m.windows.windows[i].view.viewport = r.size()
+	}
+	return m.loadWindow()
+}
+
+// splitWindow opens a new window on the current buffer above the focused
+// one, or on its left when vertical. The new window gets the focus.
+func (m model) splitWindow(vertical bool) model {
+	if len(m.windows.windows) == 0 {
+		m.windows = windowSet{windows: []window{{buffer: m.currBuffer}}}
+	}
+	m = m.saveWindow()
+
+	ws := m.windows
+	n := len(ws.windows)
+	ws.windows = append(ws.windows, ws.windows[ws.curr])
+	ws.layout = ws.layout.split(ws.curr, n, vertical)
+	ws.curr = n
+	m.windows = ws
+
+	return m.resizeWindows()
+}
+
+// focusWindow moves the focus to the window.
+func (m model) focusWindow(i int) model {
+	if !m.hasSplits() || i == m.windows.curr {
+		return m
+	}
+	m = m.saveWindow()
+	m.windows.curr = i
+	return m.loadWindow()
+}
+
+// closeWindow closes the window, the buffer stays loaded. The window before
+// it gets the focus when it was focused.
+func (m model) closeWindow(i int) (model, error) {
+	if !m.hasSplits() {
+		return m, errLastWindow
+	}
+	m = m.saveWindow()
+
+	ws := m.windows
+	ws.windows = slices.Delete(ws.windows, i, i+1)
+	ws.layout = ws.layout.remove(i)
+	if ws.curr >= i && ws.curr > 0 {
+		ws.curr--
+	}
+	m.windows = ws
+	m = m.loadWindow()
+
+	if len(ws.windows) == 1 {
+		m.windows = windowSet{}
+	}
+	return m.resizeWindows(), nil
+}
+
+// onlyWindow closes all windows but the focused one.
+func (m model) onlyWindow() model {
+	m.windows = windowSet{}
+	return m.resizeWindows()
+}
+
+// cycleWindow moves the focus to the next window, or to the previous one
+// when n is negative.
+func (m model) cycleWindow(n int) model {
+	if !m.hasSplits() {
+		return m
+	}
+
+	order := m.windows.layout.order()
+	i := slices.Index(order, m.windows.curr)
+	i = ((i+n)%len(order) + len(order)) % len(order)
+	return m.focusWindow(order[i])
+}
+
+// neighbourWindow returns the window next to the focused one in the
+// direction, or -1. Of the windows touching the focused one the one by the
+// cursor wins.
+func (m model) neighbourWindow(dx, dy int) int {
+	if !m.hasSplits() {
+		return -1
+	}
+
+	rects := m.windowRects()
+	cur := rects[m.windows.curr]
+	b := m.buffers[m.currBuffer]
+	cursorY := cur.y + b.cursorY - b.cursorYOffset
+	cursorX := cur.x + b.cursorX - b.cursorXOffset
+
+	found, best := -1, 0
+	for i, r := range rects {
+		var touches bool
+		var overlap, at int
+		switch {
+		case dx > 0:
+			touches, overlap, at = r.x == cur.x+cur.width+1, spanOverlap(r.y, r.height, cur.y, cur.height), cursorY-r.y
+		case dx < 0:
+			touches, overlap, at = r.x+r.width+1 == cur.x, spanOverlap(r.y, r.height, cur.y, cur.height), cursorY-r.y
+		case dy > 0:
+			touches, overlap, at = r.y == cur.y+cur.height, spanOverlap(r.x, r.width, cur.x, cur.width), cursorX-r.x
+		case dy < 0:
+			touches, overlap, at = r.y+r.height == cur.y, spanOverlap(r.x, r.width, cur.x, cur.width), cursorX-r.x
+		}
+		if !touches || overlap <= 0 {
+			continue
+		}
+
+		size := r.height
+		if dy != 0 {
+			size = r.width
+		}
+		score := overlap
+		if at >= 0 && at < size {
+			score += cur.width + cur.height
+		}
+		if score > best {
+			found, best = i, score
+		}
+	}
+	return found
+}
+
+func spanOverlap(start1, len1, start2, len2 int) int {
+	return min(start1+len1, start2+len2) - max(start1, start2)
+}
+
+// windowsBufferDeleted fixes the windows, including the ones in other tab
+// pages, after the buffer was deleted. The windows showing it show the
+// current buffer instead.
+func (m model) windowsBufferDeleted(i int) model {
+	replacement := window{buffer: m.currBuffer, view: m.buffers[m.currBuffer].view}
+	m.windows = m.windows.bufferDeleted(i, replacement)
+
+	m.tabs = slices.Clone(m.tabs)
+	for j, ws := range m.tabs {
+		m.tabs[j] = ws.bufferDeleted(i, replacement)
+	}
+	return m
+}
+
+func (ws windowSet) bufferDeleted(i int, replacement window) windowSet {
+	ws.windows = slices.Clone(ws.windows)
+	for j, w := range ws.windows {
+		switch {
+		case w.buffer == i:
+			ws.windows[j] = replacement
+		case w.buffer > i:
+			ws.windows[j].buffer--
+		}
+	}
+	return ws
+}
+
+// windowsView renders the windows.
+func (m model) windowsView() string {
+	return strings.Join(m.layoutView(m.windows.layout, m.windowRects()), "\n")
+}
+
+func (m model) layoutView(l windowLayout, rects []rect) []string {
+	if l.children == nil {
+		return m.windowView(l.win, rects[l.win])
+	}
+
+	if !l.vertical {
+		var lines []string
+		for _, c := range l.children {
+			lines = append(lines, m.layoutView(c, rects)...)
+		}
+		return lines
+	}
+
+	// The children are as high as the first window in the node
+	lines := make([]string, rects[l.order()[0]].height)
+	separator := m.style.windowSeparator.Render("│")
+	for i, c := range l.children {
+		for y, line := range m.layoutView(c, rects) {
+			if y >= len(lines) {
+				break
+			}
+			if i > 0 {
+				lines[y] += separator
+			}
+			lines[y] += line
+		}
+	}
+	return lines
+}
+
+// windowView renders the window as exactly r.height lines of r.width
+// columns, the last one being its status line.
+func (m model) windowView(i int, r rect) []string {
+	focused := i == m.windows.curr
+
+	var b buffer
+	if focused {
+		b = m.buffers[m.currBuffer]
+	} else {
+		w := m.windows.windows[i]
+		b = m.buffers[w.buffer].withView(w.view)
+	}
+	b.viewport = r.size()
+	b.highlights = nil
+	b = m.searchHighlights(b)
+
+	statusStyle := m.style.statusBarInactive
+	if focused {
+		statusStyle = m.style.statusBar
+		if isVisualMode(m.mode) {
+			b.highlights = append(b.highlights, highlight{textRange: m.Selection(), style: m.style.visual})
+		}
+		if m.mode == ModeConfirm && m.substitute != nil {
+			b.highlights = append(b.highlights, highlight{textRange: m.substitute.currentMatch(), style: m.style.searchCurrent})
+		}
+	}
+
+	name := fileNameLabel(b.filename, b.state)
+	pos := filePossitionInfo(b.cursorY+1, b.cursorX+1)
+	if !focused {
+		// Only the focused window shows the cursor
+		b.cursorY = -1
+	}
+
+	lines := strings.Split(strings.TrimSuffix(b.View(), "\n"), "\n")
+	for len(lines) < r.height-1 {
+		lines = append(lines, "")
+	}
+	lines = lines[:max(r.height-1, 0)]
+
+	fit := lipgloss.NewStyle().MaxWidth(r.width)
+	for j, line := range lines {
+		line = fit.Render(line)
+		lines[j] = line + strings.Repeat(" ", max(r.width-lipgloss.Width(line), 0))
+	}
+
+	pad := max(r.width-len(name)-len(pos)-2, 1)
+	status := fit.Render(" " + name + strings.Repeat(" ", pad) + pos + " ")
+	status += strings.Repeat(" ", max(r.width-lipgloss.Width(status), 0))
+	return append(lines, statusStyle.Render(status))
+}
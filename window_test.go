@@ -0,0 +1,163 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+var ctrlW = tea.KeyMsg{Type: tea.KeyCtrlW}
+
+func windowTestModel(content string) model {
+	m := initialModel()
+	m.buffers[0] = newBuffer(m.style, bufferStateSavedOpt, bufferWithContent("file.txt", content))
+	newModel, _ := m.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	return newModel.(model)
+}
+
+func TestSplitWindow(t *testing.T) {
+	m := windowTestModel("one\ntwo\nthree")
+
+	m = runCommand(m, "split")
+	if len(m.windows.windows) != 2 || m.windows.curr != 1 {
+		t.Fatalf("Expected the new window to be focused, got %+v", m.windows)
+	}
+	if got := m.windowRects(); got[1] != (rect{width: 80, height: 11}) || got[0] != (rect{y: 11, width: 80, height: 11}) {
+		t.Errorf("Expected the new window above, got %+v", got)
+	}
+	if got := m.CurrentBuffer().Viewport(); got.Height != 12 {
+		t.Errorf("Expected the buffer to get the size of the window, got %+v", got)
+	}
+
+	m = pressKeys(m, ctrlW, runeKeys("v")[0])
+	if got := m.windowRects(); got[2] != (rect{width: 40, height: 11}) || got[1] != (rect{x: 41, width: 39, height: 11}) {
+		t.Errorf("Expected the new window on the left, got %+v", got)
+	}
+
+	// The status bar at the bottom shows the focused one again
+	if got := strings.Count(m.View(), "file.txt"); got != 4 {
+		t.Errorf("Expected a status line for every window, got %d", got)
+	}
+}
+
+func TestWindowsHaveOwnCursors(t *testing.T) {
+	m := windowTestModel("one\ntwo\nthree")
+	m = pressKeys(m, ctrlW, runeKeys("s")[0])
+	m = pressKeys(m, runeKeys("jj")...)
+
+	m = pressKeys(m, ctrlW, ctrlW)
+	if m.windows.curr != 0 || cursorOf(m) != (position{}) {
+		t.Errorf("Expected the first window to keep its cursor, got %v", cursorOf(m))
+	}
+
+	m = pressKeys(m, ctrlW, runeKeys("W")[0])
+	if m.windows.curr != 1 || cursorOf(m) != (position{line: 2}) {
+		t.Errorf("Expected the cursor of the second window back, got %v", cursorOf(m))
+	}
+}
+
+func TestEditsShowInAllWindows(t *testing.T) {
+	m := windowTestModel("one\ntwo\nthree")
+	m = pressKeys(m, runeKeys("jj")...)
+	m = runCommand(m, "vsplit")
+	m = pressKeys(m, runeKeys("dd")...)
+
+	if got := strings.Count(m.View(), "three"); got != 0 {
+		t.Errorf("Expected the deleted line to be gone from both windows, found it %d times", got)
+	}
+	if got := strings.Count(m.View(), "two"); got != 2 {
+		t.Errorf("Expected both windows to show the text, found it %d times", got)
+	}
+
+	// The other window had its cursor on the deleted line
+	m = pressKeys(m, ctrlW, runeKeys("l")[0])
+	if got := cursorOf(m); got != (position{line: 1}) {
+		t.Errorf("Expected the cursor to be kept in the text, got %v", got)
+	}
+	m = pressKeys(m, runeKeys("dd")...)
+	if got := m.CurrentBuffer().Lines(); !slices.Equal(got, []string{"one"}) {
+		t.Errorf("Expected the edit to use the cursor of the window, got %q", got)
+	}
+}
+
+func TestFocusNeighbourWindow(t *testing.T) {
+	m := windowTestModel("one")
+	m = runCommand(m, "split")
+	m = runCommand(m, "vsplit")
+
+	// 2 | 1
+	// -----
+	//   0
+	tests := []struct {
+		key  string
+		want int
+	}{
+		{"l", 1},
+		{"j", 0},
+		{"k", 2},
+		{"h", 2},
+		{"j", 0},
+	}
+	for _, tt := range tests {
+		m = pressKeys(m, ctrlW, runeKeys(tt.key)[0])
+		if m.windows.curr != tt.want {
+			t.Errorf("Expected ctrl-w %s to focus window %d, got %d", tt.key, tt.want, m.windows.curr)
+		}
+	}
+}
+
+func TestCloseWindow(t *testing.T) {
+	m := windowTestModel("one")
+	m = runCommand(m, "split")
+	m = runCommand(m, "split")
+
+	m = runCommand(m, "close")
+	if len(m.windows.windows) != 2 || m.windows.curr != 1 {
+		t.Errorf("Expected the window to be closed, got %+v", m.windows)
+	}
+
+	m = pressKeys(m, ctrlW, runeKeys("q")[0])
+	if m.hasSplits() || m.CurrentBuffer().Viewport().Height != 24 {
+		t.Errorf("Expected the last window to fill the screen, got %+v", m.windows)
+	}
+
+	m = runCommand(m, "close")
+	if m.currentMessage == nil || m.currentMessage.text != "Cannot close last window" {
+		t.Errorf("Expected an error, got %v", m.currentMessage)
+	}
+
+	m = runCommand(m, "sp")
+	m = runCommand(m, "vs")
+	m = pressKeys(m, ctrlW, runeKeys("o")[0])
+	if m.hasSplits() {
+		t.Errorf("Expected only one window, got %+v", m.windows)
+	}
+}
+
+func TestSplitFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "other.txt")
+	if err := os.WriteFile(file, []byte("other"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := windowTestModel("one")
+	m = runCommand(m, "split "+file)
+	if m.CurrentBuffer().filename != file || m.windows.curr != 1 {
+		t.Fatalf("Expected the file to be opened in the new window")
+	}
+
+	m = pressKeys(m, ctrlW, runeKeys("j")[0])
+	if m.CurrentBuffer().filename != "file.txt" {
+		t.Errorf("Expected the other window to show the first buffer, got %q", m.CurrentBuffer().filename)
+	}
+
+	m = runCommand(m, "bd 1")
+	m = pressKeys(m, ctrlW, runeKeys("k")[0])
+	if m.currBuffer != 0 || m.CurrentBuffer().filename != file {
+		t.Errorf("Expected the window to follow the renumbered buffer, got %d", m.currBuffer)
+	}
+}
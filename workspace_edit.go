@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+)
+
+// lspWorkspaceEdit are the edits of several files. Servers supporting
+// versioned edits send documentChanges, the others changes.
+type lspWorkspaceEdit struct {
+	Changes         map[string][]lspTextEdit `json:"changes,omitempty"`
+	DocumentChanges []json.RawMessage        `json:"documentChanges,omitempty"`
+}
+
+// lspTextDocumentEdit is an item of documentChanges. File operations have
+// a kind instead.
+type lspTextDocumentEdit struct {
+	Kind         string `json:"kind,omitempty"`
+	TextDocument struct {
+		URI     string `json:"uri"`
+		Version *int   `json:"version"`
+	} `json:"textDocument"`
+	Edits []lspTextEdit `json:"edits"`
+}
+
+type lspApplyWorkspaceEditParams struct {
+	Label string           `json:"label,omitempty"`
+	Edit  lspWorkspaceEdit `json:"edit"`
+}
+
+type lspApplyWorkspaceEditResult struct {
+	Applied       bool   `json:"applied"`
+	FailureReason string `json:"failureReason,omitempty"`
+}
+
+// fileEdits are the edits of one file. The version is the one of the
+// document the edits were made for, if the server told it.
+type fileEdits struct {
+	path    string
+	version *int
+	edits   []lspTextEdit
+}
+
+// files groups the edits by file, in the order they come.
+func (e lspWorkspaceEdit) files() ([]fileEdits, error) {
+	var files []fileEdits
+	add := func(uri string, version *int, edits []lspTextEdit) {
+		path := locationPath(uri)
+		for i := range files {
+			if files[i].path == path {
+				files[i].edits = append(files[i].edits, edits...)
+				return
+			}
+		}
+		files = append(files, fileEdits{path: path, version: version, edits: edits})
+	}
+
+	if len(e.DocumentChanges) > 0 {
+		for _, raw := range e.DocumentChanges {
+			var change lspTextDocumentEdit
+			if err := json.Unmarshal(raw, &change); err != nil {
+				return nil, err
+			}
+			if change.Kind != "" {
+				return nil, fmt.Errorf("unsupported file operation: %s", change.Kind)
+			}
+			add(change.TextDocument.URI, change.TextDocument.Version, change.Edits)
+		}
+		return files, nil
+	}
+
+	for _, uri := range slices.Sorted(maps.Keys(e.Changes)) {
+		add(uri, nil, e.Changes[uri])
+	}
+	return files, nil
+}
+
+// applyWorkspaceEdit makes the edits in all the files or in none of them.
+// Files which aren't open are loaded into new buffers. The edited buffers
+// are modified, not written. It returns the names of the edited files.
+func (m model) applyWorkspaceEdit(edit lspWorkspaceEdit) (model, []string, error) {
+	files, err := edit.files()
+	if err != nil {
+		return m, nil, err
+	}
+
+	// Everything is checked before the first buffer is touched
+	buffers := make([]buffer, len(files))
+	indexes := make([]int, len(files))
+	edits := make([][]textEdit, len(files))
+	for i, f := range files {
+		indexes[i] = m.findBufferByFile(f.path)
+		if indexes[i] >= 0 {
+			buffers[i] = m.buffers[indexes[i]]
+			if version, ok := m.lsp.version(buffers[i].filename); ok && f.version != nil && *f.version != version {
+				return m, nil, fmt.Errorf("%s changed since the edit was made", f.path)
+			}
+		} else {
+			cont, err := os.ReadFile(f.path)
+			if err != nil {
+				return m, nil, err
+			}
+			b := newBuffer(m.style, bufferStateSavedOpt, bufferWithContent(f.path, string(cont)))
+			buffers[i] = b.loadUndoFile(string(cont))
+		}
+
+		// The UTF-16 positions are converted in the text before the edits
+		for _, e := range f.edits {
+			edits[i] = append(edits[i], buffers[i].textEditFromLSP(e))
+		}
+		sorted := sortTextEdits(edits[i])
+		for j := 1; j < len(sorted); j++ {
+			if sorted[j].start.before(sorted[j-1].end) {
+				return m, nil, fmt.Errorf("overlapping edits in %s", f.path)
+			}
+		}
+	}
+
+	names := make([]string, len(files))
+	for i, f := range files {
+		b := buffers[i]
+		cursor := positionAfterEdits(position{line: b.cursorY, col: b.cursorX}, edits[i])
+		b = b.beginUndoStep().applyTextEdits(edits[i])
+		b = b.moveCursorTo(cursor.col, cursor.line).SetStateModified().commitUndoStep()
+		if indexes[i] >= 0 {
+			m.buffers[indexes[i]] = b
+		} else {
+			m = m.addBuffer(b)
+		}
+		names[i] = f.path
+	}
+	return m, names, nil
+}
+
+// editSummary tells which files an edit changed.
+func editSummary(names []string) string {
+	switch len(names) {
+	case 0:
+		return "No changes"
+	case 1:
+		return "1 file changed: " + names[0]
+	}
+	return fmt.Sprintf("%d files changed: %s", len(names), strings.Join(names, ", "))
+}
+
+// workspaceEditMessage applies the edit and reports the changed files, or
+// why it failed.
+func (m model) workspaceEditMessage(edit lspWorkspaceEdit) (model, error) {
+	m, names, err := m.applyWorkspaceEdit(edit)
+	if err != nil {
+		return m.SetErrorMessage("Failed to apply the edit: " + err.Error()), err
+	}
+	return m.SetInfoMessage(editSummary(names)), nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestApplyWorkspaceEdit(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"other.txt": "añb 😀 x\nsecond\n"})
+	t.Chdir(dir)
+
+	m := initialModel()
+	m.buffers[0] = newBuffer(m.style, bufferWithContent("main.txt", "é😀 end"))
+	m.buffers[0] = m.buffers[0].moveCursorTo(len("é😀 "), 0)
+
+	// The positions count UTF-16 code units: é is one, 😀 two
+	edit := lspWorkspaceEdit{Changes: map[string][]lspTextEdit{
+		fileURI(filepath.Join(dir, "main.txt")): {
+			{Range: lspRange{Start: lspPosition{Character: 1}, End: lspPosition{Character: 3}}, NewText: "ab"},
+		},
+		fileURI(filepath.Join(dir, "other.txt")): {
+			{Range: lspRange{Start: lspPosition{Character: 4}, End: lspPosition{Character: 6}}, NewText: "smile"},
+			{Range: lspRange{Start: lspPosition{Line: 1}, End: lspPosition{Line: 1, Character: 6}}, NewText: "2nd\nthird"},
+		},
+	}}
+	m, names, err := m.applyWorkspaceEdit(edit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(names, ","); got != "main.txt,other.txt" {
+		t.Errorf("Expected both files changed, got %q", got)
+	}
+
+	if line := m.buffers[0].Line(0); line != "éab end" || cursorOf(m) != (position{line: 0, col: len("éab ")}) {
+		t.Errorf("Expected the emoji replaced keeping the cursor on end, got %q %v", line, cursorOf(m))
+	}
+	if len(m.buffers) != 2 || m.buffers[1].filename != "other.txt" {
+		t.Fatalf("Expected other.txt loaded into a new buffer")
+	}
+	other := m.buffers[1]
+	if got := strings.Join(other.Lines(), "\n"); got != "añb smile x\n2nd\nthird\n" {
+		t.Errorf("Unexpected content of other.txt: %q", got)
+	}
+	if other.state != bufferStateModified || m.buffers[0].state != bufferStateModified {
+		t.Errorf("Expected the edited buffers modified")
+	}
+	if m.currBuffer != 0 {
+		t.Errorf("Expected the current buffer kept, got %d", m.currBuffer)
+	}
+
+	m.buffers[1] = m.buffers[1].Undo()
+	if got := strings.Join(m.buffers[1].Lines(), "\n"); got != "añb 😀 x\nsecond\n" {
+		t.Errorf("Expected the edit undone at once, got %q", got)
+	}
+}
+
+func TestApplyWorkspaceEditAtomic(t *testing.T) {
+	t.Chdir(t.TempDir())
+	m := initialModel()
+	m.buffers[0] = newBuffer(m.style, bufferWithContent("main.txt", "text"))
+
+	edit := lspWorkspaceEdit{Changes: map[string][]lspTextEdit{
+		fileURI("main.txt"):    {{Range: lspRange{End: lspPosition{Character: 4}}, NewText: "changed"}},
+		fileURI("missing.txt"): {{NewText: "new"}},
+	}}
+	m, _, err := m.applyWorkspaceEdit(edit)
+	if err == nil {
+		t.Fatal("Expected an error for the missing file")
+	}
+	if m.buffers[0].Line(0) != "text" || len(m.buffers) != 1 {
+		t.Errorf("Expected nothing changed, got %q and %d buffers", m.buffers[0].Line(0), len(m.buffers))
+	}
+
+	change, _ := json.Marshal(map[string]any{"kind": "delete", "uri": fileURI("main.txt")})
+	if _, _, err := m.applyWorkspaceEdit(lspWorkspaceEdit{DocumentChanges: []json.RawMessage{change}}); err == nil {
+		t.Error("Expected file operations to be refused")
+	}
+}
+
+func TestRename(t *testing.T) {
+	m, dir := navigationTestModel(t)
+	defer m.lsp.Shutdown()
+
+	m = runCommand(m, "rename assist")
+	m = waitUntil(t, m, func(m model) bool { return m.currentMessage != nil })
+	if !strings.HasPrefix(m.currentMessage.text, "2 files changed: ") {
+		t.Errorf("Expected a summary of the files, got %q", m.currentMessage.text)
+	}
+	if line := m.CurrentBuffer().Line(3); line != "\tassist(1, \"x\")" {
+		t.Errorf("Expected the call renamed, got %q", line)
+	}
+	i := m.findBufferByFile(filepath.Join(dir, "other.go"))
+	if i < 0 {
+		t.Fatal("Expected other.go loaded")
+	}
+	if line := m.buffers[i].Line(3); line != "func assist(a int, b string) {}" || m.buffers[i].state != bufferStateModified {
+		t.Errorf("Expected the declaration renamed, got %q", line)
+	}
+	if content, _ := os.ReadFile(filepath.Join(dir, "other.go")); strings.Contains(string(content), "assist") {
+		t.Errorf("Expected the file not written")
+	}
+
+	m = runCommand(m, "rename")
+	if m.currentMessage == nil || m.currentMessage.text != "New name required" {
+		t.Errorf("Expected the new name to be required, got %+v", m.currentMessage)
+	}
+}
+
+func TestCodeActions(t *testing.T) {
+	m, _ := lspTestModel(t, os.Args[0], map[string]string{
+		"main.go": "package main\n\nfunc main() {\n\tbad()\n}\n",
+	})
+	defer m.lsp.Shutdown()
+	m = waitUntil(t, m, func(m model) bool { return len(m.CurrentBuffer().diagnostics) > 0 })
+	m = pressKeys(m, runeKeys("jjj")...)
+
+	m = pressKeys(m, runeKeys(" a")...)
+	m = waitUntil(t, m, func(m model) bool { return m.mode == ModeList })
+	if len(m.list.items) != 2 || m.list.items[0].label != "Fix the bad word (quickfix)" || m.list.items[1].label != "Say hi" {
+		t.Fatalf("Expected the fix and the command, got %+v", m.list.items)
+	}
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if line := m.CurrentBuffer().Line(3); line != "\tgood()" {
+		t.Errorf("Expected the bad word fixed, got %q", line)
+	}
+
+	m = pressKeys(m, runeKeys(" a")...)
+	m = waitUntil(t, m, func(m model) bool { return m.mode == ModeList })
+	m = pressKeys(m, runeKeys("G")...)
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEnter})
+	var result lspApplyWorkspaceEditResult
+	m = waitForLSP(t, m, "fake/workspace/applyEdit", &result)
+	if !result.Applied || m.CurrentBuffer().Line(0) != "// hi" {
+		t.Errorf("Expected the edit applied before the answer, got %+v and %q", result, m.CurrentBuffer().Line(0))
+	}
+
+	// An edit of a file which doesn't exist fails
+	arg, _ := json.Marshal(fileURI(filepath.Join(t.TempDir(), "missing.go")))
+	cmd := lspCommand{Command: "fake.hi", Arguments: []json.RawMessage{arg}}
+	m.lsp.request(m.CurrentBuffer().filename, "workspace/executeCommand", cmd, func(m model, _ json.RawMessage, _ error) (model, tea.Cmd) {
+		return m, nil
+	})
+	m = waitForLSP(t, m, "fake/workspace/applyEdit", &result)
+	if result.Applied || result.FailureReason == "" {
+		t.Errorf("Expected the edit to fail with a reason, got %+v", result)
+	}
+	if m.currentMessage == nil || !strings.HasPrefix(m.currentMessage.text, "Failed to apply the edit") {
+		t.Errorf("Expected the failure reported, got %+v", m.currentMessage)
+	}
+}